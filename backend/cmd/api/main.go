@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+	"backend/internal/server"
+)
+
+// Combined api server mounting every route group on one mux so the whole
+// backend can run as a single process locally
+func main() {
+	server.Run("api", os.Args[1:],
+		server.RegisterUserRoutes,
+		server.RegisterProductRoutes,
+	)
+}
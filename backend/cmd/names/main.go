@@ -0,0 +1,36 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"backend/pkg/logger"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// Migrate a database created before product names were normalized, fills in
+// products.normalized_name, merges the duplicates it reveals and adds the
+// constraints, ex: go run ./cmd/names -database-url postgres://...
+// Takes the same settings as the services (see -help)
+func main() {
+	cfg, err := config.Load("names", os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	slog.SetDefault(logger.New(os.Stdout, cfg.Log.Format, logger.ParseLevel(cfg.Log.Level)))
+
+	pool := db.ConnectionPool(cfg.Database)
+	defer pool.Close()
+
+	backfill, err := db.NewRepository(pool).BackfillNormalizedNames(context.Background())
+	if err != nil {
+		log.Fatalf("Error backfilling normalized names: %v", err)
+	}
+
+	for _, merge := range backfill.Merges {
+		slog.Info("merged duplicate product", "duplicate_id", merge.DuplicateID, "canonical_id", merge.CanonicalID, "alias", merge.Alias)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"backend/internal/exchange"
+	"backend/pkg/logger"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// Import ECB reference rates into the exchange_rates table from an xml or csv
+// file, ex: go run ./cmd/rates -database-url postgres://... eurofxref-hist.csv
+// Takes the same settings as the services (see -help)
+func main() {
+	cfg, err := config.Load("rates", os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	if len(cfg.Args()) != 1 {
+		log.Fatal("Usage: rates [flags] <eurofxref.xml|eurofxref.csv>")
+	}
+	path := cfg.Args()[0]
+
+	slog.SetDefault(logger.New(os.Stdout, cfg.Log.Format, logger.ParseLevel(cfg.Log.Level)))
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Error opening rates file: %v", err)
+	}
+	defer file.Close()
+
+	rates, err := exchange.ParseFile(path, file)
+	if err != nil {
+		log.Fatalf("Error parsing rates file: %v", err)
+	}
+
+	pool := db.ConnectionPool(cfg.Database)
+	defer pool.Close()
+
+	written, err := db.NewRepository(pool).UpsertExchangeRates(context.Background(), rates)
+	if err != nil {
+		log.Fatalf("Error importing rates: %v", err)
+	}
+
+	slog.Info("imported exchange rates", "file", path, "rates", written)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"backend/pkg/logger"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+)
+
+// Rebuild the cached price statistics of products with new snapshots or
+// stats older than an hour, the stats route only reads what this job last
+// built. Meant to run every few minutes from cron, ex: go run ./cmd/stats
+// -database-url postgres://... Takes the same settings as the services (see -help)
+func main() {
+	cfg, err := config.Load("stats", os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	slog.SetDefault(logger.New(os.Stdout, cfg.Log.Format, logger.ParseLevel(cfg.Log.Level)))
+
+	pool := db.ConnectionPool(cfg.Database)
+	defer pool.Close()
+
+	refreshed, err := db.NewRepository(pool).RefreshPriceStats(context.Background())
+	if err != nil {
+		log.Fatalf("Error refreshing price stats: %v", err)
+	}
+
+	slog.Info("refreshed price stats", "products", refreshed)
+}
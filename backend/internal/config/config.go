@@ -0,0 +1,413 @@
+package config
+
+import (
+	"backend/internal/ratelimit"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+type Config struct {
+	Server		ServerConfig
+	Database	DBConfig
+	Session		SessionConfig
+	Cookie		CookieConfig
+	CORS		CORSConfig
+	Scraper		ScraperConfig
+	RateLimit	RateLimitConfig
+	Log			LogConfig
+	Tracing		TraceConfig
+
+	// flag set holding every setting, used to print the effective config
+	flags		*flag.FlagSet
+}
+
+type ServerConfig struct {
+	ListenAddr	string
+}
+
+type DBConfig struct {
+	URL					string
+	MaxConns			int
+	MinConns			int
+	MaxConnLifetime		time.Duration
+	MaxConnIdleTime		time.Duration
+	HealthCheckPeriod	time.Duration
+}
+
+type SessionConfig struct {
+	TTL	time.Duration
+}
+
+type CookieConfig struct {
+	Secure		bool
+	SameSite	string
+	Domain		string
+}
+
+type CORSConfig struct {
+	AllowedOrigins		[]string
+	AllowCredentials	bool
+	MaxAge				time.Duration
+}
+
+type ScraperConfig struct {
+	Interval		time.Duration
+	Timeout			time.Duration
+	MaxConcurrency	int
+	UserAgent		string
+}
+
+type RateLimitConfig struct {
+	// memory or postgres, postgres shares buckets across replicas
+	Backend			string
+	// addresses or cidr ranges of the reverse proxies in front of the api,
+	// only requests from these are keyed on X-Forwarded-For
+	TrustedProxies	[]string
+	Default		ratelimit.Limit
+	Login		ratelimit.Limit
+	AddProduct	ratelimit.Limit
+	// one import adds up to 200 products so it has its own, slower bucket
+	Import		ratelimit.Limit
+}
+
+type LogConfig struct {
+	Format	string
+	Level	string
+}
+
+type TraceConfig struct {
+	ServiceName	string
+	EndpointURL	string
+}
+
+// settings that should never be printed as is
+var secretSettings = map[string]bool{
+	"database-url": true,
+}
+
+// Load the config for a service. Every setting can be passed as a command line
+// flag (ex: -listen-addr), an environment variable (ex: LISTEN_ADDR) or a line
+// in the env file, in that order of precedence, falling back to the defaults
+func Load(serviceName string, args []string) (*Config, error) {
+	cfg := &Config{
+		flags: flag.NewFlagSet(serviceName, flag.ContinueOnError),
+	}
+	fset := cfg.flags
+
+	envFile := fset.String("env-file", envOrDefault("ENV_FILE", ".env"), "path to an optional env file")
+
+	fset.StringVar(&cfg.Server.ListenAddr, "listen-addr", ":8000", "address the http server listens on")
+
+	fset.StringVar(&cfg.Database.URL, "database-url", "", "postgres connection string")
+	fset.IntVar(&cfg.Database.MaxConns, "db-max-conns", 50, "max connections in the pool")
+	fset.IntVar(&cfg.Database.MinConns, "db-min-conns", 5, "min idle connections kept in the pool")
+	fset.DurationVar(&cfg.Database.MaxConnLifetime, "db-max-conn-lifetime", 30*time.Minute, "max lifetime of a pooled connection")
+	fset.DurationVar(&cfg.Database.MaxConnIdleTime, "db-max-conn-idle-time", 5*time.Minute, "max idle time of a pooled connection")
+	fset.DurationVar(&cfg.Database.HealthCheckPeriod, "db-health-check-period", time.Minute, "how often idle connections are health checked")
+
+	fset.DurationVar(&cfg.Session.TTL, "session-ttl", 24*time.Hour, "how long a login session stays valid")
+
+	fset.BoolVar(&cfg.Cookie.Secure, "cookie-secure", true, "only send the session cookie over https")
+	fset.StringVar(&cfg.Cookie.SameSite, "cookie-same-site", "strict", "SameSite mode of the session cookie: strict, lax or none")
+	fset.StringVar(&cfg.Cookie.Domain, "cookie-domain", "", "domain of the session cookie, empty for the request host")
+
+	fset.Var((*listValue)(&cfg.CORS.AllowedOrigins), "cors-allowed-origins", "comma separated origins allowed to call the api")
+	fset.BoolVar(&cfg.CORS.AllowCredentials, "cors-allow-credentials", true, "allow cookies on cross origin requests")
+	fset.DurationVar(&cfg.CORS.MaxAge, "cors-max-age", 10*time.Minute, "how long browsers may cache preflight responses")
+
+	fset.DurationVar(&cfg.Scraper.Interval, "scraper-interval", time.Hour, "how often tracked products are re-scraped")
+	fset.DurationVar(&cfg.Scraper.Timeout, "scraper-timeout", 30*time.Second, "timeout for a single scrape request")
+	fset.IntVar(&cfg.Scraper.MaxConcurrency, "scraper-max-concurrency", 4, "max scrapes running at once")
+	fset.StringVar(&cfg.Scraper.UserAgent, "scraper-user-agent", "PriceCompass/1.0", "user agent sent by the scrapers")
+
+	cfg.RateLimit.Default = ratelimit.Limit{Requests: 120, Period: time.Minute}
+	cfg.RateLimit.Login = ratelimit.Limit{Requests: 5, Period: time.Minute}
+	cfg.RateLimit.AddProduct = ratelimit.Limit{Requests: 10, Period: time.Minute}
+	cfg.RateLimit.Import = ratelimit.Limit{Requests: 5, Period: time.Hour}
+	fset.StringVar(&cfg.RateLimit.Backend, "rate-limit-backend", "memory", "where rate limit buckets are kept: memory or postgres")
+	fset.Var((*listValue)(&cfg.RateLimit.TrustedProxies), "rate-limit-trusted-proxies", "comma separated proxy ips or cidrs whose X-Forwarded-For is trusted")
+	fset.Var(&cfg.RateLimit.Default, "rate-limit-default", "requests/period allowed per user or ip on each route")
+	fset.Var(&cfg.RateLimit.Login, "rate-limit-login", "requests/period allowed per ip on the login route")
+	fset.Var(&cfg.RateLimit.AddProduct, "rate-limit-add-product", "requests/period allowed per user when adding products")
+	fset.Var(&cfg.RateLimit.Import, "rate-limit-import", "requests/period allowed per user when importing a watchlist file")
+
+	fset.StringVar(&cfg.Log.Format, "log-format", "text", "log output format: json or text")
+	fset.StringVar(&cfg.Log.Level, "log-level", "info", "minimum log level: debug, info, warn or error")
+
+	fset.StringVar(&cfg.Tracing.ServiceName, "tracing-service-name", serviceName, "service name reported on spans")
+	fset.StringVar(&cfg.Tracing.EndpointURL, "tracing-endpoint", "", "OTLP/HTTP collector url, tracing is disabled when empty")
+
+	err := fset.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	setByFlag := map[string]bool{}
+	fset.Visit(func(f *flag.Flag) { setByFlag[f.Name] = true })
+
+	fileValues, err := godotenv.Read(*envFile)
+	if err != nil {
+		// the env file is optional unless it was asked for explicitly
+		if !errors.Is(err, fs.ErrNotExist) || setByFlag["env-file"] {
+			return nil, fmt.Errorf("error reading env file %s: %w", *envFile, err)
+		}
+		fileValues = map[string]string{}
+	}
+
+	var setErr error
+	fset.VisitAll(func(f *flag.Flag) {
+		if setByFlag[f.Name] || f.Name == "env-file" {
+			return
+		}
+
+		envName := EnvName(f.Name)
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			value, ok = fileValues[envName]
+		}
+		if !ok {
+			return
+		}
+
+		err := fset.Set(f.Name, value)
+		if err != nil {
+			setErr = errors.Join(setErr, fmt.Errorf("invalid value for %s: %w", envName, err))
+		}
+	})
+	if setErr != nil {
+		return nil, setErr
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// positional arguments left after the flags, used by command line tools
+func (c *Config) Args() []string {
+	return c.flags.Args()
+}
+
+// Environment variable name for a flag, ex: listen-addr -> LISTEN_ADDR
+func EnvName(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Check every setting, returning all problems at once
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.ListenAddr == "" {
+		errs = append(errs, errors.New("listen-addr is required"))
+	}
+
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("database-url is required"))
+	} else if _, err := pgxpool.ParseConfig(c.Database.URL); err != nil {
+		// the parse error echoes the connection string so it isn't wrapped here
+		errs = append(errs, errors.New("database-url is not a valid postgres connection string"))
+	}
+	if c.Database.MaxConns < 1 {
+		errs = append(errs, errors.New("db-max-conns must be at least 1"))
+	}
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		errs = append(errs, errors.New("db-min-conns must be between 0 and db-max-conns"))
+	}
+
+	if c.Session.TTL <= 0 {
+		errs = append(errs, errors.New("session-ttl must be positive"))
+	}
+
+	sameSite, err := c.Cookie.SameSiteMode()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if sameSite == http.SameSiteNoneMode && !c.Cookie.Secure {
+		errs = append(errs, errors.New("cookie-same-site none requires cookie-secure"))
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				errs = append(errs, errors.New("cors-allowed-origins can't be * when cors-allow-credentials is set"))
+			}
+			continue
+		}
+
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" {
+			errs = append(errs, fmt.Errorf("invalid cors origin %q, expected scheme://host[:port]", origin))
+		}
+	}
+
+	if c.Scraper.Interval <= 0 || c.Scraper.Timeout <= 0 {
+		errs = append(errs, errors.New("scraper-interval and scraper-timeout must be positive"))
+	}
+	if c.Scraper.MaxConcurrency < 1 {
+		errs = append(errs, errors.New("scraper-max-concurrency must be at least 1"))
+	}
+
+	if c.RateLimit.Backend != "memory" && c.RateLimit.Backend != "postgres" {
+		errs = append(errs, fmt.Errorf("invalid rate-limit-backend %q, expected memory or postgres", c.RateLimit.Backend))
+	}
+	for name, limit := range map[string]ratelimit.Limit{
+		"rate-limit-default": 		c.RateLimit.Default,
+		"rate-limit-login": 		c.RateLimit.Login,
+		"rate-limit-add-product": 	c.RateLimit.AddProduct,
+		"rate-limit-import": 		c.RateLimit.Import,
+	} {
+		if err := limit.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		if _, err := parseProxy(proxy); err != nil {
+			errs = append(errs, fmt.Errorf("invalid rate-limit-trusted-proxies entry %q, expected an ip or cidr", proxy))
+		}
+	}
+
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, fmt.Errorf("invalid log-format %q, expected json or text", c.Log.Format))
+	}
+	var level slog.Level
+	if level.UnmarshalText([]byte(c.Log.Level)) != nil {
+		errs = append(errs, fmt.Errorf("invalid log-level %q", c.Log.Level))
+	}
+
+	if c.Tracing.EndpointURL != "" {
+		parsed, err := url.Parse(c.Tracing.EndpointURL)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid tracing-endpoint %q", c.Tracing.EndpointURL))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Log every setting with its effective value, secrets are redacted
+func (c *Config) LogEffective(logger *slog.Logger) {
+	var attrs []any
+
+	c.flags.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if secretSettings[f.Name] && value != "" {
+			value = redact(value)
+		}
+
+		attrs = append(attrs, slog.String(f.Name, value))
+	})
+
+	logger.Info("effective config", attrs...)
+}
+
+// parse the configured SameSite mode for the session cookie
+func (c CookieConfig) SameSiteMode() (http.SameSite, error) {
+	switch strings.ToLower(c.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return http.SameSiteDefaultMode, fmt.Errorf("invalid cookie-same-site %q, expected strict, lax or none", c.SameSite)
+	}
+}
+
+// the trusted proxies as ranges, entries were checked when the config was
+// loaded so invalid ones are skipped
+func (c RateLimitConfig) TrustedProxyPrefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, proxy := range c.TrustedProxies {
+		if prefix, err := parseProxy(proxy); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// a single ip is a range of just that address
+func parseProxy(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// tracing is disabled when no collector endpoint is configured
+func (c TraceConfig) Enabled() bool {
+	return c.EndpointURL != ""
+}
+
+// query params libpq reads passwords from, masked like the userinfo password
+var secretParams = []string{"password", "sslpassword"}
+
+// keep the host and database of connection strings visible so a
+// misconfigured url can still be spotted, but hide the password
+func redact(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme == "" {
+		return "[REDACTED]"
+	}
+
+	query := parsed.Query()
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Set(param, "xxxxx")
+		}
+	}
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = query.Encode()
+	}
+
+	return parsed.Redacted()
+}
+
+func envOrDefault(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
+
+// flag value for comma separated lists
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
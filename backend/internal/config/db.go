@@ -1,24 +1,28 @@
 package config
 
 import (
-	"log"
-	"os"
-	"time"
+	"backend/pkg/tracing"
+	"fmt"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 // Pgxpool postgres connection pool config values
-func DatabaseConfig() *pgxpool.Config { 
-	config, err := pgxpool.ParseConfig(os.Getenv("DATABASE_URL"))
+func DatabaseConfig(cfg DBConfig) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(cfg.URL)
 	if err != nil {
-		log.Fatalf("Unable to parse DATABASE_URL %v\n", err)
+		return nil, fmt.Errorf("unable to parse database url: %w", err)
 	}
 
-	config.MaxConns = 50
-	config.MinConns = 5
-	config.MaxConnLifetime = 30 * time.Minute
-	config.MaxConnIdleTime = 5 * time.Minute
-	config.HealthCheckPeriod = time.Minute
+	config.MaxConns = int32(cfg.MaxConns)
+	config.MinConns = int32(cfg.MinConns)
+	config.MaxConnLifetime = cfg.MaxConnLifetime
+	config.MaxConnIdleTime = cfg.MaxConnIdleTime
+	config.HealthCheckPeriod = cfg.HealthCheckPeriod
 
-	return config
-}
\ No newline at end of file
+	// every finished statement is reported as a child span of the request
+	config.ConnConfig.Logger = tracing.NewQueryTracer()
+	config.ConnConfig.LogLevel = pgx.LogLevelInfo
+
+	return config, nil
+}
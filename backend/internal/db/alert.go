@@ -0,0 +1,82 @@
+package db
+
+import (
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Add an active alert rule on a product of the user's watchlist, the threshold
+// is stored as NULL when left out and the price basis when empty. Returns the
+// rule with the price basis it's checked on
+func (r *Repository) InsertAlertRule(ctx context.Context, userID, productID int, input types.AlertRuleInput) (types.AlertRule, error) {
+	var rule types.AlertRule
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `
+			INSERT INTO alert_rules (user_id, product_id, rule_type, threshold, price_basis, active, created_at)
+			SELECT uw.user_id, uw.product_id, $3, $4, NULLIF($5, ''), true, NOW()
+			FROM user_watchlist uw
+			WHERE uw.user_id = $1
+			AND uw.product_id = $2
+			RETURNING
+				id, rule_type, COALESCE(threshold, 0)::float8,
+				COALESCE(price_basis, (SELECT price_basis FROM users WHERE id = $1)),
+				active, created_at`
+
+		err := tx.QueryRow(ctx, query, userID, productID, input.Type, input.Threshold, input.PriceBasis).Scan(
+			&rule.ID,
+			&rule.Type,
+			&rule.Threshold,
+			&rule.PriceBasis,
+			&rule.Active,
+			&rule.CreatedAt,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("product %d not found in user's watchlist: %w", productID, store.ErrNotFound)
+		}
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to insert alert rule", "user_id", userID, "product_id", productID, "err", err)
+		}
+		return types.AlertRule{}, err
+	}
+
+	logger.FromContext(ctx).Info("alert rule created", "user_id", userID, "product_id", productID, "alert_id", rule.ID)
+
+	return rule, nil
+}
+
+// Delete one of the user's alert rules
+func (r *Repository) DeleteAlertRule(ctx context.Context, userID, alertID int) error {
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM alert_rules WHERE id = $1 AND user_id = $2`, alertID, userID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("alert rule %d: %w", alertID, store.ErrNotFound)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to delete alert rule", "user_id", userID, "alert_id", alertID, "err", err)
+		}
+		return err
+	}
+
+	logger.FromContext(ctx).Info("alert rule deleted", "user_id", userID, "alert_id", alertID)
+
+	return nil
+}
@@ -0,0 +1,127 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for InsertAlertRule SQL func
+func TestInsertAlertRule(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	threshold := func(value float64) *float64 {
+		return &value
+	}
+
+	t.Run("adds rules on the given or the user's price basis", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+		test.SetPriceBasis(t, pool, userID, types.PriceBasisLanded)
+
+		rule, err := repo.InsertAlertRule(ctx, userID, productID, types.AlertRuleInput{Type: types.AlertPriceBelow, Threshold: threshold(150.25)})
+
+		require.NoError(t, err)
+		assert.NotZero(t, rule.ID)
+		assert.Equal(t, types.AlertPriceBelow, rule.Type)
+		assert.Equal(t, 150.25, rule.Threshold)
+		assert.Equal(t, types.PriceBasisLanded, rule.PriceBasis, "follows the user's preference")
+		assert.True(t, rule.Active)
+		assert.False(t, rule.CreatedAt.IsZero())
+
+		var storedBasis *string
+		err = pool.QueryRow(ctx, `SELECT price_basis FROM alert_rules WHERE id = $1`, rule.ID).Scan(&storedBasis)
+		require.NoError(t, err)
+		assert.Nil(t, storedBasis, "stored as NULL so it keeps following the preference")
+
+		rule, err = repo.InsertAlertRule(ctx, userID, productID, types.AlertRuleInput{Type: types.AlertPriceBelow, Threshold: threshold(150), PriceBasis: types.PriceBasisSticker})
+
+		require.NoError(t, err)
+		assert.Equal(t, types.PriceBasisSticker, rule.PriceBasis)
+
+		rule, err = repo.InsertAlertRule(ctx, userID, productID, types.AlertRuleInput{Type: types.AlertBackInStock})
+
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, rule.Threshold)
+
+		var storedThreshold *float64
+		err = pool.QueryRow(ctx, `SELECT threshold::float8 FROM alert_rules WHERE id = $1`, rule.ID).Scan(&storedThreshold)
+		require.NoError(t, err)
+		assert.Nil(t, storedThreshold)
+	})
+
+	t.Run("predicted_below rules show up in the forecast", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		for days := 9; days >= 0; days-- {
+			checkedAt := time.Now().AddDate(0, 0, -days)
+			test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 100 + float64(days)*5, InStock: true, CheckedAt: &checkedAt})
+		}
+
+		rule, err := repo.InsertAlertRule(ctx, userID, productID, types.AlertRuleInput{Type: types.AlertPredictedBelow, Threshold: threshold(95)})
+		require.NoError(t, err)
+
+		forecast, err := repo.FetchProductForecast(ctx, userID, productID)
+
+		require.NoError(t, err)
+		require.Len(t, forecast.Alerts, 1)
+		assert.Equal(t, rule.ID, forecast.Alerts[0].ID)
+		assert.NotNil(t, forecast.Alerts[0].PredictedAt, "prices keep falling below 95")
+	})
+
+	t.Run("products outside the user's watchlist return not found", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		otherID := test.SeedUser(t, pool, "user2", "user2@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, otherID, productID)
+
+		_, err := repo.InsertAlertRule(ctx, userID, productID, types.AlertRuleInput{Type: types.AlertPriceBelow, Threshold: threshold(100)})
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		_, err = repo.InsertAlertRule(ctx, userID, 999, types.AlertRuleInput{Type: types.AlertPriceBelow, Threshold: threshold(100)})
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+// Integration tests for DeleteAlertRule SQL func
+func TestDeleteAlertRule(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	test.CleanupTables(t, pool)
+
+	userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+	otherID := test.SeedUser(t, pool, "user2", "user2@example.com")
+	productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+	alertID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPriceBelow, Threshold: 100, Active: true})
+
+	err := repo.DeleteAlertRule(ctx, otherID, alertID)
+	assert.ErrorIs(t, err, store.ErrNotFound, "another user's rule")
+
+	err = repo.DeleteAlertRule(ctx, userID, alertID)
+	require.NoError(t, err)
+
+	err = repo.DeleteAlertRule(ctx, userID, alertID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"backend/internal/normalize"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Add products.normalized_name to a database created before product names
+// were normalized. Every product without one gets normalize.ProductName of
+// its name, products whose names normalize to the same key are merged into
+// the oldest of them like an admin merge, and only then are the NOT NULL and
+// UNIQUE constraints added. Runs in one transaction holding the products
+// table lock and is a no-op on databases that already have the column. The
+// product_aliases table from init.sql has to exist already
+func (r *Repository) BackfillNormalizedNames(ctx context.Context) (types.NameBackfill, error) {
+	backfill := types.NameBackfill{Merges: []types.ProductMerge{}}
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `ALTER TABLE products ADD COLUMN IF NOT EXISTS normalized_name VARCHAR`)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, `SELECT id, product_name FROM products WHERE normalized_name IS NULL ORDER BY id`)
+		if err != nil {
+			return err
+		}
+
+		names := map[int]string{}
+		var ids []int
+		for rows.Next() {
+			var id int
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+			names[id] = name
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			_, err := tx.Exec(ctx, `UPDATE products SET normalized_name = $2 WHERE id = $1`, id, normalize.ProductName(names[id]))
+			if err != nil {
+				return err
+			}
+		}
+		backfill.Normalized = len(ids)
+
+		// the oldest product of each key stays, including products that had
+		// a normalized name before
+		duplicatesQuery := `
+			SELECT ARRAY_AGG(id ORDER BY id)
+			FROM products
+			GROUP BY normalized_name
+			HAVING COUNT(*) > 1`
+
+		rows, err = tx.Query(ctx, duplicatesQuery)
+		if err != nil {
+			return err
+		}
+
+		var groups [][]int
+		for rows.Next() {
+			var group []int
+			if err := rows.Scan(&group); err != nil {
+				rows.Close()
+				return err
+			}
+			groups = append(groups, group)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			for _, duplicateID := range group[1:] {
+				merge, err := mergeProducts(ctx, tx, duplicateID, group[0])
+				if err != nil {
+					return err
+				}
+				backfill.Merges = append(backfill.Merges, merge)
+			}
+		}
+
+		_, err = tx.Exec(ctx, `ALTER TABLE products ALTER COLUMN normalized_name SET NOT NULL`)
+		if err != nil {
+			return err
+		}
+
+		// same name postgres gives the inline UNIQUE constraint of new databases
+		_, err = tx.Exec(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS products_normalized_name_key ON products(normalized_name)`)
+		return err
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to backfill normalized product names", "err", err)
+		return types.NameBackfill{}, err
+	}
+
+	logger.FromContext(ctx).Info("backfilled normalized product names", "normalized", backfill.Normalized, "merged", len(backfill.Merges))
+
+	return backfill, nil
+}
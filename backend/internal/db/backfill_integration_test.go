@@ -0,0 +1,82 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/pkg/test"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for BackfillNormalizedNames SQL func
+func TestBackfillNormalizedNames(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	// put products back to how databases looked before normalized names
+	dropNormalizedNames := func(t *testing.T) {
+		t.Helper()
+
+		for _, query := range []string{
+			`ALTER TABLE products DROP CONSTRAINT IF EXISTS products_normalized_name_key`,
+			`DROP INDEX IF EXISTS products_normalized_name_key`,
+			`ALTER TABLE products ALTER COLUMN normalized_name DROP NOT NULL`,
+			`UPDATE products SET normalized_name = NULL`,
+		} {
+			_, err := pool.Exec(ctx, query)
+			require.NoError(t, err, query)
+		}
+	}
+
+	t.Run("fills in names, merges duplicates and adds the constraints", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		canonicalID := test.SeedProduct(t, pool, "RTX 4090 16GB", "")
+		duplicateID := test.SeedProduct(t, pool, "rtx 4090 16 gb", "https://example.com/4090.jpg")
+		otherID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, userID, duplicateID)
+
+		dropNormalizedNames(t)
+
+		backfill, err := repo.BackfillNormalizedNames(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, backfill.Normalized)
+		require.Len(t, backfill.Merges, 1)
+		assert.Equal(t, canonicalID, backfill.Merges[0].CanonicalID, "the oldest product stays")
+		assert.Equal(t, duplicateID, backfill.Merges[0].DuplicateID)
+
+		var name string
+		err = pool.QueryRow(ctx, `SELECT normalized_name FROM products WHERE id = $1`, otherID).Scan(&name)
+		require.NoError(t, err)
+		assert.Equal(t, "ryzen 7 7700", name)
+
+		var watched int
+		err = pool.QueryRow(ctx, `SELECT product_id FROM user_watchlist WHERE user_id = $1`, userID).Scan(&watched)
+		require.NoError(t, err)
+		assert.Equal(t, canonicalID, watched, "watchers move to the canonical product")
+
+		_, err = pool.Exec(ctx, `INSERT INTO products (product_name) VALUES ('No Normalized Name')`)
+		assert.Error(t, err, "normalized_name is NOT NULL again")
+
+		_, err = pool.Exec(ctx, `INSERT INTO products (product_name, normalized_name) VALUES ('Ryzen 7  7700', 'ryzen 7 7700')`)
+		assert.Error(t, err, "normalized_name is UNIQUE again")
+	})
+
+	t.Run("does nothing on migrated databases", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+		test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+
+		backfill, err := repo.BackfillNormalizedNames(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, backfill.Normalized)
+		assert.Empty(t, backfill.Merges)
+	})
+}
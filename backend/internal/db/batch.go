@@ -0,0 +1,148 @@
+package db
+
+import (
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// returned from the transaction to roll back an atomic batch
+var errBatchFailed = errors.New("watchlist batch failed")
+
+// Run the operations on the user's watchlist in one transaction, each in its
+// own savepoint. Atomic batches roll back everything at the first failure
+// and the operations after it are skipped, best effort batches keep every
+// operation that succeeded
+func (r *Repository) BatchWatchlist(ctx context.Context, userID int, batch types.WatchlistBatch) (types.WatchlistBatchResult, error) {
+	result := types.WatchlistBatchResult{Mode: batch.Mode}
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		result.Results = make([]types.WatchlistOperationResult, 0, len(batch.Operations))
+
+		for i, op := range batch.Operations {
+			opResult, err := runWatchlistOperation(ctx, tx, userID, op)
+			opResult.Index = i
+			opResult.Op = op.Op
+
+			if err != nil {
+				opResult.Status = types.BatchStatusFailed
+				opResult.Error = watchlistOperationError(ctx, userID, op, err)
+				result.Results = append(result.Results, opResult)
+
+				if batch.Mode == types.BatchModeAtomic {
+					return errBatchFailed
+				}
+				continue
+			}
+
+			opResult.Status = types.BatchStatusOK
+			result.Results = append(result.Results, opResult)
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errBatchFailed) {
+		for i := range result.Results {
+			if result.Results[i].Status == types.BatchStatusOK {
+				result.Results[i].Status = types.BatchStatusRolledBack
+				result.Results[i].Entry = nil
+			}
+		}
+		for i := len(result.Results); i < len(batch.Operations); i++ {
+			result.Results = append(result.Results, types.WatchlistOperationResult{
+				Index: 	i,
+				Op: 	batch.Operations[i].Op,
+				Status: types.BatchStatusSkipped,
+			})
+		}
+	} else if err != nil {
+		logger.FromContext(ctx).Error("failed to run watchlist batch", "user_id", userID, "err", err)
+		return types.WatchlistBatchResult{}, err
+	} else {
+		result.Committed = true
+	}
+
+	for _, opResult := range result.Results {
+		switch opResult.Status {
+		case types.BatchStatusOK:
+			result.Succeeded++
+		case types.BatchStatusFailed:
+			result.Failed++
+		}
+	}
+
+	logger.FromContext(ctx).Info("watchlist batch run", "user_id", userID, "mode", batch.Mode,
+		"operations", len(batch.Operations), "committed", result.Committed, "failed", result.Failed)
+
+	return result, nil
+}
+
+// run one operation in a savepoint, rolled back when it fails
+func runWatchlistOperation(ctx context.Context, tx pgx.Tx, userID int, op types.WatchlistOperation) (types.WatchlistOperationResult, error) {
+	var result types.WatchlistOperationResult
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer savepoint.Rollback(ctx)
+
+	switch op.Op {
+	case types.BatchOpAdd:
+		var product types.Product
+		if op.Source != nil {
+			product, err = findOrCreateSourceProduct(ctx, savepoint, op.ProductName, *op.Source)
+		} else {
+			product, err = findOrCreateProduct(ctx, savepoint, op.ProductName)
+		}
+		if err != nil {
+			return result, err
+		}
+
+		result.ProductID = product.ID
+		result.ProductName = product.Name
+		err = addToWatchlist(ctx, savepoint, userID, product)
+
+	case types.BatchOpDelete:
+		result.ProductID = op.ProductID
+		err = deleteFromWatchlist(ctx, savepoint, userID, op.ProductID)
+
+	case types.BatchOpUpdate:
+		result.ProductID = op.ProductID
+		var entry types.WatchlistEntry
+		entry, err = updateWatchlistEntry(ctx, savepoint, userID, op.ProductID, *op.Update)
+		result.Entry = &entry
+	}
+
+	if err != nil {
+		result.Entry = nil
+		return result, err
+	}
+
+	return result, savepoint.Commit(ctx)
+}
+
+// the message for a failed operation, unexpected errors are logged and
+// reported as a database error
+func watchlistOperationError(ctx context.Context, userID int, op types.WatchlistOperation, err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return "product already in user's watchlist"
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return "product not found in user's watchlist"
+	}
+	if errors.Is(err, store.ErrConflict) {
+		return "a platform can't be both preferred and excluded"
+	}
+
+	logger.FromContext(ctx).Error("failed to run watchlist operation", "user_id", userID, "op", op.Op, "product_id", op.ProductID, "err", err)
+	return "database error"
+}
@@ -0,0 +1,704 @@
+package db
+
+import (
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// collection item counts and totals priced on the owner's watchlist by the
+// tracked products CTE. Members of a shared collection see the owner's prices
+const collectionSummarySelect = `
+	SELECT
+		c.id, c.user_id, (SELECT username FROM users WHERE id = c.user_id),
+		c.name, COALESCE(c.description, ''), c.created_at,
+		COUNT(t.product_id),
+		COALESCE(SUM(t.lowest_price * ci.quantity) FILTER (WHERE t.lowest_price > 0), 0)::float8,
+		COUNT(t.product_id) FILTER (WHERE t.lowest_price = 0),
+		COALESCE(ds.display_currency, '')
+	FROM collections c
+	LEFT JOIN display ds ON ds.user_id = c.user_id
+	LEFT JOIN collection_items ci ON ci.collection_id = c.id
+	LEFT JOIN tracked t ON t.user_id = c.user_id AND t.product_id = ci.product_id`
+
+// collections owned by the user ($1) with their item counts and totals
+const collectionSummaryQuery = trackedProductsCTE + collectionSummarySelect + `
+	WHERE c.user_id = $1`
+
+// collections shared with the user ($1) with their item counts, totals and
+// the user's role, priced for all of their owners at once
+const sharedCollectionsQuery = `
+	WITH watchers AS (
+		SELECT DISTINCT c.user_id
+		FROM collection_members cm
+		INNER JOIN collections c ON cm.collection_id = c.id
+		WHERE cm.user_id = $1
+	),` + trackedProductsBody + collectionSummarySelect + `
+	INNER JOIN collection_members cm ON cm.collection_id = c.id AND cm.user_id = $1
+	GROUP BY c.id, ds.display_currency, cm.role
+	ORDER BY c.name ASC, c.id ASC`
+
+func scanCollection(row pgx.Row, extra ...interface{}) (types.Collection, error) {
+	var collection types.Collection
+
+	dest := []interface{}{
+		&collection.ID,
+		&collection.OwnerID,
+		&collection.Owner,
+		&collection.Name,
+		&collection.Description,
+		&collection.CreatedAt,
+		&collection.ItemCount,
+		&collection.Total,
+		&collection.UnpricedCount,
+		&collection.Currency,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+
+	return collection, err
+}
+
+// Fetch the user's own collections sorted by name along with their totals,
+// followed by the collections shared with them
+func (r *Repository) FetchCollections(ctx context.Context, userID int) ([]types.Collection, error) {
+	collections := []types.Collection{}
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := collectionSummaryQuery + `
+			GROUP BY c.id, ds.display_currency
+			ORDER BY c.name ASC, c.id ASC`
+
+		rows, err := tx.Query(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			collection, err := scanCollection(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			collection.Role = types.CollectionRoleOwner
+			collections = append(collections, collection)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		rows, err = tx.Query(ctx, sharedCollectionsQuery, userID)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var role string
+
+			collection, err := scanCollection(rows, &role)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			collection.Role = role
+			collections = append(collections, collection)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to fetch collections", "user_id", userID, "err", err)
+		return []types.Collection{}, err
+	}
+
+	return collections, nil
+}
+
+// Fetch a collection the user owns or is a member of with its items in the
+// order they were added, its members and their alerts on the items
+func (r *Repository) FetchCollection(ctx context.Context, userID, collectionID int) (types.Collection, error) {
+	var collection types.Collection
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		collection, err = fetchCollection(ctx, tx, userID, collectionID)
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to fetch collection", "user_id", userID, "collection_id", collectionID, "err", err)
+		}
+		return types.Collection{}, err
+	}
+
+	return collection, nil
+}
+
+// Role of the user in the collection, not found when they are neither the
+// owner nor a member so other users' collections stay hidden
+func (r *Repository) FetchCollectionAccess(ctx context.Context, userID, collectionID int) (types.CollectionAccess, error) {
+	var access types.CollectionAccess
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		access, err = collectionAccess(ctx, tx, userID, collectionID)
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to fetch collection access", "user_id", userID, "collection_id", collectionID, "err", err)
+		}
+		return types.CollectionAccess{}, err
+	}
+
+	return access, nil
+}
+
+func collectionAccess(ctx context.Context, tx pgx.Tx, userID, collectionID int) (types.CollectionAccess, error) {
+	access := types.CollectionAccess{CollectionID: collectionID}
+
+	query := `
+		SELECT c.user_id, CASE WHEN c.user_id = $1 THEN $3 ELSE cm.role END
+		FROM collections c
+		LEFT JOIN collection_members cm ON cm.collection_id = c.id AND cm.user_id = $1
+		WHERE c.id = $2
+		AND (c.user_id = $1 OR cm.user_id IS NOT NULL)`
+
+	err := tx.QueryRow(ctx, query, userID, collectionID, types.CollectionRoleOwner).Scan(&access.OwnerID, &access.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return types.CollectionAccess{}, fmt.Errorf("collection %d: %w", collectionID, store.ErrNotFound)
+	}
+	if err != nil {
+		return types.CollectionAccess{}, err
+	}
+
+	return access, nil
+}
+
+// access checked against the action, forbidden when the user can see the
+// collection but their role doesn't allow the change
+func requireCollectionRole(ctx context.Context, tx pgx.Tx, userID, collectionID int, allowed func(types.CollectionAccess) bool) (types.CollectionAccess, error) {
+	access, err := collectionAccess(ctx, tx, userID, collectionID)
+	if err != nil {
+		return types.CollectionAccess{}, err
+	}
+	if !allowed(access) {
+		return types.CollectionAccess{}, fmt.Errorf("%s of collection %d: %w", access.Role, collectionID, store.ErrForbidden)
+	}
+	return access, nil
+}
+
+func isCollectionOwner(access types.CollectionAccess) bool {
+	return access.Role == types.CollectionRoleOwner
+}
+
+func canEditCollection(access types.CollectionAccess) bool {
+	return access.CanEdit()
+}
+
+// collection row with its totals from the owner's watchlist
+func fetchCollectionSummary(ctx context.Context, tx pgx.Tx, access types.CollectionAccess) (types.Collection, error) {
+	query := collectionSummaryQuery + `
+		AND c.id = $2
+		GROUP BY c.id, ds.display_currency`
+
+	collection, err := scanCollection(tx.QueryRow(ctx, query, access.OwnerID, access.CollectionID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return types.Collection{}, fmt.Errorf("collection %d: %w", access.CollectionID, store.ErrNotFound)
+	}
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	collection.Role = access.Role
+	return collection, nil
+}
+
+// collection queries run on the caller's transaction so changes can return
+// the collection they just made
+func fetchCollection(ctx context.Context, tx pgx.Tx, userID, collectionID int) (types.Collection, error) {
+	access, err := collectionAccess(ctx, tx, userID, collectionID)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	collection, err := fetchCollectionSummary(ctx, tx, access)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	itemsQuery := trackedProductsCTE + `
+		SELECT ` + trackedProductsColumns + `, items.quantity
+		FROM tracked
+		INNER JOIN (
+			SELECT product_id, quantity, added_at as item_added_at
+			FROM collection_items
+			WHERE collection_id = $2
+		) items USING (product_id)
+		ORDER BY items.item_added_at ASC, product_id ASC`
+
+	rows, err := tx.Query(ctx, itemsQuery, access.OwnerID, collectionID)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	collection.Items = []types.CollectionItem{}
+	for rows.Next() {
+		var item types.CollectionItem
+
+		item.UserProduct, err = scanUserProduct(rows, &item.Quantity)
+		if err != nil {
+			rows.Close()
+			return types.Collection{}, err
+		}
+
+		collection.Items = append(collection.Items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return types.Collection{}, err
+	}
+
+	collection.Members, err = fetchCollectionMembers(ctx, tx, collectionID)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	// everyone with access sees the alerts the owner and members set on the items
+	alertsQuery := `
+		SELECT
+			ar.id, ar.rule_type, COALESCE(ar.threshold, 0), COALESCE(ar.price_basis, u.price_basis),
+			ar.active, ar.created_at, ar.product_id, ar.user_id, u.username
+		FROM alert_rules ar
+		INNER JOIN users u ON ar.user_id = u.id
+		INNER JOIN collection_items ci ON ci.product_id = ar.product_id
+		INNER JOIN collections c ON ci.collection_id = c.id
+		WHERE c.id = $1
+		AND (
+			ar.user_id = c.user_id
+			OR ar.user_id IN (SELECT user_id FROM collection_members WHERE collection_id = $1)
+		)
+		ORDER BY ar.product_id ASC, ar.created_at ASC, ar.id ASC`
+
+	rows, err = tx.Query(ctx, alertsQuery, collectionID)
+	if err != nil {
+		return types.Collection{}, err
+	}
+	defer rows.Close()
+
+	collection.Alerts = []types.CollectionAlert{}
+	for rows.Next() {
+		var alert types.CollectionAlert
+
+		err := rows.Scan(
+			&alert.ID,
+			&alert.Type,
+			&alert.Threshold,
+			&alert.PriceBasis,
+			&alert.Active,
+			&alert.CreatedAt,
+			&alert.ProductID,
+			&alert.UserID,
+			&alert.Username,
+		)
+		if err != nil {
+			return types.Collection{}, err
+		}
+
+		collection.Alerts = append(collection.Alerts, alert)
+	}
+
+	return collection, rows.Err()
+}
+
+func fetchCollectionMembers(ctx context.Context, tx pgx.Tx, collectionID int) ([]types.CollectionMember, error) {
+	query := `
+		SELECT cm.user_id, u.username, cm.role, cm.added_at
+		FROM collection_members cm
+		INNER JOIN users u ON cm.user_id = u.id
+		WHERE cm.collection_id = $1
+		ORDER BY cm.added_at ASC, cm.user_id ASC`
+
+	rows, err := tx.Query(ctx, query, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []types.CollectionMember{}
+	for rows.Next() {
+		var member types.CollectionMember
+
+		err := rows.Scan(&member.UserID, &member.Username, &member.Role, &member.AddedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// Create an empty collection for the user, errors with a unique violation
+// when the user already has one with the same name
+func (r *Repository) InsertCollection(ctx context.Context, userID int, input types.CollectionInput) (types.Collection, error) {
+	var collection types.Collection
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		var collectionID int
+
+		query := `
+			INSERT INTO collections (user_id, name, description, created_at)
+			VALUES ($1, $2, NULLIF($3, ''), NOW())
+			RETURNING id`
+
+		err := tx.QueryRow(ctx, query, userID, input.Name, input.Description).Scan(&collectionID)
+		if err != nil {
+			return err
+		}
+
+		collection, err = fetchCollection(ctx, tx, userID, collectionID)
+		return err
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to insert collection", "user_id", userID, "err", err)
+		return types.Collection{}, err
+	}
+
+	logger.FromContext(ctx).Info("collection created", "user_id", userID, "collection_id", collection.ID)
+
+	return collection, nil
+}
+
+// Rename the collection or change its description, only the owner can and
+// only the fields set on the update are written
+func (r *Repository) UpdateCollection(ctx context.Context, userID, collectionID int, update types.CollectionUpdate) (types.Collection, error) {
+	var collection types.Collection
+
+	args := []interface{}{userID, collectionID}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	sets := []string{}
+	if update.Name != nil {
+		sets = append(sets, "name = "+addArg(*update.Name))
+	}
+	if update.Description != nil {
+		sets = append(sets, "description = NULLIF("+addArg(*update.Description)+", '')")
+	}
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := requireCollectionRole(ctx, tx, userID, collectionID, isCollectionOwner)
+		if err != nil {
+			return err
+		}
+
+		if len(sets) > 0 {
+			query := `
+				UPDATE collections
+				SET ` + strings.Join(sets, ", ") + `
+				WHERE user_id = $1 AND id = $2`
+
+			_, err := tx.Exec(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+		}
+
+		collection, err = fetchCollection(ctx, tx, userID, collectionID)
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrForbidden) {
+			logger.FromContext(ctx).Error("failed to update collection", "user_id", userID, "collection_id", collectionID, "err", err)
+		}
+		return types.Collection{}, err
+	}
+
+	return collection, nil
+}
+
+// Delete the collection, only the owner can and the products stay in the
+// watchlist
+func (r *Repository) DeleteCollection(ctx context.Context, userID, collectionID int) error {
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := requireCollectionRole(ctx, tx, userID, collectionID, isCollectionOwner)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM collections WHERE user_id = $1 AND id = $2`, userID, collectionID)
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrForbidden) {
+			logger.FromContext(ctx).Error("failed to delete collection", "user_id", userID, "collection_id", collectionID, "err", err)
+		}
+		return err
+	}
+
+	logger.FromContext(ctx).Info("collection deleted", "user_id", userID, "collection_id", collectionID)
+
+	return nil
+}
+
+// Add a product to the collection or change its quantity when it is already
+// in there, the owner and editors can. The product has to be on the owner's
+// watchlist so the collection total includes it, editors can't add products
+// the owner doesn't watch. Returns the updated collection
+func (r *Repository) SetCollectionItem(ctx context.Context, userID, collectionID, productID, quantity int) (types.Collection, error) {
+	var collection types.Collection
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		access, err := requireCollectionRole(ctx, tx, userID, collectionID, canEditCollection)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO collection_items (collection_id, product_id, quantity, added_at)
+			SELECT $1, product_id, $4, NOW()
+			FROM user_watchlist
+			WHERE user_id = $2
+			AND product_id = $3
+			ON CONFLICT (collection_id, product_id) DO UPDATE
+				SET quantity = EXCLUDED.quantity`
+
+		tag, err := tx.Exec(ctx, query, collectionID, access.OwnerID, productID, quantity)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("product %d in owner's watchlist: %w", productID, store.ErrNotFound)
+		}
+
+		collection, err = fetchCollection(ctx, tx, userID, collectionID)
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrForbidden) {
+			logger.FromContext(ctx).Error("failed to set collection item", "user_id", userID, "collection_id", collectionID, "product_id", productID, "err", err)
+		}
+		return types.Collection{}, err
+	}
+
+	return collection, nil
+}
+
+// Remove a product from the collection, the owner and editors can and it
+// stays in the watchlist
+func (r *Repository) DeleteCollectionItem(ctx context.Context, userID, collectionID, productID int) error {
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := requireCollectionRole(ctx, tx, userID, collectionID, canEditCollection)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			DELETE FROM collection_items
+			WHERE collection_id = $1
+			AND product_id = $2`
+
+		tag, err := tx.Exec(ctx, query, collectionID, productID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("product %d in collection %d: %w", productID, collectionID, store.ErrNotFound)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrForbidden) {
+			logger.FromContext(ctx).Error("failed to delete collection item", "user_id", userID, "collection_id", collectionID, "product_id", productID, "err", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Fetch every tag on the user's watchlist with how many entries use it, the
+// most used first
+func (r *Repository) FetchUserTags(ctx context.Context, userID int) ([]types.TagCount, error) {
+	tags := []types.TagCount{}
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `
+			SELECT tag, COUNT(*)
+			FROM user_watchlist, UNNEST(tags) tag
+			WHERE user_id = $1
+			GROUP BY tag
+			ORDER BY COUNT(*) DESC, tag ASC`
+
+		rows, err := tx.Query(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var tag types.TagCount
+
+			err := rows.Scan(&tag.Tag, &tag.Count)
+			if err != nil {
+				return err
+			}
+
+			tags = append(tags, tag)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to fetch user tags", "user_id", userID, "err", err)
+		return []types.TagCount{}, err
+	}
+
+	return tags, nil
+}
+
+// Share the collection with the user matching the invite's username or email,
+// only the owner can. Inviting a member again changes their role
+func (r *Repository) AddCollectionMember(ctx context.Context, userID, collectionID int, invite types.CollectionInvite) (types.CollectionMember, error) {
+	var member types.CollectionMember
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		access, err := requireCollectionRole(ctx, tx, userID, collectionID, isCollectionOwner)
+		if err != nil {
+			return err
+		}
+
+		// usernames are matched exactly, emails ignoring case
+		var memberID int
+		userQuery := `
+			SELECT id, username FROM users
+			WHERE ($1 <> '' AND username = $1)
+			OR ($2 <> '' AND LOWER(email) = LOWER($2))
+			LIMIT 1`
+
+		err = tx.QueryRow(ctx, userQuery, invite.Username, invite.Email).Scan(&memberID, &member.Username)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("user to invite: %w", store.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		if memberID == access.OwnerID {
+			return fmt.Errorf("owner of collection %d can't be a member: %w", collectionID, store.ErrForbidden)
+		}
+
+		query := `
+			INSERT INTO collection_members (collection_id, user_id, role, added_at)
+			VALUES ($1, $2, $3, NOW())
+			ON CONFLICT (collection_id, user_id) DO UPDATE
+				SET role = EXCLUDED.role
+			RETURNING user_id, role, added_at`
+
+		return tx.QueryRow(ctx, query, collectionID, memberID, invite.Role).Scan(&member.UserID, &member.Role, &member.AddedAt)
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrForbidden) {
+			logger.FromContext(ctx).Error("failed to add collection member", "user_id", userID, "collection_id", collectionID, "err", err)
+		}
+		return types.CollectionMember{}, err
+	}
+
+	logger.FromContext(ctx).Info("collection shared", "user_id", userID, "collection_id", collectionID, "member_id", member.UserID, "role", member.Role)
+
+	return member, nil
+}
+
+// Change the role of a member, only the owner can
+func (r *Repository) UpdateCollectionMember(ctx context.Context, userID, collectionID, memberID int, role string) (types.CollectionMember, error) {
+	var member types.CollectionMember
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := requireCollectionRole(ctx, tx, userID, collectionID, isCollectionOwner)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE collection_members cm
+			SET role = $3
+			FROM users u
+			WHERE cm.user_id = u.id
+			AND cm.collection_id = $1
+			AND cm.user_id = $2
+			RETURNING cm.user_id, u.username, cm.role, cm.added_at`
+
+		err = tx.QueryRow(ctx, query, collectionID, memberID, role).Scan(&member.UserID, &member.Username, &member.Role, &member.AddedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("member %d of collection %d: %w", memberID, collectionID, store.ErrNotFound)
+		}
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrForbidden) {
+			logger.FromContext(ctx).Error("failed to update collection member", "user_id", userID, "collection_id", collectionID, "member_id", memberID, "err", err)
+		}
+		return types.CollectionMember{}, err
+	}
+
+	return member, nil
+}
+
+// Stop sharing the collection with a member, the owner can remove anyone and
+// members can remove themselves to leave the collection
+func (r *Repository) DeleteCollectionMember(ctx context.Context, userID, collectionID, memberID int) error {
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := requireCollectionRole(ctx, tx, userID, collectionID, func(access types.CollectionAccess) bool {
+			return access.Role == types.CollectionRoleOwner || userID == memberID
+		})
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `DELETE FROM collection_members WHERE collection_id = $1 AND user_id = $2`, collectionID, memberID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("member %d of collection %d: %w", memberID, collectionID, store.ErrNotFound)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrForbidden) {
+			logger.FromContext(ctx).Error("failed to delete collection member", "user_id", userID, "collection_id", collectionID, "member_id", memberID, "err", err)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,335 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for the collection SQL funcs
+func TestCollections(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	// seeds a watched product with one amazon offer at the price, 0 for no offer
+	watch := func(t *testing.T, userID int, name string, price float64) int {
+		productID := test.SeedProduct(t, pool, name, "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+		if price > 0 {
+			sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+				ProductID: 	productID,
+				Platform: 	"amazon",
+				URL: 		"https://amazon.com/" + name,
+			})
+			test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: price, InStock: true})
+		}
+		return productID
+	}
+
+	t.Run("creates, renames and deletes collections", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		collection, err := repo.InsertCollection(ctx, userID, types.CollectionInput{Name: "Homelab build"})
+		require.NoError(t, err)
+		assert.Equal(t, "Homelab build", collection.Name)
+		assert.Empty(t, collection.Items)
+		assert.Equal(t, "USD", collection.Currency)
+
+		_, err = repo.InsertCollection(ctx, userID, types.CollectionInput{Name: "Homelab build"})
+		assert.Error(t, err, "names are unique per user")
+
+		name, description := "Rack", "12U rack parts"
+		collection, err = repo.UpdateCollection(ctx, userID, collection.ID, types.CollectionUpdate{Name: &name, Description: &description})
+		require.NoError(t, err)
+		assert.Equal(t, "Rack", collection.Name)
+		assert.Equal(t, "12U rack parts", collection.Description)
+
+		otherID := test.SeedUser(t, pool, "user2", "user2@example.com")
+		_, err = repo.UpdateCollection(ctx, otherID, collection.ID, types.CollectionUpdate{Name: &name})
+		assert.ErrorIs(t, err, store.ErrNotFound, "other users can't change it")
+
+		require.NoError(t, repo.DeleteCollection(ctx, userID, collection.ID))
+		assert.ErrorIs(t, repo.DeleteCollection(ctx, userID, collection.ID), store.ErrNotFound)
+	})
+
+	t.Run("totals the lowest price of the items times their quantity", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		cpuID := watch(t, userID, "Ryzen 7 7700", 300)
+		ramID := watch(t, userID, "DDR5 16GB", 50)
+		caseID := watch(t, userID, "Rack Case", 0)
+		notWatchedID := test.SeedProduct(t, pool, "Not Watched", "")
+
+		collection, err := repo.InsertCollection(ctx, userID, types.CollectionInput{Name: "Homelab build"})
+		require.NoError(t, err)
+
+		_, err = repo.SetCollectionItem(ctx, userID, collection.ID, cpuID, 1)
+		require.NoError(t, err)
+		_, err = repo.SetCollectionItem(ctx, userID, collection.ID, ramID, 2)
+		require.NoError(t, err)
+		_, err = repo.SetCollectionItem(ctx, userID, collection.ID, caseID, 1)
+		require.NoError(t, err)
+		collection, err = repo.SetCollectionItem(ctx, userID, collection.ID, ramID, 4)
+		require.NoError(t, err)
+
+		_, err = repo.SetCollectionItem(ctx, userID, collection.ID, notWatchedID, 1)
+		assert.ErrorIs(t, err, store.ErrNotFound, "only watched products can be added")
+
+		assert.Equal(t, 3, collection.ItemCount)
+		assert.Equal(t, 500.0, collection.Total)
+		assert.Equal(t, 1, collection.UnpricedCount)
+		require.Len(t, collection.Items, 3)
+		assert.Equal(t, cpuID, collection.Items[0].ProductID)
+		assert.Equal(t, 4, collection.Items[1].Quantity)
+
+		collections, err := repo.FetchCollections(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, collections, 1)
+		assert.Equal(t, 500.0, collections[0].Total)
+		assert.Nil(t, collections[0].Items)
+
+		require.NoError(t, repo.DeleteCollectionItem(ctx, userID, collection.ID, ramID))
+		assert.ErrorIs(t, repo.DeleteCollectionItem(ctx, userID, collection.ID, ramID), store.ErrNotFound)
+
+		// leaving the watchlist drops the product from the collection too
+		require.NoError(t, repo.DeleteProductForUser(ctx, userID, cpuID))
+
+		collection, err = repo.FetchCollection(ctx, userID, collection.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, collection.ItemCount)
+		assert.Zero(t, collection.Total)
+	})
+
+	t.Run("filters the tracked products by collection and tags", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		cpuID := watch(t, userID, "Ryzen 7 7700", 300)
+		gpuID := watch(t, userID, "RTX 4070", 550)
+		watch(t, userID, "Desk Lamp", 20)
+
+		collection, err := repo.InsertCollection(ctx, userID, types.CollectionInput{Name: "Homelab build"})
+		require.NoError(t, err)
+		_, err = repo.SetCollectionItem(ctx, userID, collection.ID, cpuID, 1)
+		require.NoError(t, err)
+
+		tags := []string{"pc", "deals"}
+		_, err = repo.UpdateWatchlistEntry(ctx, userID, gpuID, types.WatchlistUpdate{Tags: &tags})
+		require.NoError(t, err)
+		tags = []string{"pc"}
+		_, err = repo.UpdateWatchlistEntry(ctx, userID, cpuID, types.WatchlistUpdate{Tags: &tags})
+		require.NoError(t, err)
+
+		page, err := repo.FetchUserTrackedProductsPage(ctx, userID, types.ProductListQuery{
+			Sort: 			types.SortByName,
+			Order: 			types.SortAsc,
+			Limit: 			10,
+			CollectionID: 	&collection.ID,
+		})
+		require.NoError(t, err)
+		require.Len(t, page.Products, 1)
+		assert.Equal(t, cpuID, page.Products[0].ProductID)
+		assert.Equal(t, []string{"pc"}, page.Products[0].Tags)
+
+		page, err = repo.FetchUserTrackedProductsPage(ctx, userID, types.ProductListQuery{
+			Sort: 	types.SortByName,
+			Order: 	types.SortAsc,
+			Limit: 	10,
+			Tags: 	[]string{"pc", "deals"},
+		})
+		require.NoError(t, err)
+		require.Len(t, page.Products, 1)
+		assert.Equal(t, gpuID, page.Products[0].ProductID)
+
+		tagCounts, err := repo.FetchUserTags(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, []types.TagCount{{Tag: "pc", Count: 2}, {Tag: "deals", Count: 1}}, tagCounts)
+	})
+}
+
+// Integration tests for sharing collections with members
+func TestSharedCollections(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	t.Run("members read with their role and only editors change items", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		ownerID := test.SeedUser(t, pool, "owner", "owner@example.com")
+		viewerID := test.SeedUser(t, pool, "viewer", "viewer@example.com")
+		editorID := test.SeedUser(t, pool, "editor", "editor@example.com")
+		outsiderID := test.SeedUser(t, pool, "outsider", "outsider@example.com")
+
+		cpuID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, ownerID, cpuID)
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: cpuID, Platform: "amazon", URL: "https://amazon.com/cpu"})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 300, InStock: true})
+
+		collection, err := repo.InsertCollection(ctx, ownerID, types.CollectionInput{Name: "Homelab build"})
+		require.NoError(t, err)
+		_, err = repo.SetCollectionItem(ctx, ownerID, collection.ID, cpuID, 1)
+		require.NoError(t, err)
+
+		member, err := repo.AddCollectionMember(ctx, ownerID, collection.ID, types.CollectionInvite{Username: "viewer", Role: types.CollectionRoleViewer})
+		require.NoError(t, err)
+		assert.Equal(t, viewerID, member.UserID)
+		member, err = repo.AddCollectionMember(ctx, ownerID, collection.ID, types.CollectionInvite{Email: "EDITOR@example.com", Role: types.CollectionRoleEditor})
+		require.NoError(t, err)
+		assert.Equal(t, "editor", member.Username)
+
+		_, err = repo.AddCollectionMember(ctx, ownerID, collection.ID, types.CollectionInvite{Username: "nobody", Role: types.CollectionRoleViewer})
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = repo.AddCollectionMember(ctx, ownerID, collection.ID, types.CollectionInvite{Username: "owner", Role: types.CollectionRoleViewer})
+		assert.ErrorIs(t, err, store.ErrForbidden)
+		_, err = repo.AddCollectionMember(ctx, editorID, collection.ID, types.CollectionInvite{Username: "outsider", Role: types.CollectionRoleViewer})
+		assert.ErrorIs(t, err, store.ErrForbidden, "only the owner shares")
+
+		// the viewer sees the owner's prices and the shared alerts
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: viewerID, ProductID: cpuID, RuleType: types.AlertPriceBelow, Threshold: 250, Active: true})
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: outsiderID, ProductID: cpuID, RuleType: types.AlertPriceBelow, Threshold: 200, Active: true})
+
+		shared, err := repo.FetchCollection(ctx, viewerID, collection.ID)
+		require.NoError(t, err)
+		assert.Equal(t, types.CollectionRoleViewer, shared.Role)
+		assert.Equal(t, "owner", shared.Owner)
+		assert.Equal(t, 300.0, shared.Total)
+		require.Len(t, shared.Items, 1)
+		require.Len(t, shared.Members, 2)
+		require.Len(t, shared.Alerts, 1, "outsiders' alerts stay private")
+		assert.Equal(t, "viewer", shared.Alerts[0].Username)
+		assert.Equal(t, 250.0, shared.Alerts[0].Threshold)
+
+		collections, err := repo.FetchCollections(ctx, viewerID)
+		require.NoError(t, err)
+		require.Len(t, collections, 1)
+		assert.Equal(t, types.CollectionRoleViewer, collections[0].Role)
+		assert.Equal(t, 300.0, collections[0].Total)
+
+		_, err = repo.FetchCollection(ctx, outsiderID, collection.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		access, err := repo.FetchCollectionAccess(ctx, editorID, collection.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ownerID, access.OwnerID)
+		assert.True(t, access.CanEdit())
+
+		// viewers can't change anything
+		_, err = repo.SetCollectionItem(ctx, viewerID, collection.ID, cpuID, 2)
+		assert.ErrorIs(t, err, store.ErrForbidden)
+		name := "Mine now"
+		_, err = repo.UpdateCollection(ctx, viewerID, collection.ID, types.CollectionUpdate{Name: &name})
+		assert.ErrorIs(t, err, store.ErrForbidden)
+		assert.ErrorIs(t, repo.DeleteCollection(ctx, editorID, collection.ID), store.ErrForbidden)
+
+		// editors can't add products the owner doesn't watch
+		ramID := test.SeedProduct(t, pool, "DDR5 16GB", "")
+		test.AddProductToWatchlist(t, pool, editorID, ramID)
+
+		_, err = repo.SetCollectionItem(ctx, editorID, collection.ID, ramID, 2)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		ownerProducts, err := repo.FetchUserTrackedProducts(ctx, ownerID)
+		require.NoError(t, err)
+		assert.Len(t, ownerProducts, 1, "the owner's watchlist is left alone")
+
+		// editors add from the owner's watchlist
+		test.AddProductToWatchlist(t, pool, ownerID, ramID)
+
+		shared, err = repo.SetCollectionItem(ctx, editorID, collection.ID, ramID, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, shared.ItemCount)
+
+		require.NoError(t, repo.DeleteCollectionItem(ctx, editorID, collection.ID, ramID))
+	})
+
+	t.Run("owners manage members and members can leave", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		ownerID := test.SeedUser(t, pool, "owner", "owner@example.com")
+		viewerID := test.SeedUser(t, pool, "viewer", "viewer@example.com")
+		editorID := test.SeedUser(t, pool, "editor", "editor@example.com")
+
+		collection, err := repo.InsertCollection(ctx, ownerID, types.CollectionInput{Name: "Homelab build"})
+		require.NoError(t, err)
+		_, err = repo.AddCollectionMember(ctx, ownerID, collection.ID, types.CollectionInvite{Username: "viewer", Role: types.CollectionRoleViewer})
+		require.NoError(t, err)
+		_, err = repo.AddCollectionMember(ctx, ownerID, collection.ID, types.CollectionInvite{Username: "editor", Role: types.CollectionRoleViewer})
+		require.NoError(t, err)
+
+		member, err := repo.UpdateCollectionMember(ctx, ownerID, collection.ID, editorID, types.CollectionRoleEditor)
+		require.NoError(t, err)
+		assert.Equal(t, types.CollectionRoleEditor, member.Role)
+		_, err = repo.UpdateCollectionMember(ctx, editorID, collection.ID, viewerID, types.CollectionRoleEditor)
+		assert.ErrorIs(t, err, store.ErrForbidden)
+
+		assert.ErrorIs(t, repo.DeleteCollectionMember(ctx, editorID, collection.ID, viewerID), store.ErrForbidden)
+		require.NoError(t, repo.DeleteCollectionMember(ctx, viewerID, collection.ID, viewerID))
+		require.NoError(t, repo.DeleteCollectionMember(ctx, ownerID, collection.ID, editorID))
+
+		_, err = repo.FetchCollection(ctx, viewerID, collection.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		collection, err = repo.FetchCollection(ctx, ownerID, collection.ID)
+		require.NoError(t, err)
+		assert.Empty(t, collection.Members)
+	})
+
+	t.Run("collections shared by several owners are priced on each owner's watchlist", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		memberID := test.SeedUser(t, pool, "member", "member@example.com")
+		firstID := test.SeedUser(t, pool, "first", "first@example.com")
+		secondID := test.SeedUser(t, pool, "second", "second@example.com")
+
+		cpuID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: cpuID, Platform: "amazon", URL: "https://amazon.com/cpu"})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 300, InStock: true})
+		test.AddProductToWatchlist(t, pool, firstID, cpuID)
+		test.AddProductToWatchlist(t, pool, secondID, cpuID)
+
+		// the second owner doesn't buy from amazon so the product has no price for them
+		_, err := pool.Exec(ctx, `UPDATE user_watchlist SET excluded_platforms = '{amazon}' WHERE user_id = $1`, secondID)
+		require.NoError(t, err)
+
+		for _, ownerID := range []int{firstID, secondID} {
+			collection, err := repo.InsertCollection(ctx, ownerID, types.CollectionInput{Name: "Build"})
+			require.NoError(t, err)
+			_, err = repo.SetCollectionItem(ctx, ownerID, collection.ID, cpuID, 2)
+			require.NoError(t, err)
+			_, err = repo.AddCollectionMember(ctx, ownerID, collection.ID, types.CollectionInvite{Username: "member", Role: types.CollectionRoleViewer})
+			require.NoError(t, err)
+		}
+		_, err = repo.InsertCollection(ctx, memberID, types.CollectionInput{Name: "Own"})
+		require.NoError(t, err)
+
+		collections, err := repo.FetchCollections(ctx, memberID)
+		require.NoError(t, err)
+		require.Len(t, collections, 3)
+
+		assert.Equal(t, "Own", collections[0].Name)
+		assert.Equal(t, types.CollectionRoleOwner, collections[0].Role)
+		assert.Equal(t, 0, collections[0].ItemCount)
+
+		assert.Equal(t, "first", collections[1].Owner)
+		assert.Equal(t, types.CollectionRoleViewer, collections[1].Role)
+		assert.Equal(t, 600.0, collections[1].Total)
+		assert.Equal(t, 0, collections[1].UnpricedCount)
+
+		assert.Equal(t, "second", collections[2].Owner)
+		assert.Equal(t, 0.0, collections[2].Total)
+		assert.Equal(t, 1, collections[2].UnpricedCount)
+	})
+}
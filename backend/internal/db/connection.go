@@ -8,11 +8,16 @@ import (
 )
 
 // set up pgxpool db connection pool
-func ConnectionPool() *pgxpool.Pool {
-	pool, err := pgxpool.ConnectConfig(context.Background(), config.DatabaseConfig())
+func ConnectionPool(cfg config.DBConfig) *pgxpool.Pool {
+	poolConfig, err := config.DatabaseConfig(cfg)
+	if err != nil {
+		log.Fatalf("Invalid database config %v\n", err)
+	}
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), poolConfig)
 	if err != nil {
 		log.Fatalf("Unable to connect to database %v\n", err)
 	}
 
 	return pool
-}
\ No newline at end of file
+}
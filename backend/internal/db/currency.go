@@ -0,0 +1,108 @@
+package db
+
+import (
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Insert or update exchange rates, re-importing a file overwrites the rates
+// of the days it covers. Returns how many rates were written
+func (r *Repository) UpsertExchangeRates(ctx context.Context, rates []types.ExchangeRate) (int, error) {
+	dates := make([]time.Time, len(rates))
+	currencies := make([]string, len(rates))
+	values := make([]float64, len(rates))
+	for i, rate := range rates {
+		dates[i] = rate.Date
+		currencies[i] = rate.Currency
+		values[i] = rate.Rate
+	}
+
+	var written int64
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `
+			INSERT INTO exchange_rates (rate_date, currency, rate)
+			SELECT * FROM UNNEST($1::date[], $2::varchar[], $3::numeric[])
+			ON CONFLICT (currency, rate_date) DO UPDATE
+				SET rate = EXCLUDED.rate`
+
+		tag, err := tx.Exec(ctx, query, dates, currencies, values)
+		if err != nil {
+			return err
+		}
+
+		written = tag.RowsAffected()
+		return nil
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to upsert exchange rates", "rates", len(rates), "err", err)
+		return 0, err
+	}
+
+	return int(written), nil
+}
+
+// Fetch the user's settings like the display currency
+func (r *Repository) FetchUserPreferences(ctx context.Context, userID int) (types.UserPreferences, error) {
+	var preferences types.UserPreferences
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `SELECT display_currency, price_basis FROM users WHERE id = $1`
+
+		err := tx.QueryRow(ctx, query, userID).Scan(&preferences.DisplayCurrency, &preferences.PriceBasis)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("user %d: %w", userID, store.ErrNotFound)
+		}
+		return err
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to fetch user preferences", "user_id", userID, "err", err)
+		return types.UserPreferences{}, err
+	}
+
+	return preferences, nil
+}
+
+// Update the user's settings, empty fields keep their current value.
+// Returns the stored preferences
+func (r *Repository) UpdateUserPreferences(ctx context.Context, userID int, preferences types.UserPreferences) (types.UserPreferences, error) {
+	var updated types.UserPreferences
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `
+			UPDATE users
+			SET display_currency = COALESCE(NULLIF(UPPER($2), ''), display_currency),
+				price_basis = COALESCE(NULLIF($3, ''), price_basis)
+			WHERE id = $1
+			RETURNING display_currency, price_basis`
+
+		err := tx.QueryRow(ctx, query, userID, preferences.DisplayCurrency, preferences.PriceBasis).Scan(
+			&updated.DisplayCurrency,
+			&updated.PriceBasis,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("user %d: %w", userID, store.ErrNotFound)
+		}
+		return err
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to update user preferences", "user_id", userID, "err", err)
+		return types.UserPreferences{}, err
+	}
+
+	logger.FromContext(ctx).Debug("updated user preferences", "user_id", userID,
+		"display_currency", updated.DisplayCurrency, "price_basis", updated.PriceBasis)
+
+	return updated, nil
+}
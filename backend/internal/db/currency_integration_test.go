@@ -0,0 +1,117 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for UpsertExchangeRates SQL func
+func TestUpsertExchangeRates(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	day := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+
+	t.Run("inserts rates and overwrites them on re-import", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		written, err := repo.UpsertExchangeRates(ctx, []types.ExchangeRate{
+			{Date: day, Currency: "USD", Rate: 1.0921},
+			{Date: day, Currency: "GBP", Rate: 0.86053},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, written)
+
+		written, err = repo.UpsertExchangeRates(ctx, []types.ExchangeRate{
+			{Date: day, Currency: "USD", Rate: 1.1},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, written)
+
+		var count int
+		var usdRate float64
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM exchange_rates`).Scan(&count))
+		require.NoError(t, pool.QueryRow(ctx, `SELECT rate FROM exchange_rates WHERE currency = 'USD'`).Scan(&usdRate))
+		assert.Equal(t, 2, count)
+		assert.Equal(t, 1.1, usdRate)
+	})
+
+	t.Run("converts between currencies through the euro rates", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		_, err := repo.UpsertExchangeRates(ctx, []types.ExchangeRate{
+			{Date: day, Currency: "USD", Rate: 1.10},
+			{Date: day, Currency: "GBP", Rate: 0.85},
+		})
+		require.NoError(t, err)
+
+		var gbpToUSD, eurToGBP float64
+		var unknown *float64
+		require.NoError(t, pool.QueryRow(ctx, `SELECT convert_price(85, 'GBP', 'USD', $1)`, day).Scan(&gbpToUSD))
+		require.NoError(t, pool.QueryRow(ctx, `SELECT convert_price(100, 'eur', 'GBP', $1)`, day).Scan(&eurToGBP))
+		require.NoError(t, pool.QueryRow(ctx, `SELECT convert_price(100, 'JPY', 'USD', $1)`, day).Scan(&unknown))
+
+		assert.Equal(t, 110.0, gbpToUSD)
+		assert.Equal(t, 85.0, eurToGBP)
+		assert.Nil(t, unknown)
+	})
+}
+
+// Integration tests for the user preferences SQL funcs
+func TestUserPreferences(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	t.Run("defaults to USD and stores updates", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		preferences, err := repo.FetchUserPreferences(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, "USD", preferences.DisplayCurrency)
+
+		updated, err := repo.UpdateUserPreferences(ctx, userID, types.UserPreferences{DisplayCurrency: "eur"})
+		require.NoError(t, err)
+		assert.Equal(t, "EUR", updated.DisplayCurrency)
+
+		preferences, err = repo.FetchUserPreferences(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, "EUR", preferences.DisplayCurrency)
+		assert.Equal(t, "sticker", preferences.PriceBasis)
+	})
+
+	t.Run("empty fields keep their value", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		test.SetDisplayCurrency(t, pool, userID, "GBP")
+
+		updated, err := repo.UpdateUserPreferences(ctx, userID, types.UserPreferences{PriceBasis: "landed"})
+
+		require.NoError(t, err)
+		assert.Equal(t, types.UserPreferences{DisplayCurrency: "GBP", PriceBasis: "landed"}, updated)
+	})
+
+	t.Run("returns not found for unknown users", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		_, err := repo.FetchUserPreferences(ctx, 99999)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		_, err = repo.UpdateUserPreferences(ctx, 99999, types.UserPreferences{DisplayCurrency: "EUR"})
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// rows fetched from the export cursor per round trip
+const exportBatchSize = 500
+
+// every snapshot of every source of the user's watched products, grouped by
+// product and source, oldest snapshot first
+const exportQuery = `
+	SELECT
+		p.id, p.product_name, uw.added_at, uw.target_price::float8,
+		COALESCE(uw.notes, ''), COALESCE(uw.tags, '{}'), uw.paused,
+		pso.id, pso.platform, pso.product_url,
+		COALESCE(psnap.condition, pso.condition), COALESCE(psnap.seller_name, pso.seller_name),
+		psnap.seller_rating::float8, psnap.price::float8, psnap.currency,
+		psnap.shipping_cost::float8, psnap.estimated_tax::float8, psnap.discount::float8,
+		psnap.in_stock, psnap.checked_at
+	FROM user_watchlist uw
+	INNER JOIN products p ON uw.product_id = p.id
+	LEFT JOIN product_sources pso ON pso.product_id = p.id
+	LEFT JOIN price_snapshots psnap ON psnap.product_source_id = pso.id
+	WHERE uw.user_id = $1
+	ORDER BY p.id ASC, pso.id ASC, psnap.checked_at ASC, psnap.id ASC`
+
+// Stream the user's watchlist and full price history to each, one row at a
+// time through a server side cursor so the export is never held in memory.
+// Stops at the first error returned by each
+func (r *Repository) ExportWatchlist(ctx context.Context, userID int, each func(types.ExportRow) error) error {
+	var count int
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DECLARE export_cursor NO SCROLL CURSOR FOR `+exportQuery, userID)
+		if err != nil {
+			return err
+		}
+
+		for {
+			fetched, err := fetchExportBatch(ctx, tx, each)
+			if err != nil {
+				return err
+			}
+
+			count += fetched
+			if fetched < exportBatchSize {
+				return nil
+			}
+		}
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to export watchlist", "user_id", userID, "rows", count, "err", err)
+		return err
+	}
+
+	logger.FromContext(ctx).Info("watchlist exported", "user_id", userID, "rows", count)
+
+	return nil
+}
+
+// the next batch of the export cursor, returns how many rows were read
+func fetchExportBatch(ctx context.Context, tx pgx.Tx, each func(types.ExportRow) error) (int, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf(`FETCH %d FROM export_cursor`, exportBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var fetched int
+	for rows.Next() {
+		var row types.ExportRow
+
+		err := rows.Scan(
+			&row.ProductID, &row.ProductName, &row.AddedAt, &row.TargetPrice,
+			&row.Notes, &row.Tags, &row.Paused,
+			&row.SourceID, &row.Platform, &row.ProductURL,
+			&row.Condition, &row.SellerName,
+			&row.SellerRating, &row.Price, &row.Currency,
+			&row.ShippingCost, &row.EstimatedTax, &row.Discount,
+			&row.InStock, &row.CheckedAt,
+		)
+		if err != nil {
+			return fetched, err
+		}
+		fetched++
+
+		err = each(row)
+		if err != nil {
+			return fetched, err
+		}
+	}
+
+	return fetched, rows.Err()
+}
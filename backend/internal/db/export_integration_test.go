@@ -0,0 +1,109 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for the export SQL funcs
+func TestExportWatchlist(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	collect := func(t *testing.T, userID int) []types.ExportRow {
+		rows := []types.ExportRow{}
+		err := repo.ExportWatchlist(ctx, userID, func(row types.ExportRow) error {
+			rows = append(rows, row)
+			return nil
+		})
+		require.NoError(t, err)
+		return rows
+	}
+
+	t.Run("exports every snapshot of every source oldest first", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		otherID := test.SeedUser(t, pool, "user2", "user2@example.com")
+
+		cpuID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		caseID := test.SeedProduct(t, pool, "Rack Case", "")
+		test.AddProductToWatchlist(t, pool, userID, cpuID)
+		test.AddProductToWatchlist(t, pool, userID, caseID)
+		test.AddProductToWatchlist(t, pool, otherID, cpuID)
+
+		amazonID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: cpuID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		ebayID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: cpuID, Platform: "ebay", URL: "https://ebay.com/ryzen", Condition: "used", SellerName: "parts-bin"})
+
+		older := time.Now().Add(-48 * time.Hour)
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 329.99, InStock: true, CheckedAt: &older})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 299.99, InStock: true})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: ebayID, Price: 240, InStock: true})
+
+		_, err := pool.Exec(ctx, `UPDATE user_watchlist SET tags = '{cpu}', target_price = 250 WHERE user_id = $1 AND product_id = $2`, userID, cpuID)
+		require.NoError(t, err)
+
+		rows := collect(t, userID)
+		require.Len(t, rows, 4, "three snapshots and one product without sources")
+
+		assert.Equal(t, 329.99, *rows[0].Price)
+		assert.Equal(t, 299.99, *rows[1].Price)
+		assert.Equal(t, "amazon", *rows[0].Platform)
+		assert.Equal(t, []string{"cpu"}, rows[0].Tags)
+		assert.Equal(t, 250.0, *rows[0].TargetPrice)
+
+		assert.Equal(t, "ebay", *rows[2].Platform)
+		assert.Equal(t, "used", *rows[2].Condition)
+		assert.Equal(t, "parts-bin", *rows[2].SellerName)
+
+		assert.Equal(t, caseID, rows[3].ProductID)
+		assert.Nil(t, rows[3].SourceID)
+		assert.Nil(t, rows[3].Price)
+		assert.Empty(t, rows[3].Tags)
+	})
+
+	t.Run("reads past one cursor batch", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+
+		_, err := pool.Exec(ctx, `
+			INSERT INTO price_snapshots (product_source_id, price, currency, in_stock, checked_at)
+			SELECT $1, 300 + n, 'USD', true, NOW() - n * INTERVAL '1 hour'
+			FROM generate_series(1, 1201) n`, sourceID)
+		require.NoError(t, err)
+
+		assert.Len(t, collect(t, userID), 1201)
+	})
+
+	t.Run("stops at the first callback error", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		test.AddProductToWatchlist(t, pool, userID, test.SeedProduct(t, pool, "One", ""))
+		test.AddProductToWatchlist(t, pool, userID, test.SeedProduct(t, pool, "Two", ""))
+
+		calls := 0
+		stop := errors.New("client went away")
+		err := repo.ExportWatchlist(ctx, userID, func(row types.ExportRow) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
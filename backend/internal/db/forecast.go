@@ -0,0 +1,262 @@
+package db
+
+import (
+	"backend/internal/forecast"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// days of snapshots the forecast is fitted on
+	forecastHistoryDays = 180
+	forecastHorizonDays = 30
+	// no forecast when the last snapshot is older than this many days, carrying
+	// it forward would fit the model on a flat line of made up prices
+	forecastStaleDays = 7
+)
+
+// Forecast the daily lowest price of a product over the next 30 days from
+// the last 180 days of snapshots passing the user's watchlist filters, with
+// the user's active predicted_below alerts and the first day each is forecast
+// to trigger on its price basis. Days without snapshots carry the previous
+// day's price forward up to today, there are no points when the last snapshot
+// is more than a week old
+func (r *Repository) FetchProductForecast(ctx context.Context, userID, productID int) (types.ProductForecast, error) {
+	result := types.ProductForecast{
+		ProductID: 	productID,
+		Points: 	[]types.ForecastPoint{},
+		Alerts: 	[]types.ForecastAlert{},
+	}
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		productQuery := `
+			SELECT
+				COALESCE((SELECT display_currency FROM users WHERE id = $2), $3),
+				COALESCE((SELECT price_basis FROM users WHERE id = $2), $4),
+				COALESCE((SELECT paused FROM user_watchlist WHERE user_id = $2 AND product_id = $1), false)
+			FROM products
+			WHERE id = $1`
+
+		var paused bool
+		err := tx.QueryRow(ctx, productQuery, productID, userID, types.DefaultDisplayCurrency, types.PriceBasisSticker).Scan(
+			&result.Currency,
+			&result.PriceBasis,
+			&paused,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("product %d: %w", productID, store.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		projection, err := forecastPrices(ctx, tx, userID, productID, result.Currency, result.PriceBasis)
+		if err != nil {
+			return err
+		}
+
+		result.HistoryDays = projection.historyDays
+		result.Method = projection.Method
+		for _, point := range projection.Points {
+			result.Points = append(result.Points, types.ForecastPoint{
+				Date: 	projection.lastDay.AddDate(0, 0, point.Step),
+				Price: 	roundCents(point.Value),
+				Lower: 	roundCents(point.Lower),
+				Upper: 	roundCents(point.Upper),
+			})
+		}
+
+		alertsQuery := `
+			SELECT id, rule_type, COALESCE(threshold, 0), COALESCE(price_basis, $4), active, created_at
+			FROM alert_rules
+			WHERE user_id = $1
+			AND product_id = $2
+			AND rule_type = $3
+			AND active
+			ORDER BY created_at ASC, id ASC`
+
+		rows, err := tx.Query(ctx, alertsQuery, userID, productID, types.AlertPredictedBelow, result.PriceBasis)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var alert types.ForecastAlert
+
+			err := rows.Scan(&alert.ID, &alert.Type, &alert.Threshold, &alert.PriceBasis, &alert.Active, &alert.CreatedAt)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			result.Alerts = append(result.Alerts, alert)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// rules of a paused watchlist entry aren't predicted to trigger, rules
+		// on another price basis than the user's are checked against a forecast
+		// of the prices on their own basis
+		if paused {
+			return nil
+		}
+
+		projections := map[string]priceProjection{result.PriceBasis: projection}
+		for i, alert := range result.Alerts {
+			projection, ok := projections[alert.PriceBasis]
+			if !ok {
+				projection, err = forecastPrices(ctx, tx, userID, productID, result.Currency, alert.PriceBasis)
+				if err != nil {
+					return err
+				}
+				projections[alert.PriceBasis] = projection
+			}
+
+			if point, ok := projection.FirstBelow(alert.Threshold); ok {
+				predictedAt := projection.lastDay.AddDate(0, 0, point.Step)
+				predictedPrice := roundCents(point.Value)
+				result.Alerts[i].PredictedAt, result.Alerts[i].PredictedPrice = &predictedAt, &predictedPrice
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to fetch product forecast", "user_id", userID, "product_id", productID, "err", err)
+		}
+		return types.ProductForecast{}, err
+	}
+
+	return result, nil
+}
+
+// a forecast of the daily lowest price on one price basis, without points
+// when there's not enough history or the last snapshot is stale
+type priceProjection struct {
+	forecast.Result
+	lastDay		time.Time
+	historyDays	int
+}
+
+func forecastPrices(ctx context.Context, tx pgx.Tx, userID, productID int, currency, priceBasis string) (priceProjection, error) {
+	days, prices, err := fetchDailyLowestPrices(ctx, tx, userID, productID, currency, priceBasis)
+	if err != nil {
+		return priceProjection{}, err
+	}
+
+	now := today()
+	if len(days) > 0 && days[len(days)-1].Before(now.AddDate(0, 0, -forecastStaleDays)) {
+		return priceProjection{lastDay: now}, nil
+	}
+
+	values, lastDay := fillDailyPrices(days, prices, now)
+	projection := priceProjection{lastDay: lastDay, historyDays: len(values)}
+
+	projection.Result, err = forecast.Forecast(values, forecastHorizonDays)
+	if errors.Is(err, forecast.ErrNotEnoughHistory) {
+		return projection, nil
+	}
+	return projection, err
+}
+
+// lowest price of the product per day across the sources and offers passing
+// the user's watchlist filters, snapshots in currencies without rates are
+// left out
+func fetchDailyLowestPrices(ctx context.Context, tx pgx.Tx, userID, productID int, currency, priceBasis string) ([]time.Time, []float64, error) {
+	query := `
+		WITH history AS (
+			SELECT
+				psnap.checked_at::date as day,
+				convert_price(
+					effective_price(psnap.price, psnap.shipping_cost, psnap.estimated_tax, psnap.discount, $3),
+					psnap.currency, $2, psnap.checked_at
+				) as price
+			FROM product_sources ps
+			INNER JOIN price_snapshots psnap ON ps.id = psnap.product_source_id
+			LEFT JOIN user_watchlist uw ON uw.product_id = ps.product_id AND uw.user_id = $5
+			WHERE ps.product_id = $1
+			AND psnap.checked_at > $4
+			AND offer_acceptable(
+				COALESCE(psnap.condition, ps.condition), COALESCE(psnap.seller_name, ps.seller_name), psnap.seller_rating,
+				uw.acceptable_conditions, uw.min_seller_rating, uw.allow_unrated_sellers
+			)
+			AND ps.platform <> ALL(COALESCE(uw.excluded_platforms, '{}'))
+		)
+		SELECT day, MIN(price)::float8
+		FROM history
+		WHERE price IS NOT NULL
+		GROUP BY day
+		ORDER BY day ASC`
+
+	since := time.Now().AddDate(0, 0, -forecastHistoryDays)
+	rows, err := tx.Query(ctx, query, productID, currency, priceBasis, since, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	var prices []float64
+	for rows.Next() {
+		var day time.Time
+		var price float64
+
+		if err := rows.Scan(&day, &price); err != nil {
+			return nil, nil, err
+		}
+
+		days = append(days, day)
+		prices = append(prices, price)
+	}
+
+	return days, prices, rows.Err()
+}
+
+// one price per day from the first day up to today, days without a price
+// repeat the one before. Returns the day of the last value
+func fillDailyPrices(days []time.Time, prices []float64, today time.Time) ([]float64, time.Time) {
+	if len(days) == 0 {
+		return nil, today
+	}
+
+	last := days[len(days)-1]
+	if today.After(last) {
+		last = today
+	}
+
+	var values []float64
+	next := 0
+	for day := days[0]; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if next < len(days) && !days[next].After(day) {
+			values = append(values, prices[next])
+			next++
+		} else {
+			values = append(values, values[len(values)-1])
+		}
+	}
+
+	return values, last
+}
+
+// current date at midnight UTC, the way dates scan from postgres
+func today() time.Time {
+	year, month, day := time.Now().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func roundCents(price float64) float64 {
+	return math.Round(price*100) / 100
+}
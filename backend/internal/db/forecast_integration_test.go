@@ -0,0 +1,169 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for FetchProductForecast SQL func
+func TestFetchProductForecast(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	daysAgo := func(days int) *time.Time {
+		at := time.Now().AddDate(0, 0, -days)
+		return &at
+	}
+
+	t.Run("projects the daily lowest price with the user's predicted_below alerts", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		otherID := test.SeedUser(t, pool, "user2", "user2@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		amazonID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		neweggID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "newegg", URL: "https://newegg.com/ryzen"})
+
+		// every other day for two weeks, falling 4 a snapshot, newegg is
+		// always pricier so only the amazon prices count
+		for i, days := range []int{13, 11, 9, 7, 5, 3, 1} {
+			price := 200 - float64(i)*4
+			test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: price, InStock: true, CheckedAt: daysAgo(days)})
+			test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: neweggID, Price: price + 50, InStock: true, CheckedAt: daysAgo(days)})
+		}
+
+		triggeredID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPredictedBelow, Threshold: 170, Active: true})
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPredictedBelow, Threshold: 50, Active: true})
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPredictedBelow, Threshold: 170, Active: false})
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPriceBelow, Threshold: 170, Active: true})
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: otherID, ProductID: productID, RuleType: types.AlertPredictedBelow, Threshold: 170, Active: true})
+
+		forecast, err := repo.FetchProductForecast(ctx, userID, productID)
+
+		require.NoError(t, err)
+		assert.Equal(t, productID, forecast.ProductID)
+		assert.Equal(t, "USD", forecast.Currency)
+		assert.Equal(t, "holt", forecast.Method)
+		assert.Equal(t, 14, forecast.HistoryDays, "gaps and the days up to today are filled")
+
+		require.Len(t, forecast.Points, 30)
+		first, last := forecast.Points[0], forecast.Points[29]
+		assert.Less(t, first.Price, 176.0, "keeps falling")
+		assert.Less(t, last.Price, first.Price)
+		assert.LessOrEqual(t, last.Lower, last.Price)
+		assert.GreaterOrEqual(t, last.Upper, last.Price)
+		assert.Equal(t, first.Date.AddDate(0, 0, 29), last.Date)
+
+		require.Len(t, forecast.Alerts, 2, "only the user's active predicted_below alerts")
+		triggered := forecast.Alerts[0]
+		assert.Equal(t, triggeredID, triggered.ID)
+		require.NotNil(t, triggered.PredictedAt)
+		require.NotNil(t, triggered.PredictedPrice)
+		assert.Less(t, *triggered.PredictedPrice, 170.0)
+		assert.Nil(t, forecast.Alerts[1].PredictedAt, "forecast never gets below 50")
+	})
+
+	t.Run("not enough history returns no points", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 300, InStock: true, CheckedAt: daysAgo(2)})
+
+		forecast, err := repo.FetchProductForecast(ctx, userID, productID)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, forecast.HistoryDays)
+		assert.Empty(t, forecast.Method)
+		assert.NotNil(t, forecast.Points)
+		assert.Empty(t, forecast.Points)
+		assert.NotNil(t, forecast.Alerts)
+	})
+
+	t.Run("leaves out offers the user's watchlist filters reject", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+		amazonID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		ebayID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "ebay", URL: "https://ebay.com/ryzen", Condition: "used"})
+
+		// the used ebay listing is always cheaper and falling, amazon stays flat
+		for i, days := range []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0} {
+			test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 200, InStock: true, CheckedAt: daysAgo(days)})
+			test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: ebayID, Price: 150 - float64(i)*5, InStock: true, CheckedAt: daysAgo(days)})
+		}
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPredictedBelow, Threshold: 190, Active: true})
+
+		_, err := pool.Exec(ctx, `UPDATE user_watchlist SET acceptable_conditions = '{new}' WHERE user_id = $1`, userID)
+		require.NoError(t, err)
+
+		forecast, err := repo.FetchProductForecast(ctx, userID, productID)
+
+		require.NoError(t, err)
+		require.Len(t, forecast.Points, 30)
+		for _, point := range forecast.Points {
+			assert.InDelta(t, 200, point.Price, 0.01, "only the new amazon offer counts")
+		}
+		require.Len(t, forecast.Alerts, 1)
+		assert.Nil(t, forecast.Alerts[0].PredictedAt)
+
+		_, err = pool.Exec(ctx, `UPDATE user_watchlist SET acceptable_conditions = NULL, excluded_platforms = '{amazon}' WHERE user_id = $1`, userID)
+		require.NoError(t, err)
+
+		forecast, err = repo.FetchProductForecast(ctx, userID, productID)
+
+		require.NoError(t, err)
+		require.Len(t, forecast.Points, 30)
+		assert.Less(t, forecast.Points[0].Price, 110.0, "only the ebay offer counts")
+		require.NotNil(t, forecast.Alerts[0].PredictedAt)
+	})
+
+	t.Run("stale history returns no points", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+
+		// two weeks of daily prices that stopped 10 days ago
+		for days := 23; days >= 10; days-- {
+			test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 300 - float64(days), InStock: true, CheckedAt: daysAgo(days)})
+		}
+		alertID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPredictedBelow, Threshold: 1000, Active: true})
+
+		forecast, err := repo.FetchProductForecast(ctx, userID, productID)
+
+		require.NoError(t, err)
+		assert.Empty(t, forecast.Method)
+		assert.Equal(t, 0, forecast.HistoryDays)
+		assert.NotNil(t, forecast.Points)
+		assert.Empty(t, forecast.Points)
+		require.Len(t, forecast.Alerts, 1)
+		assert.Equal(t, alertID, forecast.Alerts[0].ID)
+		assert.Nil(t, forecast.Alerts[0].PredictedAt, "no prediction without a forecast")
+	})
+
+	t.Run("returns not found for missing products", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		_, err := repo.FetchProductForecast(ctx, userID, 999)
+
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// rows inserted per transaction
+const importBatchSize = 50
+
+// Add the items to the user's watchlist in transactions of importBatchSize
+// rows. Each row runs in a savepoint so a failing row doesn't undo the rest
+// of its batch. Items already on the watchlist get the new tags and target
+// price, so uploading the same file twice changes nothing. Only errors when
+// the request is cancelled, with the results of the batches already saved
+func (r *Repository) ImportWatchlist(ctx context.Context, userID int, items []types.ImportItem) ([]types.ImportResult, error) {
+	results := make([]types.ImportResult, 0, len(items))
+
+	for start := 0; start < len(items); start += importBatchSize {
+		if ctx.Err() != nil {
+			logger.FromContext(ctx).Warn("watchlist import cancelled", "user_id", userID, "rows", len(results))
+			return results, ctx.Err()
+		}
+
+		batch := items[start:min(start+importBatchSize, len(items))]
+		batchResults := make([]types.ImportResult, 0, len(batch))
+
+		err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+			batchResults = batchResults[:0]
+
+			for _, item := range batch {
+				result, err := importWatchlistItem(ctx, tx, userID, item)
+				if err != nil {
+					logger.FromContext(ctx).Error("failed to import watchlist row", "user_id", userID, "line", item.Line, "err", err)
+					result = types.ImportResult{Line: item.Line, Status: types.ImportStatusFailed, Error: "database error"}
+				}
+
+				batchResults = append(batchResults, result)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			// the commit failed so none of the batch was saved
+			logger.FromContext(ctx).Error("failed to import watchlist batch", "user_id", userID, "err", err)
+			batchResults = batchResults[:0]
+			for _, item := range batch {
+				batchResults = append(batchResults, types.ImportResult{Line: item.Line, Status: types.ImportStatusFailed, Error: "database error"})
+			}
+		}
+
+		results = append(results, batchResults...)
+	}
+
+	logger.FromContext(ctx).Info("watchlist imported", "user_id", userID, "rows", len(items))
+
+	return results, nil
+}
+
+// find or create the item's product and add it to the watchlist inside a
+// savepoint, rolled back when anything fails
+func importWatchlistItem(ctx context.Context, tx pgx.Tx, userID int, item types.ImportItem) (types.ImportResult, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return types.ImportResult{}, err
+	}
+	defer savepoint.Rollback(ctx)
+
+	var product types.Product
+	if item.Source != nil {
+		product, err = findOrCreateSourceProduct(ctx, savepoint, item.Name, *item.Source)
+	} else {
+		product, err = findOrCreateProduct(ctx, savepoint, item.Name)
+	}
+	if err != nil {
+		return types.ImportResult{}, err
+	}
+
+	// only touches an existing entry when the row changes it, xmax is 0 for
+	// freshly inserted rows
+	query := `
+		INSERT INTO user_watchlist (user_id, product_id, added_at, target_price, tags)
+		VALUES ($1, $2, NOW(), $3, $4::varchar[])
+		ON CONFLICT (user_id, product_id) DO UPDATE
+		SET
+			target_price = COALESCE(EXCLUDED.target_price, user_watchlist.target_price),
+			tags = (
+				SELECT ARRAY_AGG(DISTINCT tag)
+				FROM UNNEST(COALESCE(user_watchlist.tags, '{}') || COALESCE(EXCLUDED.tags, '{}')) tag
+			)
+		WHERE user_watchlist.target_price IS DISTINCT FROM COALESCE(EXCLUDED.target_price, user_watchlist.target_price)
+		OR NOT COALESCE(user_watchlist.tags, '{}') @> COALESCE(EXCLUDED.tags, '{}')
+		RETURNING xmax = 0`
+
+	result := types.ImportResult{Line: item.Line, ProductID: product.ID, ProductName: product.Name}
+
+	var inserted bool
+	err = savepoint.QueryRow(ctx, query, userID, product.ID, item.TargetPrice, nullIfEmpty(item.Tags)).Scan(&inserted)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		result.Status = types.ImportStatusUnchanged
+	case err != nil:
+		return types.ImportResult{}, err
+	case inserted:
+		result.Status = types.ImportStatusCreated
+	default:
+		result.Status = types.ImportStatusUpdated
+	}
+
+	return result, savepoint.Commit(ctx)
+}
@@ -0,0 +1,129 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for the watchlist import SQL funcs
+func TestImportWatchlist(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	price := func(value float64) *float64 { return &value }
+
+	statuses := func(results []types.ImportResult) []string {
+		out := []string{}
+		for _, result := range results {
+			out = append(out, result.Status)
+		}
+		return out
+	}
+
+	t.Run("imports rows and is idempotent on re-upload", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		existingID := test.SeedProduct(t, pool, "DDR5 16GB", "")
+		test.AddProductToWatchlist(t, pool, userID, existingID)
+
+		items := []types.ImportItem{
+			{Line: 2, Name: "Ryzen 7 7700", TargetPrice: price(250), Tags: []string{"cpu"}},
+			{Line: 3, Name: "Amazon B09XS7JWHH", Source: &types.ProductSource{Platform: "amazon", PlatformProductID: "B09XS7JWHH", URL: "https://www.amazon.com/dp/B09XS7JWHH"}},
+			{Line: 4, Name: "ddr5 16gb", Tags: []string{"ram"}},
+		}
+
+		results, err := repo.ImportWatchlist(ctx, userID, items)
+		require.NoError(t, err)
+		assert.Equal(t, []string{types.ImportStatusCreated, types.ImportStatusCreated, types.ImportStatusUpdated}, statuses(results))
+		assert.Equal(t, existingID, results[2].ProductID, "names match by their normalized form")
+
+		results, err = repo.ImportWatchlist(ctx, userID, items)
+		require.NoError(t, err)
+		assert.Equal(t, []string{types.ImportStatusUnchanged, types.ImportStatusUnchanged, types.ImportStatusUnchanged}, statuses(results))
+
+		var watched, sources int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM user_watchlist WHERE user_id = $1`, userID).Scan(&watched))
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM product_sources`).Scan(&sources))
+		assert.Equal(t, 3, watched)
+		assert.Equal(t, 1, sources)
+
+		var targetPrice float64
+		var tags []string
+		require.NoError(t, pool.QueryRow(ctx,
+			`SELECT target_price::float8, tags FROM user_watchlist WHERE user_id = $1 AND product_id = $2`,
+			userID, results[0].ProductID,
+		).Scan(&targetPrice, &tags))
+		assert.Equal(t, 250.0, targetPrice)
+		assert.Equal(t, []string{"cpu"}, tags)
+	})
+
+	t.Run("merges tags and changes the target price of watched products", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		_, err := repo.ImportWatchlist(ctx, userID, []types.ImportItem{{Line: 1, Name: "Ryzen 7 7700", TargetPrice: price(250), Tags: []string{"cpu"}}})
+		require.NoError(t, err)
+
+		results, err := repo.ImportWatchlist(ctx, userID, []types.ImportItem{{Line: 1, Name: "Ryzen 7 7700", Tags: []string{"build"}}})
+		require.NoError(t, err)
+		assert.Equal(t, types.ImportStatusUpdated, results[0].Status)
+
+		var targetPrice float64
+		var tags []string
+		require.NoError(t, pool.QueryRow(ctx,
+			`SELECT target_price::float8, tags FROM user_watchlist WHERE user_id = $1`, userID,
+		).Scan(&targetPrice, &tags))
+		assert.Equal(t, 250.0, targetPrice, "rows without a target price keep the old one")
+		assert.ElementsMatch(t, []string{"cpu", "build"}, tags)
+	})
+
+	t.Run("imports more rows than one batch", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		items := []types.ImportItem{}
+		for i := 1; i <= 120; i++ {
+			items = append(items, types.ImportItem{Line: i, Name: fmt.Sprintf("Product %d", i)})
+		}
+
+		results, err := repo.ImportWatchlist(ctx, userID, items)
+		require.NoError(t, err)
+		require.Len(t, results, 120)
+		assert.Equal(t, 120, results[119].Line)
+
+		var watched int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM user_watchlist WHERE user_id = $1`, userID).Scan(&watched))
+		assert.Equal(t, 120, watched)
+	})
+
+	t.Run("a failing row doesn't undo the rest of its batch", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		results, err := repo.ImportWatchlist(ctx, userID, []types.ImportItem{
+			{Line: 1, Name: "Ryzen 7 7700"},
+			{Line: 2, Name: "DDR5 16GB", TargetPrice: price(1e12)},
+			{Line: 3, Name: "Rack Case"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{types.ImportStatusCreated, types.ImportStatusFailed, types.ImportStatusCreated}, statuses(results))
+
+		var watched int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM user_watchlist WHERE user_id = $1`, userID).Scan(&watched))
+		assert.Equal(t, 2, watched)
+	})
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Merge a duplicate product into the canonical one. Watchlist entries and
+// product sources are repointed to the canonical product, users watching
+// both keep a single entry with the earliest added_at, the duplicate's name
+// is recorded as an alias and the duplicate is deleted, all in one transaction
+func (r *Repository) MergeProducts(ctx context.Context, duplicateID, canonicalID int) (types.ProductMerge, error) {
+	var merge types.ProductMerge
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		merge, err = mergeProducts(ctx, tx, duplicateID, canonicalID)
+		return err
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to merge products", "duplicate_id", duplicateID, "canonical_id", canonicalID, "err", err)
+		return types.ProductMerge{}, err
+	}
+
+	logger.FromContext(ctx).Info("merged duplicate product", "duplicate_id", duplicateID, "canonical_id", canonicalID,
+		"moved_watchers", merge.MovedWatchers, "merged_watchers", merge.MergedWatchers, "moved_sources", merge.MovedSources)
+
+	return merge, nil
+}
+
+// merge on the caller's transaction so the name backfill can merge the
+// duplicates it finds before adding the unique constraint
+func mergeProducts(ctx context.Context, tx pgx.Tx, duplicateID, canonicalID int) (types.ProductMerge, error) {
+	merge := types.ProductMerge{CanonicalID: canonicalID, DuplicateID: duplicateID}
+
+	// lock both rows so concurrent inserts and merges wait for this one
+	lockQuery := `
+		SELECT id, product_name
+		FROM products
+		WHERE id = ANY($1)
+		ORDER BY id
+		FOR UPDATE`
+
+	rows, err := tx.Query(ctx, lockQuery, []int{duplicateID, canonicalID})
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	found := 0
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return types.ProductMerge{}, err
+		}
+		if id == duplicateID {
+			merge.Alias = name
+		}
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return types.ProductMerge{}, err
+	}
+	if found != 2 {
+		return types.ProductMerge{}, fmt.Errorf("merging product %d into %d: %w", duplicateID, canonicalID, store.ErrNotFound)
+	}
+
+	// users watching both keep the canonical entry with the earliest added_at,
+	// settings only made on the duplicate entry carry over
+	mergeWatchersQuery := `
+		UPDATE user_watchlist c
+		SET
+			added_at = LEAST(c.added_at, d.added_at),
+			acceptable_conditions = COALESCE(c.acceptable_conditions, d.acceptable_conditions),
+			min_seller_rating = COALESCE(c.min_seller_rating, d.min_seller_rating),
+			allow_unrated_sellers = CASE
+				WHEN c.min_seller_rating IS NULL THEN d.allow_unrated_sellers
+				ELSE c.allow_unrated_sellers
+			END,
+			target_price = COALESCE(c.target_price, d.target_price),
+			notes = COALESCE(c.notes, d.notes),
+			preferred_platforms = COALESCE(c.preferred_platforms, d.preferred_platforms),
+			excluded_platforms = COALESCE(c.excluded_platforms, d.excluded_platforms),
+			paused = c.paused AND d.paused,
+			tags = (
+				SELECT ARRAY_AGG(DISTINCT tag)
+				FROM UNNEST(COALESCE(c.tags, '{}') || COALESCE(d.tags, '{}')) tag
+			)
+		FROM user_watchlist d
+		WHERE c.product_id = $2
+		AND d.product_id = $1
+		AND c.user_id = d.user_id`
+
+	tag, err := tx.Exec(ctx, mergeWatchersQuery, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+	merge.MergedWatchers = int(tag.RowsAffected())
+
+	dropWatchersQuery := `
+		DELETE FROM user_watchlist d
+		USING user_watchlist c
+		WHERE d.product_id = $1
+		AND c.product_id = $2
+		AND c.user_id = d.user_id`
+
+	_, err = tx.Exec(ctx, dropWatchersQuery, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	tag, err = tx.Exec(ctx, `UPDATE user_watchlist SET product_id = $2 WHERE product_id = $1`, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+	merge.MovedWatchers = int(tag.RowsAffected())
+
+	tag, err = tx.Exec(ctx, `UPDATE product_sources SET product_id = $2 WHERE product_id = $1`, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+	merge.MovedSources = int(tag.RowsAffected())
+
+	// the canonical product's stats now cover the moved sources too
+	_, err = tx.Exec(ctx, `UPDATE price_stats SET stale = true WHERE product_id = $1`, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	// collections holding both keep the canonical item with the larger quantity
+	mergeItemsQuery := `
+		UPDATE collection_items c
+		SET quantity = GREATEST(c.quantity, d.quantity)
+		FROM collection_items d
+		WHERE c.product_id = $2
+		AND d.product_id = $1
+		AND c.collection_id = d.collection_id`
+
+	_, err = tx.Exec(ctx, mergeItemsQuery, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	dropItemsQuery := `
+		DELETE FROM collection_items d
+		USING collection_items c
+		WHERE d.product_id = $1
+		AND c.product_id = $2
+		AND c.collection_id = d.collection_id`
+
+	_, err = tx.Exec(ctx, dropItemsQuery, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE collection_items SET product_id = $2 WHERE product_id = $1`, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	// public links to the duplicate's price history keep working
+	_, err = tx.Exec(ctx, `UPDATE share_links SET product_id = $2 WHERE product_id = $1`, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	// alerts of users who watched the duplicate keep firing for the canonical product
+	_, err = tx.Exec(ctx, `UPDATE alert_rules SET product_id = $2 WHERE product_id = $1`, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	// keep the duplicate's image and scrape priority if the canonical lacks them
+	productQuery := `
+		UPDATE products c
+		SET image_url = COALESCE(c.image_url, d.image_url),
+			check_priority = GREATEST(c.check_priority, d.check_priority)
+		FROM products d
+		WHERE c.id = $2
+		AND d.id = $1`
+
+	_, err = tx.Exec(ctx, productQuery, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	// earlier merges into the duplicate follow it to the canonical product
+	_, err = tx.Exec(ctx, `UPDATE product_aliases SET product_id = $2 WHERE product_id = $1`, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	aliasQuery := `
+		INSERT INTO product_aliases (product_id, alias_name, normalized_name, merged_at)
+		SELECT $2, product_name, normalized_name, NOW()
+		FROM products
+		WHERE id = $1
+		ON CONFLICT (normalized_name) DO UPDATE
+			SET product_id = EXCLUDED.product_id, merged_at = EXCLUDED.merged_at`
+
+	_, err = tx.Exec(ctx, aliasQuery, duplicateID, canonicalID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM products WHERE id = $1`, duplicateID)
+	if err != nil {
+		return types.ProductMerge{}, err
+	}
+
+	return merge, nil
+}
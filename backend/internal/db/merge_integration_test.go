@@ -0,0 +1,118 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+	"backend/pkg/test"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for MergeProducts SQL func
+func TestMergeProducts(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	t.Run("moves watchers and sources to the canonical product", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		user1 := test.SeedUser(t, pool, "user1", "user1@example.com")
+		user2 := test.SeedUser(t, pool, "user2", "user2@example.com")
+
+		canonicalID := test.SeedProduct(t, pool, "Sony WH-1000XM5", "")
+		duplicateID := test.SeedProduct(t, pool, "Sony WH1000XM5 Headphones", "https://example.com/xm5.jpg")
+
+		test.AddProductToWatchlist(t, pool, user1, canonicalID)
+		test.AddProductToWatchlist(t, pool, user1, duplicateID)
+		test.AddProductToWatchlist(t, pool, user2, duplicateID)
+
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID:         duplicateID,
+			Platform:          "amazon",
+			PlatformProductID: "B09XS7JWHH",
+			URL:               "https://amazon.com/dp/B09XS7JWHH",
+		})
+
+		alertID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{
+			UserID: 	user2,
+			ProductID: 	duplicateID,
+			RuleType: 	"price_below",
+			Threshold: 	299,
+			Active: 	true,
+		})
+
+		merge, err := repo.MergeProducts(ctx, duplicateID, canonicalID)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Sony WH1000XM5 Headphones", merge.Alias)
+		assert.Equal(t, 1, merge.MergedWatchers, "user1 watched both")
+		assert.Equal(t, 1, merge.MovedWatchers, "user2 only watched the duplicate")
+		assert.Equal(t, 1, merge.MovedSources)
+
+		var watchers int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM user_watchlist WHERE product_id = $1`, canonicalID).Scan(&watchers))
+		assert.Equal(t, 2, watchers)
+
+		var sourceProductID int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT product_id FROM product_sources WHERE id = $1`, sourceID).Scan(&sourceProductID))
+		assert.Equal(t, canonicalID, sourceProductID)
+
+		var alertProductID int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT product_id FROM alert_rules WHERE id = $1`, alertID).Scan(&alertProductID))
+		assert.Equal(t, canonicalID, alertProductID, "alerts follow the merge instead of being deleted")
+
+		var imageURL string
+		require.NoError(t, pool.QueryRow(ctx, `SELECT image_url FROM products WHERE id = $1`, canonicalID).Scan(&imageURL))
+		assert.Equal(t, "", imageURL, "an existing image on the canonical product is kept")
+
+		var duplicates int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM products WHERE id = $1`, duplicateID).Scan(&duplicates))
+		assert.Equal(t, 0, duplicates)
+
+		var aliasProductID int
+		require.NoError(t, pool.QueryRow(ctx,
+			`SELECT product_id FROM product_aliases WHERE normalized_name = $1`,
+			"sony wh1000xm5 headphones",
+		).Scan(&aliasProductID))
+		assert.Equal(t, canonicalID, aliasProductID)
+	})
+
+	t.Run("aliases of the duplicate follow it", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		firstID := test.SeedProduct(t, pool, "Product A", "")
+		secondID := test.SeedProduct(t, pool, "Product B", "")
+		thirdID := test.SeedProduct(t, pool, "Product C", "")
+
+		_, err := repo.MergeProducts(ctx, firstID, secondID)
+		require.NoError(t, err)
+		_, err = repo.MergeProducts(ctx, secondID, thirdID)
+		require.NoError(t, err)
+
+		var aliases int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM product_aliases WHERE product_id = $1`, thirdID).Scan(&aliases))
+		assert.Equal(t, 2, aliases)
+	})
+
+	t.Run("returns not found and changes nothing for missing products", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Product A", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		_, err := repo.MergeProducts(ctx, productID, 99999)
+
+		require.ErrorIs(t, err, store.ErrNotFound)
+
+		var watchers int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM user_watchlist WHERE product_id = $1`, productID).Scan(&watchers))
+		assert.Equal(t, 1, watchers)
+	})
+}
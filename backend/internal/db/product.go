@@ -1,128 +1,355 @@
 package db
 
 import (
+	"backend/internal/normalize"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
 	"github.com/jackc/pgx/v4"
-	"backend/internal/types"
-	"backend/pkg/db"
 )
 
 // Insert a product into the products table for the user with the name and timestamps
 // additionally returns product metadata so frontend can immediately show the product
-func (r *Repository) InsertProductForUser(userID int, productName string) (types.Product, error) {
-	ctx := context.Background()
+func (r *Repository) InsertProductForUser(ctx context.Context, userID int, productName string) (types.Product, error) {
 	var product types.Product
 
-	createdAt := time.Now()
-
 	// One transaction for both queries so it can rollback on errors
 	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
-		// Try to insert, but if conflict, fetch the existing product instead
-		productQuery := `
-			INSERT INTO products (product_name, created_at, last_checked_at)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (product_name) DO UPDATE
-				SET product_name = EXCLUDED.product_name
-			RETURNING id, product_name, created_at`
-
-		err := tx.QueryRow(ctx, productQuery, productName, createdAt, nil).Scan(
-			&product.ID,
-			&product.Name,
-			&product.CreatedAt,
-		)
+		var err error
+		product, err = findOrCreateProduct(ctx, tx, productName)
 		if err != nil {
 			return err
 		}
 
-		// Add this product for the user
-		userProductQuery := `
-			INSERT INTO user_watchlist (user_id, product_id, added_at)
-			VALUES ($1, $2, $3)`
+		err = addToWatchlist(ctx, tx, userID, product)
+		if err != nil {
+			return err
+		}
 
-		_, err = tx.Exec(ctx, userProductQuery, userID, product.ID, product.CreatedAt)
+		// an existing product may already have sources and prices to show
+		product, err = fetchProductDetail(ctx, tx, userID, product.ID)
 		return err
 	})
 
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to insert product for user", "user_id", userID, "product_name", productName, "err", err)
 		return types.Product{}, err
 	}
 
-	product.Prices = []types.PriceData{}
+	logger.FromContext(ctx).Debug("inserted product for user", "user_id", userID, "product_id", product.ID)
 
 	// todo: create a helper function to trigger price scraping, call the api for that ig
 	// should prob just send a job request to a job queeue and have the api for that consume from it
 
-	return types.Product{
-		ID: product.ID,
-		Name: product.Name,
-		URL: "idk man",
-		CreatedAt: createdAt,
-		Prices: []types.PriceData{},
-	}, nil
+	return product, nil
+}
+
+// Insert a product for the user from a store listing. If the listing is already
+// a source of a product that product is added to the watchlist, otherwise the
+// product is found or created by name and the listing is added as its source
+func (r *Repository) InsertProductURLForUser(ctx context.Context, userID int, productName string, source types.ProductSource) (types.Product, error) {
+	var product types.Product
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		product, err = findOrCreateSourceProduct(ctx, tx, productName, source)
+		if err != nil {
+			return err
+		}
+
+		err = addToWatchlist(ctx, tx, userID, product)
+		if err != nil {
+			return err
+		}
+
+		product, err = fetchProductDetail(ctx, tx, userID, product.ID)
+		return err
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to insert product url for user", "user_id", userID, "platform", source.Platform,
+			"platform_product_id", source.PlatformProductID, "err", err)
+		return types.Product{}, err
+	}
+
+	logger.FromContext(ctx).Debug("inserted product url for user", "user_id", userID, "product_id", product.ID, "platform", source.Platform)
+
+	// link to the listing the user added rather than the product's cheapest one
+	product.URL = source.URL
+
+	return product, nil
+}
+
+// Find the product for the name by its normalized form, following aliases of
+// merged products, and create it when there's no match
+func findOrCreateProduct(ctx context.Context, tx pgx.Tx, productName string) (types.Product, error) {
+	var product types.Product
+
+	normalizedName := normalize.ProductName(productName)
+
+	// names merged into another product resolve to the canonical product
+	aliasQuery := `
+		SELECT p.id, p.product_name, p.created_at
+		FROM product_aliases a
+		INNER JOIN products p ON a.product_id = p.id
+		WHERE a.normalized_name = $1`
+
+	err := tx.QueryRow(ctx, aliasQuery, normalizedName).Scan(
+		&product.ID,
+		&product.Name,
+		&product.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Try to insert, but if a product with the same normalized name
+		// exists fetch it instead
+		productQuery := `
+			INSERT INTO products (product_name, normalized_name, created_at, last_checked_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (normalized_name) DO UPDATE
+				SET normalized_name = EXCLUDED.normalized_name
+			RETURNING id, product_name, created_at`
+
+		err = tx.QueryRow(ctx, productQuery, productName, normalizedName, time.Now(), nil).Scan(
+			&product.ID,
+			&product.Name,
+			&product.CreatedAt,
+		)
+	}
+
+	return product, err
+}
+
+// Find the product the listing is a source of, otherwise find or create the
+// product by name and add the listing as its source
+func findOrCreateSourceProduct(ctx context.Context, tx pgx.Tx, productName string, source types.ProductSource) (types.Product, error) {
+	var product types.Product
+
+	sourceQuery := `
+		SELECT p.id, p.product_name, p.created_at
+		FROM product_sources ps
+		INNER JOIN products p ON ps.product_id = p.id
+		WHERE ps.platform = $1
+		AND ps.platform_product_id = $2`
+
+	err := tx.QueryRow(ctx, sourceQuery, source.Platform, source.PlatformProductID).Scan(
+		&product.ID,
+		&product.Name,
+		&product.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		product, err = findOrCreateProduct(ctx, tx, productName)
+		if err != nil {
+			return types.Product{}, err
+		}
+
+		insertSourceQuery := `
+			INSERT INTO product_sources (product_id, platform, platform_product_id, product_url)
+			VALUES ($1, $2, $3, $4)`
+
+		_, err = tx.Exec(ctx, insertSourceQuery, product.ID, source.Platform, source.PlatformProductID, source.URL)
+	}
+
+	return product, err
+}
+
+// Add the product to the user's watchlist, errors with a unique violation
+// when the user already tracks it
+func addToWatchlist(ctx context.Context, tx pgx.Tx, userID int, product types.Product) error {
+	userProductQuery := `
+		INSERT INTO user_watchlist (user_id, product_id, added_at)
+		VALUES ($1, $2, $3)`
+
+	_, err := tx.Exec(ctx, userProductQuery, userID, product.ID, time.Now())
+	return err
+}
+
+// CTE with one row per product in the user's ($1) watchlist along with the
+// lowest latest price across its sources, the drop from its 30 day high and
+// a 0 to 100 deal score: half from the price's percentile in its history,
+// 30% from being within 20% of the all time low and 20% from the trend. The
+// history and so the all time low only go back 180 days, older prices say
+// little about a deal today and would make the scan grow without bound.
+// Only offers passing the entry's condition and seller rating filters from
+// platforms it doesn't exclude count, preferred platforms win price ties and
+// prices are on the user's price basis (sticker or landed cost) converted to
+// their display currency with the rates of the day they were checked
+const trackedProductsCTE = `
+	WITH watchers AS (
+		SELECT $1::int as user_id
+	),` + trackedProductsBody
+
+// the tracked products CTE for every user in a watchers CTE the query starts
+// with, rows of each stage carry the user_id they were priced for
+const trackedProductsBody = `
+	user_products AS (
+		SELECT uw.user_id, uw.product_id, uw.acceptable_conditions, uw.min_seller_rating, uw.allow_unrated_sellers, uw.preferred_platforms, uw.excluded_platforms
+		FROM user_watchlist uw
+		INNER JOIN watchers w ON uw.user_id = w.user_id
+	),
+	display AS (
+		SELECT u.id as user_id, u.display_currency, u.price_basis
+		FROM users u
+		INNER JOIN watchers w ON u.id = w.user_id
+	),
+	latest_prices AS (
+		SELECT DISTINCT ON (up.user_id, pso.id)
+			up.user_id, pso.product_id, pso.platform,
+			convert_price(
+				effective_price(psnap.price, psnap.shipping_cost, psnap.estimated_tax, psnap.discount, ds.price_basis),
+				psnap.currency, ds.display_currency, psnap.checked_at
+			) as price,
+			psnap.in_stock, psnap.checked_at,
+			offer_acceptable(
+				COALESCE(psnap.condition, pso.condition), COALESCE(psnap.seller_name, pso.seller_name), psnap.seller_rating,
+				up.acceptable_conditions, up.min_seller_rating, up.allow_unrated_sellers
+			) AND pso.platform <> ALL(COALESCE(up.excluded_platforms, '{}')) as acceptable,
+			COALESCE(pso.platform = ANY(up.preferred_platforms), false) as preferred
+		FROM product_sources pso
+		INNER JOIN user_products up ON pso.product_id = up.product_id
+		INNER JOIN display ds ON up.user_id = ds.user_id
+		LEFT JOIN price_snapshots psnap ON pso.id = psnap.product_source_id
+		ORDER BY up.user_id, pso.id, psnap.checked_at DESC NULLS LAST
+	),
+	lowest_prices AS (
+		SELECT
+			user_id, product_id,
+			MIN(price) FILTER (WHERE price IS NOT NULL AND acceptable) as lowest_price,
+			(ARRAY_AGG(platform ORDER BY price ASC, preferred DESC) FILTER (WHERE price IS NOT NULL AND acceptable))[1] as lowest_source,
+			(ARRAY_AGG(in_stock ORDER BY price ASC, preferred DESC) FILTER (WHERE price IS NOT NULL AND acceptable))[1] as in_stock
+		FROM latest_prices
+		GROUP BY user_id, product_id
+	),
+	history AS (
+		SELECT
+			up.user_id, pso.product_id,
+			convert_price(
+				effective_price(psnap.price, psnap.shipping_cost, psnap.estimated_tax, psnap.discount, ds.price_basis),
+				psnap.currency, ds.display_currency, psnap.checked_at
+			) as price,
+			psnap.checked_at
+		FROM product_sources pso
+		INNER JOIN user_products up ON pso.product_id = up.product_id
+		INNER JOIN display ds ON up.user_id = ds.user_id
+		INNER JOIN price_snapshots psnap ON pso.id = psnap.product_source_id
+		WHERE psnap.checked_at > NOW() - INTERVAL '180 days'
+		AND offer_acceptable(
+			COALESCE(psnap.condition, pso.condition), COALESCE(psnap.seller_name, pso.seller_name), psnap.seller_rating,
+			up.acceptable_conditions, up.min_seller_rating, up.allow_unrated_sellers
+		)
+		AND pso.platform <> ALL(COALESCE(up.excluded_platforms, '{}'))
+	),
+	history_stats AS (
+		SELECT
+			h.user_id, h.product_id,
+			MAX(h.price) FILTER (WHERE h.checked_at > NOW() - INTERVAL '30 days') as high_price,
+			MIN(h.price) as low_price,
+			COUNT(h.price) as snapshot_count,
+			COUNT(h.price) FILTER (WHERE h.price < lp.lowest_price) as cheaper_count,
+			AVG(h.price) FILTER (WHERE h.checked_at > NOW() - INTERVAL '7 days') as avg_7d,
+			AVG(h.price) FILTER (WHERE h.checked_at <= NOW() - INTERVAL '7 days' AND h.checked_at > NOW() - INTERVAL '30 days') as avg_prior
+		FROM history h
+		LEFT JOIN lowest_prices lp ON h.user_id = lp.user_id AND h.product_id = lp.product_id
+		WHERE h.price IS NOT NULL
+		GROUP BY h.user_id, h.product_id
+	),
+	-- how the current lowest price compares to the last 180 days: its
+	-- percentile (0 is the cheapest), how far above the all time low it
+	-- is and whether the last week is cheaper (down) or pricier (up) than the
+	-- three weeks before by more than 2%. Products need a current price and
+	-- at least 3 snapshots
+	deals AS (
+		SELECT
+			hs.user_id, hs.product_id,
+			100.0 * hs.cheaper_count / hs.snapshot_count as percentile,
+			(lp.lowest_price - hs.low_price) / NULLIF(hs.low_price, 0) * 100 as above_low,
+			CASE
+				WHEN hs.avg_7d IS NULL OR hs.avg_prior IS NULL THEN ''
+				WHEN hs.avg_7d < hs.avg_prior * 0.98 THEN 'down'
+				WHEN hs.avg_7d > hs.avg_prior * 1.02 THEN 'up'
+				ELSE 'flat'
+			END as trend
+		FROM history_stats hs
+		INNER JOIN lowest_prices lp ON hs.user_id = lp.user_id AND hs.product_id = lp.product_id
+		WHERE lp.lowest_price IS NOT NULL
+		AND hs.snapshot_count >= 3
+	),
+	tracked AS (
+		SELECT 
+			uw.user_id, p.id as product_id, p.product_name,
+			COALESCE(p.image_url, '') as image_url,
+			p.last_checked_at,
+			uw.added_at, 
+			COALESCE(lp.lowest_price, 0) as lowest_price,
+			COALESCE(lp.lowest_source, '') as lowest_source,
+			COALESCE(lp.in_stock, false) as in_stock,
+			COALESCE(GREATEST(hs.high_price - lp.lowest_price, 0), 0) as price_drop,
+			ds.display_currency as currency,
+			ds.price_basis,
+			COALESCE(uw.target_price, 0) as target_price,
+			uw.paused,
+			COALESCE(uw.tags, '{}') as tags,
+			COALESCE(ROUND(
+				0.5 * (100 - d.percentile)
+				+ 0.3 * GREATEST(100 - d.above_low * 5, 0)
+				+ 0.2 * CASE d.trend WHEN 'down' THEN 100 WHEN 'up' THEN 0 ELSE 50 END,
+			1), 0) as deal_score,
+			COALESCE(ROUND(d.percentile, 1), 0) as price_percentile,
+			COALESCE(ROUND(d.above_low, 1), 0) as above_all_time_low,
+			COALESCE(d.trend, '') as price_trend
+		FROM user_watchlist uw
+		INNER JOIN display ds ON uw.user_id = ds.user_id
+		INNER JOIN products p ON uw.product_id = p.id
+		LEFT JOIN lowest_prices lp ON uw.user_id = lp.user_id AND p.id = lp.product_id
+		LEFT JOIN history_stats hs ON uw.user_id = hs.user_id AND p.id = hs.product_id
+		LEFT JOIN deals d ON uw.user_id = d.user_id AND p.id = d.product_id
+	)`
+
+const trackedProductsColumns = `
+	product_id, product_name, image_url, last_checked_at, added_at,
+	lowest_price, lowest_source, in_stock, price_drop, currency, price_basis,
+	target_price, paused, tags, deal_score, price_percentile, above_all_time_low,
+	price_trend`
+
+// sql expression and cursor value cast for each sort option
+var trackedProductsSorts = map[string]struct {
+	expr string
+	cast string
+}{
+	types.SortByAddedAt:     {"added_at", "timestamp"},
+	types.SortByPrice:       {"lowest_price", "numeric"},
+	types.SortByName:        {"product_name", "text"},
+	types.SortByLastChecked: {"COALESCE(last_checked_at, 'epoch'::timestamp)", "timestamp"},
+	types.SortByBiggestDrop: {"price_drop", "numeric"},
+	types.SortByDealScore:   {"deal_score", "numeric"},
 }
 
 // Fetch all tracked products for the user, returns a list of products with the
 // name, added at timestamp, lowest price, and an availablity flag
-func (r *Repository) FetchUserTrackedProducts(userID int) ([]types.UserProduct, error) {
-	ctx := context.Background()
+func (r *Repository) FetchUserTrackedProducts(ctx context.Context, userID int) ([]types.UserProduct, error) {
 	var productList []types.UserProduct
-	
-	err := db.WithTransaction(ctx, r.pool, func(pgx.Tx) error {
-		query := `
-			WITH latest_prices AS (
-				SELECT DISTINCT ON (pso.product_id, pso.platform)
-					pso.product_id, pso.platform,
-					psnap.price, psnap.in_stock, psnap.checked_at
-				FROM product_sources pso
-				LEFT JOIN price_snapshots psnap ON pso.id = psnap.product_source_id
-				ORDER BY pso.product_id, pso.platform, psnap.checked_at DESC NULLS LAST
-			),
-			lowest_prices AS (
-				SELECT
-					product_id,
-					MIN(price) FILTER (WHERE price IS NOT NULL) as lowest_price,
-					(ARRAY_AGG(platform ORDER BY price ASC) FILTER (WHERE price IS NOT NULL))[1] as lowest_source,
-					(ARRAY_AGG(in_stock ORDER BY price ASC) FILTER (WHERE price IS NOT NULL))[1] as in_stock
-				FROM latest_prices
-				GROUP BY product_id
-			)
-			SELECT 
-				p.id, p.product_name, p.image_url, p.last_checked_at,
-				uw.added_at, 
-				COALESCE(lp.lowest_price, 0) as lowest_price,
-				COALESCE(lp.lowest_source, '') as lowest_source,
-				COALESCE(lp.in_stock, false) as in_stock
-			FROM user_watchlist uw
-			INNER JOIN products p ON uw.product_id = p.id
-			LEFT JOIN lowest_prices lp ON p.id = lp.product_id
-			WHERE uw.user_id = $1
-			ORDER BY uw.added_at DESC`
-	
-		rows, err := r.pool.Query(
-			context.Background(), 
-			query, 
-			userID,
-		)
 
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := trackedProductsCTE + `
+			SELECT ` + trackedProductsColumns + `
+			FROM tracked
+			ORDER BY added_at DESC`
+
+		rows, err := tx.Query(ctx, query, userID)
 		if err != nil {
 			return err
 		}
+		defer rows.Close()
 
 		for rows.Next() {
-			var product types.UserProduct
-
-			err := rows.Scan(
-				&product.ProductID,
-				&product.ProductName,
-				&product.ImageUrl,
-				&product.LastCheckedAt,
-				&product.AddedAt,
-				&product.LowestPrice,
-				&product.LowestSource,
-				&product.InStock,
-			)
+			product, err := scanUserProduct(rows)
 			if err != nil {
 				return err
 			}
@@ -130,42 +357,240 @@ func (r *Repository) FetchUserTrackedProducts(userID int) ([]types.UserProduct,
 			productList = append(productList, product)
 		}
 
-		return nil
+		return rows.Err()
 	})
 
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to fetch user tracked products", "user_id", userID, "err", err)
 		return []types.UserProduct{}, err
 	}
 
 	return productList, nil
 }
 
-// Delete the specified product for the user
-func (r *Repository) DeleteProductForUser(userID, productID int) error {
-	ctx := context.Background()
+// Fetch one page of the user's tracked products sorted and filtered by the
+// query, along with the cursor for the next page and the total matching count
+func (r *Repository) FetchUserTrackedProductsPage(ctx context.Context, userID int, query types.ProductListQuery) (types.ProductPage, error) {
+	page := types.ProductPage{Products: []types.UserProduct{}}
+
+	sort, ok := trackedProductsSorts[query.Sort]
+	if !ok {
+		return page, fmt.Errorf("unknown sort %q", query.Sort)
+	}
+
+	direction := "ASC"
+	comparison := ">"
+	if query.Order == types.SortDesc {
+		direction = "DESC"
+		comparison = "<"
+	}
+
+	args := []interface{}{userID}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	filters := []string{"TRUE"}
+	if query.InStock != nil {
+		filters = append(filters, "in_stock = "+addArg(*query.InStock))
+	}
+	if len(query.Sources) > 0 {
+		filters = append(filters, `EXISTS (
+			SELECT 1 FROM product_sources pso
+			WHERE pso.product_id = tracked.product_id
+			AND pso.platform = ANY(`+addArg(query.Sources)+`))`)
+	}
+	if query.MinPrice != nil {
+		filters = append(filters, "lowest_price >= "+addArg(*query.MinPrice))
+	}
+	if query.MaxPrice != nil {
+		filters = append(filters, "lowest_price <= "+addArg(*query.MaxPrice))
+	}
+	if query.Search != "" {
+		filters = append(filters, "product_name ILIKE "+addArg("%"+escapeLike(query.Search)+"%"))
+	}
+	if query.CollectionID != nil {
+		filters = append(filters, `EXISTS (
+			SELECT 1 FROM collection_items ci
+			INNER JOIN collections c ON ci.collection_id = c.id
+			WHERE ci.product_id = tracked.product_id
+			AND c.id = `+addArg(*query.CollectionID)+`
+			AND c.user_id = $1)`)
+	}
+	if len(query.Tags) > 0 {
+		filters = append(filters, "tags @> "+addArg(query.Tags)+"::varchar[]")
+	}
+	where := strings.Join(filters, " AND ")
 
 	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
-		query := `
-			DELETE FROM user_watchlist
-			WHERE user_id = $1
-			AND product_id = $2
-		`
+		countQuery := trackedProductsCTE + `
+			SELECT COUNT(*) FROM tracked WHERE ` + where
 
-		cmdTag, err := tx.Exec(ctx, query, userID, productID)
+		err := tx.QueryRow(ctx, countQuery, args...).Scan(&page.TotalCount)
 		if err != nil {
 			return err
 		}
 
-		if cmdTag.RowsAffected() == 0 {
-			return errors.New("product not found in user's watchlist")
+		pageWhere := where
+		if query.Cursor != nil {
+			pageWhere += fmt.Sprintf(" AND (%s, product_id) %s (%s::%s, %s)",
+				sort.expr, comparison, addArg(query.Cursor.Value), sort.cast, addArg(query.Cursor.ProductID))
+		}
+
+		// fetch one extra row to know if there is another page
+		pageQuery := trackedProductsCTE + `
+			SELECT ` + trackedProductsColumns + `
+			FROM tracked
+			WHERE ` + pageWhere + `
+			ORDER BY ` + sort.expr + ` ` + direction + `, product_id ` + direction + `
+			LIMIT ` + addArg(query.Limit+1)
+
+		rows, err := tx.Query(ctx, pageQuery, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			product, err := scanUserProduct(rows)
+			if err != nil {
+				return err
+			}
+
+			page.Products = append(page.Products, product)
 		}
 
-		return nil
+		return rows.Err()
 	})
 
 	if err != nil {
+		logger.FromContext(ctx).Error("failed to fetch user tracked products page", "user_id", userID, "err", err)
+		return types.ProductPage{}, err
+	}
+
+	if len(page.Products) > query.Limit {
+		page.Products = page.Products[:query.Limit]
+		last := page.Products[query.Limit-1]
+
+		page.NextCursor = types.ProductCursor{
+			Sort:      query.Sort,
+			Order:     query.Order,
+			Value:     productSortValue(last, query.Sort),
+			ProductID: last.ProductID,
+		}.Encode()
+	}
+
+	return page, nil
+}
+
+// scan a row selected with trackedProductsColumns
+func scanUserProduct(rows pgx.Rows, extra ...interface{}) (types.UserProduct, error) {
+	var product types.UserProduct
+	var lastCheckedAt *time.Time
+
+	dest := []interface{}{
+		&product.ProductID,
+		&product.ProductName,
+		&product.ImageUrl,
+		&lastCheckedAt,
+		&product.AddedAt,
+		&product.LowestPrice,
+		&product.LowestSource,
+		&product.InStock,
+		&product.PriceDrop,
+		&product.Currency,
+		&product.PriceBasis,
+		&product.TargetPrice,
+		&product.Paused,
+		&product.Tags,
+		&product.DealScore,
+		&product.PricePercentile,
+		&product.AboveAllTimeLow,
+		&product.PriceTrend,
+	}
+
+	err := rows.Scan(append(dest, extra...)...)
+	if err != nil {
+		return types.UserProduct{}, err
+	}
+
+	if lastCheckedAt != nil {
+		product.LastCheckedAt = *lastCheckedAt
+	}
+	product.GoodTimeToBuy = product.DealScore >= types.GoodDealScore
+
+	return product, nil
+}
+
+// value of the sort column for a product, formatted so postgres can cast it
+// back when the cursor is used
+func productSortValue(product types.UserProduct, sort string) string {
+	switch sort {
+	case types.SortByPrice:
+		return strconv.FormatFloat(product.LowestPrice, 'f', -1, 64)
+	case types.SortByBiggestDrop:
+		return strconv.FormatFloat(product.PriceDrop, 'f', -1, 64)
+	case types.SortByDealScore:
+		return strconv.FormatFloat(product.DealScore, 'f', -1, 64)
+	case types.SortByName:
+		return product.ProductName
+	case types.SortByLastChecked:
+		if product.LastCheckedAt.IsZero() {
+			return "epoch"
+		}
+		return product.LastCheckedAt.Format(time.RFC3339Nano)
+	default:
+		return product.AddedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// escape the ILIKE wildcards so user searches match literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// Delete the specified product for the user
+func (r *Repository) DeleteProductForUser(ctx context.Context, userID, productID int) error {
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		return deleteFromWatchlist(ctx, tx, userID, productID)
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to delete product for user", "user_id", userID, "product_id", productID, "err", err)
+		}
 		return err
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// Remove the product from the user's watchlist and their collections
+func deleteFromWatchlist(ctx context.Context, tx pgx.Tx, userID, productID int) error {
+	query := `
+		DELETE FROM user_watchlist
+		WHERE user_id = $1
+		AND product_id = $2
+	`
+
+	cmdTag, err := tx.Exec(ctx, query, userID, productID)
+	if err != nil {
+		return err
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return fmt.Errorf("product %d not found in user's watchlist: %w", productID, store.ErrNotFound)
+	}
+
+	// the product leaves the user's collections with the watchlist
+	collectionsQuery := `
+		DELETE FROM collection_items ci
+		USING collections c
+		WHERE ci.collection_id = c.id
+		AND c.user_id = $1
+		AND ci.product_id = $2`
+
+	_, err = tx.Exec(ctx, collectionsQuery, userID, productID)
+	return err
+}
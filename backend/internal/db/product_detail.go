@@ -0,0 +1,328 @@
+package db
+
+import (
+	"backend/internal/discount"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Fetch one product with every source and its latest snapshot, the lowest
+// price ever seen, the 30 day average and the user's alert rules evaluated
+// against the latest offers. Offers keep their own currency plus a display
+// price on the user's price basis in their display currency, which the stats
+// are computed in
+func (r *Repository) FetchProductDetail(ctx context.Context, userID, productID int) (types.Product, error) {
+	var product types.Product
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		product, err = fetchProductDetail(ctx, tx, userID, productID)
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to fetch product detail", "user_id", userID, "product_id", productID, "err", err)
+		}
+		return types.Product{}, err
+	}
+
+	return product, nil
+}
+
+// product detail queries run on the caller's transaction so inserts can
+// return the product they just created
+func fetchProductDetail(ctx context.Context, tx pgx.Tx, userID, productID int) (types.Product, error) {
+	product := types.Product{
+		Prices: 	[]types.PriceData{},
+		Sources: 	[]types.ProductOffer{},
+		Alerts: 	[]types.ProductAlert{},
+	}
+
+	productQuery := `
+		SELECT
+			id, product_name, COALESCE(image_url, ''), created_at, last_checked_at,
+			COALESCE((SELECT display_currency FROM users WHERE id = $2), $3),
+			COALESCE((SELECT price_basis FROM users WHERE id = $2), $4)
+		FROM products
+		WHERE id = $1`
+
+	err := tx.QueryRow(ctx, productQuery, productID, userID, types.DefaultDisplayCurrency, types.PriceBasisSticker).Scan(
+		&product.ID,
+		&product.Name,
+		&product.ImageUrl,
+		&product.CreatedAt,
+		&product.LastCheckedAt,
+		&product.Currency,
+		&product.PriceBasis,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return types.Product{}, fmt.Errorf("product %d: %w", productID, store.ErrNotFound)
+	}
+	if err != nil {
+		return types.Product{}, err
+	}
+
+	// offers passing the user's watchlist filters first, then the cheapest
+	// offer first with sources that were never scraped last. Each offer comes
+	// with the source's prices before its latest snapshot to check claimed
+	// discounts against
+	sourcesQuery := `
+		SELECT
+			ps.id, ps.platform,
+			COALESCE(ps.platform_product_id, ''),
+			COALESCE(ps.product_url, ''),
+			COALESCE(latest.price, 0),
+			COALESCE(latest.currency, ''),
+			COALESCE(latest.shipping_cost, 0),
+			COALESCE(latest.estimated_tax, 0),
+			COALESCE(latest.discount, 0),
+			COALESCE(latest.landed_price, 0),
+			COALESCE(latest.display_price, 0),
+			COALESCE(latest.in_stock, false),
+			latest.checked_at,
+			COALESCE(latest.condition, ps.condition),
+			COALESCE(latest.seller_name, ps.seller_name, ''),
+			COALESCE(latest.seller_rating, 0)::float8,
+			offer_acceptable(
+				COALESCE(latest.condition, ps.condition), COALESCE(latest.seller_name, ps.seller_name), latest.seller_rating,
+				uw.acceptable_conditions, uw.min_seller_rating, uw.allow_unrated_sellers
+			) AND ps.platform <> ALL(COALESCE(uw.excluded_platforms, '{}')) as acceptable,
+			COALESCE(latest.list_price, 0),
+			COALESCE(prior.lowest_30d, 0),
+			COALESCE(prior.highest_90d, 0)
+		FROM product_sources ps
+		LEFT JOIN user_watchlist uw ON uw.product_id = ps.product_id AND uw.user_id = $4
+		LEFT JOIN LATERAL (
+			SELECT
+				price, currency, in_stock, checked_at,
+				shipping_cost, estimated_tax, discount, list_price,
+				condition, seller_name, seller_rating,
+				effective_price(price, shipping_cost, estimated_tax, discount, 'landed') as landed_price,
+				convert_price(
+					effective_price(price, shipping_cost, estimated_tax, discount, $3),
+					currency, $2, checked_at
+				) as display_price
+			FROM price_snapshots
+			WHERE product_source_id = ps.id
+			ORDER BY checked_at DESC
+			LIMIT 1
+		) latest ON true
+		LEFT JOIN LATERAL (
+			SELECT
+				MIN(price) FILTER (WHERE checked_at >= latest.checked_at - INTERVAL '30 days') as lowest_30d,
+				MAX(price) as highest_90d
+			FROM price_snapshots
+			WHERE product_source_id = ps.id
+			AND checked_at < latest.checked_at
+			AND checked_at >= latest.checked_at - INTERVAL '90 days'
+			AND currency = latest.currency
+			AND price IS NOT NULL
+		) prior ON true
+		WHERE ps.product_id = $1
+		ORDER BY acceptable DESC, latest.display_price ASC NULLS LAST, ps.id ASC`
+
+	rows, err := tx.Query(ctx, sourcesQuery, productID, product.Currency, product.PriceBasis, userID)
+	if err != nil {
+		return types.Product{}, err
+	}
+
+	for rows.Next() {
+		var offer types.ProductOffer
+		var listPrice float64
+		var history discount.History
+
+		err := rows.Scan(
+			&offer.SourceID,
+			&offer.Platform,
+			&offer.PlatformProductID,
+			&offer.URL,
+			&offer.Price,
+			&offer.Currency,
+			&offer.ShippingCost,
+			&offer.EstimatedTax,
+			&offer.Discount,
+			&offer.LandedPrice,
+			&offer.DisplayPrice,
+			&offer.InStock,
+			&offer.CheckedAt,
+			&offer.Condition,
+			&offer.SellerName,
+			&offer.SellerRating,
+			&offer.Acceptable,
+			&listPrice,
+			&history.LowestPrice30d,
+			&history.HighestPrice90d,
+		)
+		if err != nil {
+			rows.Close()
+			return types.Product{}, err
+		}
+		offer.DiscountCheck = discount.Check(listPrice, offer.Price, history)
+
+		product.Sources = append(product.Sources, offer)
+		if offer.CheckedAt != nil {
+			product.Prices = append(product.Prices, types.PriceData{
+				Source: 	offer.Platform,
+				Price: 		offer.DisplayPrice,
+				InStock: 	offer.InStock,
+				Timestamp: 	*offer.CheckedAt,
+			})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return types.Product{}, err
+	}
+
+	// the product links to its cheapest acceptable offer
+	if len(product.Sources) > 0 {
+		product.URL = product.Sources[0].URL
+	}
+
+	// snapshots in currencies without rates are left out of the stats
+	statsQuery := `
+		WITH history AS (
+			SELECT
+				convert_price(
+					effective_price(psnap.price, psnap.shipping_cost, psnap.estimated_tax, psnap.discount, $4),
+					psnap.currency, $3, psnap.checked_at
+				) as price,
+				psnap.checked_at
+			FROM product_sources ps
+			INNER JOIN price_snapshots psnap ON ps.id = psnap.product_source_id
+			WHERE ps.product_id = $1
+		)
+		SELECT
+			COALESCE(MIN(price), 0),
+			COALESCE(AVG(price) FILTER (WHERE checked_at > $2), 0)::float8
+		FROM history
+		WHERE price IS NOT NULL`
+
+	err = tx.QueryRow(ctx, statsQuery, productID, time.Now().AddDate(0, 0, -30), product.Currency, product.PriceBasis).Scan(
+		&product.LowestEverPrice,
+		&product.AvgPrice30d,
+	)
+	if err != nil {
+		return types.Product{}, err
+	}
+
+	// rules are evaluated on their own price basis against the latest snapshot
+	// of each source, the cheapest in stock acceptable offer sets the current
+	// price and the 30 day average covers every source like the stats above.
+	// Rules of a paused watchlist entry never trigger and the offer's discount
+	// check from the sources above flags misleading discounts
+	alertsQuery := `
+		SELECT
+			ar.id, ar.rule_type, COALESCE(ar.threshold, 0), ar.basis, ar.active, ar.created_at,
+			COALESCE(offer.price, 0)::float8,
+			COALESCE(offer.source_id, 0),
+			COALESCE(history.avg_30d, 0)::float8,
+			COALESCE((SELECT paused FROM user_watchlist WHERE user_id = $1 AND product_id = $2), false)
+		FROM (
+			SELECT id, rule_type, threshold, active, created_at, COALESCE(price_basis, $3) as basis
+			FROM alert_rules
+			WHERE user_id = $1
+			AND product_id = $2
+		) ar
+		LEFT JOIN LATERAL (
+			SELECT
+				ps.id as source_id,
+				convert_price(
+					effective_price(latest.price, latest.shipping_cost, latest.estimated_tax, latest.discount, ar.basis),
+					latest.currency, $4, latest.checked_at
+				) as price
+			FROM product_sources ps
+			LEFT JOIN user_watchlist uw ON uw.product_id = ps.product_id AND uw.user_id = $1
+			INNER JOIN LATERAL (
+				SELECT price, currency, in_stock, checked_at, shipping_cost, estimated_tax, discount, condition, seller_name, seller_rating
+				FROM price_snapshots
+				WHERE product_source_id = ps.id
+				ORDER BY checked_at DESC
+				LIMIT 1
+			) latest ON true
+			WHERE ps.product_id = $2
+			AND latest.in_stock
+			AND offer_acceptable(
+				COALESCE(latest.condition, ps.condition), COALESCE(latest.seller_name, ps.seller_name), latest.seller_rating,
+				uw.acceptable_conditions, uw.min_seller_rating, uw.allow_unrated_sellers
+			)
+			AND ps.platform <> ALL(COALESCE(uw.excluded_platforms, '{}'))
+			ORDER BY price ASC NULLS LAST, ps.id ASC
+			LIMIT 1
+		) offer ON true
+		LEFT JOIN LATERAL (
+			SELECT AVG(convert_price(
+				effective_price(psnap.price, psnap.shipping_cost, psnap.estimated_tax, psnap.discount, ar.basis),
+				psnap.currency, $4, psnap.checked_at
+			)) as avg_30d
+			FROM product_sources ps
+			INNER JOIN price_snapshots psnap ON ps.id = psnap.product_source_id
+			WHERE ps.product_id = $2
+			AND psnap.checked_at > $5
+		) history ON true
+		ORDER BY ar.created_at ASC, ar.id ASC`
+
+	rows, err = tx.Query(ctx, alertsQuery, userID, productID, product.PriceBasis, product.Currency, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		return types.Product{}, err
+	}
+	defer rows.Close()
+
+	discountChecks := map[int]*types.DiscountCheck{}
+	for _, offer := range product.Sources {
+		discountChecks[offer.SourceID] = offer.DiscountCheck
+	}
+
+	for rows.Next() {
+		var alert types.ProductAlert
+		var paused bool
+		var avgPrice30d float64
+
+		err := rows.Scan(
+			&alert.ID,
+			&alert.Type,
+			&alert.Threshold,
+			&alert.PriceBasis,
+			&alert.Active,
+			&alert.CreatedAt,
+			&alert.CurrentPrice,
+			&alert.SourceID,
+			&avgPrice30d,
+			&paused,
+		)
+		if err != nil {
+			return types.Product{}, err
+		}
+		check := discountChecks[alert.SourceID]
+		alert.MisleadingDiscount = check != nil && check.Misleading
+		alert.Triggered = alert.Active && !paused && alertTriggered(alert, alert.SourceID != 0, avgPrice30d)
+
+		product.Alerts = append(product.Alerts, alert)
+	}
+
+	return product, rows.Err()
+}
+
+// whether the current price meets the rule, price_below at or under the
+// threshold, percent_drop at least threshold percent under the 30 day average
+// and back_in_stock as soon as any acceptable offer is in stock
+func alertTriggered(alert types.ProductAlert, inStock bool, avgPrice30d float64) bool {
+	switch alert.Type {
+	case types.AlertPriceBelow:
+		return alert.CurrentPrice > 0 && alert.CurrentPrice <= alert.Threshold
+	case types.AlertPercentDrop:
+		return alert.CurrentPrice > 0 && avgPrice30d > 0 && (avgPrice30d-alert.CurrentPrice)/avgPrice30d*100 >= alert.Threshold
+	case types.AlertBackInStock:
+		return inStock
+	}
+	return false
+}
@@ -0,0 +1,383 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for FetchProductDetail SQL func
+func TestFetchProductDetail(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	t.Run("returns sources with their latest snapshot and price stats", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Sony WH-1000XM5", "https://example.com/xm5.jpg")
+
+		amazonID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID:         productID,
+			Platform:          "amazon",
+			PlatformProductID: "B09XS7JWHH",
+			URL:               "https://www.amazon.com/dp/B09XS7JWHH",
+		})
+		bestBuyID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID:         productID,
+			Platform:          "bestbuy",
+			PlatformProductID: "6505727",
+			URL:               "https://www.bestbuy.com/site/6505727.p?skuId=6505727",
+		})
+		test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID:         productID,
+			Platform:          "newegg",
+			PlatformProductID: "N82E16826",
+			URL:               "https://www.newegg.com/p/N82E16826",
+		})
+
+		longAgo := time.Now().AddDate(0, -3, 0)
+		lastWeek := time.Now().AddDate(0, 0, -7)
+		yesterday := time.Now().AddDate(0, 0, -1)
+
+		// the all time low is older than 30 days so it doesn't count towards the average
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 199.99, InStock: true, CheckedAt: &longAgo})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 349.99, InStock: true, CheckedAt: &lastWeek})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 329.99, InStock: false, CheckedAt: &yesterday})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: bestBuyID, Price: 300.00, InStock: true, CheckedAt: &yesterday})
+
+		product, err := repo.FetchProductDetail(ctx, userID, productID)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Sony WH-1000XM5", product.Name)
+		assert.Equal(t, "https://example.com/xm5.jpg", product.ImageUrl)
+		assert.Equal(t, 199.99, product.LowestEverPrice)
+		assert.InDelta(t, (349.99+329.99+300.00)/3, product.AvgPrice30d, 0.01)
+
+		require.Len(t, product.Sources, 3)
+		assert.Equal(t, "bestbuy", product.Sources[0].Platform, "cheapest offer first")
+		assert.Equal(t, 300.00, product.Sources[0].Price)
+		assert.Equal(t, "USD", product.Sources[0].Currency)
+		assert.Equal(t, "amazon", product.Sources[1].Platform)
+		assert.Equal(t, 329.99, product.Sources[1].Price, "latest snapshot, not the lowest")
+		assert.False(t, product.Sources[1].InStock)
+		assert.Equal(t, "newegg", product.Sources[2].Platform, "sources never scraped come last")
+		assert.Nil(t, product.Sources[2].CheckedAt)
+
+		assert.Len(t, product.Prices, 2)
+		assert.Equal(t, "https://www.bestbuy.com/site/6505727.p?skuId=6505727", product.URL)
+	})
+
+	t.Run("converts offers and stats to the user's display currency", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		test.SetDisplayCurrency(t, pool, userID, "EUR")
+		productID := test.SeedProduct(t, pool, "Global Product", "")
+
+		usID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID:         productID,
+			Platform:          "amazon_us",
+			PlatformProductID: "US123",
+			URL:               "https://www.amazon.com/dp/US123",
+		})
+		ukID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID:         productID,
+			Platform:          "amazon_uk",
+			PlatformProductID: "UK123",
+			URL:               "https://www.amazon.co.uk/dp/UK123",
+		})
+
+		test.SeedExchangeRate(t, pool, time.Now(), "USD", 1.25)
+		test.SeedExchangeRate(t, pool, time.Now(), "GBP", 0.80)
+
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: usID, Price: 100, Currency: "USD", InStock: true})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: ukID, Price: 72, Currency: "GBP", InStock: true})
+
+		product, err := repo.FetchProductDetail(ctx, userID, productID)
+
+		require.NoError(t, err)
+		assert.Equal(t, "EUR", product.Currency)
+		require.Len(t, product.Sources, 2)
+		assert.Equal(t, "amazon_us", product.Sources[0].Platform, "USD 100 is EUR 80, GBP 72 is EUR 90")
+		assert.Equal(t, 100.0, product.Sources[0].Price)
+		assert.Equal(t, "USD", product.Sources[0].Currency)
+		assert.Equal(t, 80.0, product.Sources[0].DisplayPrice)
+		assert.Equal(t, 90.0, product.Sources[1].DisplayPrice)
+		assert.Equal(t, 80.0, product.LowestEverPrice)
+		assert.InDelta(t, 85.0, product.AvgPrice30d, 0.01)
+	})
+
+	t.Run("breaks down landed cost and uses it for the user's price basis", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		test.SetPriceBasis(t, pool, userID, "landed")
+		productID := test.SeedProduct(t, pool, "Heavy Product", "")
+
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID:         productID,
+			Platform:          "ebay",
+			PlatformProductID: "EBAY123",
+			URL:               "https://www.ebay.com/itm/123456789",
+		})
+
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{
+			ProductSourceID: sourceID,
+			Price:           95,
+			InStock:         true,
+			ShippingCost:    15,
+			EstimatedTax:    7.60,
+			Discount:        5,
+		})
+
+		product, err := repo.FetchProductDetail(ctx, userID, productID)
+
+		require.NoError(t, err)
+		assert.Equal(t, "landed", product.PriceBasis)
+		require.Len(t, product.Sources, 1)
+
+		offer := product.Sources[0]
+		assert.Equal(t, 95.0, offer.Price)
+		assert.Equal(t, 15.0, offer.ShippingCost)
+		assert.Equal(t, 7.60, offer.EstimatedTax)
+		assert.Equal(t, 5.0, offer.Discount)
+		assert.Equal(t, 112.60, offer.LandedPrice)
+		assert.Equal(t, 112.60, offer.DisplayPrice)
+		assert.Equal(t, 112.60, product.LowestEverPrice)
+	})
+
+	t.Run("checks claimed discounts against each source's price history", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Sony WH-1000XM5", "")
+
+		genuineID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID: 			productID,
+			Platform: 			"amazon",
+			PlatformProductID: 	"B09XS7JWHH",
+			URL: 				"https://www.amazon.com/dp/B09XS7JWHH",
+		})
+		inflatedID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID: 			productID,
+			Platform: 			"bestbuy",
+			PlatformProductID: 	"6505727",
+			URL: 				"https://www.bestbuy.com/site/6505727.p?skuId=6505727",
+		})
+
+		twoMonthsAgo := time.Now().AddDate(0, -2, 0)
+		lastWeek := time.Now().AddDate(0, 0, -7)
+
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: genuineID, Price: 399.99, InStock: true, CheckedAt: &lastWeek})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: genuineID, Price: 299.99, ListPrice: 399.99, InStock: true})
+
+		// list price never charged, the source sat at 329.99
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: inflatedID, Price: 349.99, InStock: true, CheckedAt: &twoMonthsAgo})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: inflatedID, Price: 329.99, InStock: true, CheckedAt: &lastWeek})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: inflatedID, Price: 319.99, ListPrice: 499.99, InStock: true})
+
+		product, err := repo.FetchProductDetail(ctx, userID, productID)
+
+		require.NoError(t, err)
+		require.Len(t, product.Sources, 2)
+
+		genuine := product.Sources[0].DiscountCheck
+		require.NotNil(t, genuine)
+		assert.Equal(t, 399.99, genuine.ListPrice)
+		assert.Equal(t, 25.0, genuine.ClaimedPercent)
+		assert.Equal(t, 399.99, genuine.ReferencePrice)
+		assert.True(t, genuine.Verified)
+		assert.False(t, genuine.Misleading)
+
+		inflated := product.Sources[1].DiscountCheck
+		require.NotNil(t, inflated)
+		assert.Equal(t, 329.99, inflated.ReferencePrice)
+		assert.Equal(t, 349.99, inflated.HighestPrice90d)
+		assert.True(t, inflated.Misleading)
+		assert.Equal(t, types.DiscountReasonNeverCharged, inflated.Reason)
+	})
+
+	t.Run("checks discounts on the listed sticker price and flags them on alerts", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		test.SetDisplayCurrency(t, pool, userID, "USD")
+		test.SetPriceBasis(t, pool, userID, "landed")
+		productID := test.SeedProduct(t, pool, "Sony WH-1000XM5", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID: 			productID,
+			Platform: 			"amazon",
+			PlatformProductID: 	"B09XS7JWHH",
+			URL: 				"https://www.amazon.de/dp/B09XS7JWHH",
+		})
+
+		test.SeedExchangeRate(t, pool, time.Now().AddDate(0, 0, -30), "USD", 1.25)
+
+		// listed in EUR at 100 "down from 200" after sitting at 110, shown as
+		// (100 + 20 shipping) * 1.25 = 150 USD landed
+		lastWeek := time.Now().AddDate(0, 0, -7)
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 110, Currency: "EUR", InStock: true, CheckedAt: &lastWeek})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 100, Currency: "EUR", ShippingCost: 20, ListPrice: 200, InStock: true})
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPriceBelow, Threshold: 160, Active: true})
+
+		product, err := repo.FetchProductDetail(ctx, userID, productID)
+
+		require.NoError(t, err)
+		require.Len(t, product.Sources, 1)
+		assert.Equal(t, 150.0, product.Sources[0].DisplayPrice)
+
+		check := product.Sources[0].DiscountCheck
+		require.NotNil(t, check)
+		assert.Equal(t, 200.0, check.ListPrice)
+		assert.Equal(t, 50.0, check.ClaimedPercent, "the sticker price in EUR, not the converted landed one")
+		assert.Equal(t, 110.0, check.ReferencePrice)
+		assert.True(t, check.Misleading)
+
+		require.Len(t, product.Alerts, 1)
+		alert := product.Alerts[0]
+		assert.Equal(t, 150.0, alert.CurrentPrice)
+		assert.Equal(t, sourceID, alert.SourceID)
+		assert.True(t, alert.MisleadingDiscount)
+		assert.True(t, alert.Triggered)
+	})
+
+	t.Run("offers without a list price have no discount check", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Sony WH-1000XM5", "")
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID: 			productID,
+			Platform: 			"amazon",
+			PlatformProductID: 	"B09XS7JWHH",
+			URL: 				"https://www.amazon.com/dp/B09XS7JWHH",
+		})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 299.99, InStock: true})
+
+		product, err := repo.FetchProductDetail(ctx, userID, productID)
+
+		require.NoError(t, err)
+		require.Len(t, product.Sources, 1)
+		assert.Nil(t, product.Sources[0].DiscountCheck)
+	})
+
+	t.Run("returns only the user's alert rules", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		user1 := test.SeedUser(t, pool, "user1", "user1@example.com")
+		user2 := test.SeedUser(t, pool, "user2", "user2@example.com")
+		productID := test.SeedProduct(t, pool, "Product A", "")
+
+		alertID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: user1, ProductID: productID, RuleType: "price_below", Threshold: 250, Active: true})
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: user2, ProductID: productID, RuleType: "back_in_stock", Active: true})
+
+		product, err := repo.FetchProductDetail(ctx, user1, productID)
+
+		require.NoError(t, err)
+		require.Len(t, product.Alerts, 1)
+		assert.Equal(t, alertID, product.Alerts[0].ID)
+		assert.Equal(t, "price_below", product.Alerts[0].Type)
+		assert.Equal(t, 250.0, product.Alerts[0].Threshold)
+	})
+
+	t.Run("evaluates alerts on their own price basis or the user's", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Heavy Product", "")
+
+		ebayID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "ebay", URL: "https://www.ebay.com/itm/123456789"})
+		amazonID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://www.amazon.com/dp/B0HEAVY123"})
+
+		lastWeek := time.Now().AddDate(0, 0, -7)
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: ebayID, Price: 125, InStock: true, CheckedAt: &lastWeek})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{
+			ProductSourceID: 	ebayID,
+			Price: 				95,
+			InStock: 			true,
+			ShippingCost: 		15,
+			EstimatedTax: 		7.60,
+			Discount: 			5,
+		})
+		// cheaper but out of stock, only counts towards the average
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 80, InStock: false})
+
+		stickerID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPriceBelow, Threshold: 100, Active: true})
+		landedID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPriceBelow, Threshold: 100, PriceBasis: "landed", Active: true})
+		dropID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPercentDrop, Threshold: 4, Active: true})
+		stockID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertBackInStock, Active: true})
+		inactiveID := test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPriceBelow, Threshold: 100, Active: false})
+
+		product, err := repo.FetchProductDetail(ctx, userID, productID)
+
+		require.NoError(t, err)
+		alerts := map[int]types.ProductAlert{}
+		for _, alert := range product.Alerts {
+			alerts[alert.ID] = alert
+		}
+		require.Len(t, alerts, 5)
+
+		assert.Equal(t, "sticker", alerts[stickerID].PriceBasis, "follows the user's price basis")
+		assert.Equal(t, 95.0, alerts[stickerID].CurrentPrice)
+		assert.True(t, alerts[stickerID].Triggered)
+
+		assert.Equal(t, "landed", alerts[landedID].PriceBasis)
+		assert.Equal(t, 112.60, alerts[landedID].CurrentPrice)
+		assert.False(t, alerts[landedID].Triggered, "shipping and tax push the landed cost over the threshold")
+
+		assert.True(t, alerts[dropID].Triggered, "95 is 5% under the 30 day average of 100")
+		assert.True(t, alerts[stockID].Triggered)
+		assert.False(t, alerts[inactiveID].Triggered)
+
+		test.SetPriceBasis(t, pool, userID, "landed")
+
+		product, err = repo.FetchProductDetail(ctx, userID, productID)
+
+		require.NoError(t, err)
+		for _, alert := range product.Alerts {
+			if alert.ID == stickerID {
+				assert.Equal(t, "landed", alert.PriceBasis)
+				assert.Equal(t, 112.60, alert.CurrentPrice)
+				assert.False(t, alert.Triggered, "rules without their own basis follow the user's change")
+			}
+		}
+	})
+
+	t.Run("product without sources has empty lists and zero stats", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Product A", "")
+
+		product, err := repo.FetchProductDetail(ctx, userID, productID)
+
+		require.NoError(t, err)
+		assert.Empty(t, product.Sources)
+		assert.Empty(t, product.Alerts)
+		assert.Empty(t, product.URL)
+		assert.Zero(t, product.LowestEverPrice)
+		assert.Zero(t, product.AvgPrice30d)
+	})
+
+	t.Run("returns not found for missing products", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		_, err := repo.FetchProductDetail(ctx, userID, 99999)
+
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
@@ -4,6 +4,8 @@ package db_test
 
 import (
 	"backend/internal/db"
+	"backend/internal/platform"
+	"backend/internal/store"
 	"backend/internal/types"
 	"backend/pkg/test"
 	"context"
@@ -12,6 +14,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -34,17 +37,19 @@ func TestMain(m *testing.M) {
 func TestInsertProductForUser(t *testing.T) {
 	pool := testDB.Pool
 	repo := db.NewRepository(pool)
+	ctx := context.Background()
 
 	t.Run("Returns correct added product", func(t *testing.T) {
 		test.CleanupTables(t, pool)
 
 		userID := test.SeedUser(t, pool, "username1", "example@example.com")
-		product, err := repo.InsertProductForUser(userID, "product")
+		product, err := repo.InsertProductForUser(ctx, userID, "product")
 		
 		require.NoError(t, err)
 		require.NotEmpty(t, product.ID, "product ID should be returned")
 		assert.Equal(t, "product", product.Name, "return product name should match input")
-		require.NotEmpty(t, product.URL, "product url should be returned")
+		assert.Empty(t, product.URL, "product added by name has no source to link to yet")
+		require.NotNil(t, product.Sources, "product sources should be returned")
 		require.NotZero(t, product.CreatedAt, "product url should be returned")
 		require.NotNil(t, product.Prices, "product url should be returned")
 	})
@@ -67,14 +72,15 @@ func TestInsertProductForUser(t *testing.T) {
 		for _, user := range users {
 			userID := test.SeedUser(t, pool, user.Username, user.Email)
 
-			product, err = repo.InsertProductForUser(userID, "product")
+			product, err = repo.InsertProductForUser(ctx, userID, "product")
 			require.NoError(t, err)
 		}
 
 
 		require.NotEmpty(t, product.ID, "product ID should be returned")
 		assert.Equal(t, "product", product.Name, "return product name should match input")
-		require.NotEmpty(t, product.URL, "product url should be returned")
+		assert.Empty(t, product.URL, "product added by name has no source to link to yet")
+		require.NotNil(t, product.Sources, "product sources should be returned")
 		require.NotZero(t, product.CreatedAt, "product url should be returned")
 		require.NotNil(t, product.Prices, "product url should be returned")
 	})
@@ -89,7 +95,7 @@ func TestInsertProductForUser(t *testing.T) {
 		var pgErr *pgconn.PgError
 
 		for i := 0; i < 2; i++ {
-			_, err = repo.InsertProductForUser(userID, "product")
+			_, err = repo.InsertProductForUser(ctx, userID, "product")
 		}
 
 		require.ErrorAs(t, err, &pgErr, "Should throw error due to duplicate product for the same error")
@@ -108,7 +114,7 @@ func TestInsertProductForUser(t *testing.T) {
 		test.SeedProduct(t, pool, nonExistantProduct, "https://imgur.com/idk.jpg")
 
 		// attempt to insert, will fail on second insert because the user doesn't exist
-		_, err := repo.InsertProductForUser(nonExistantUserID, insertProduct)
+		_, err := repo.InsertProductForUser(ctx, nonExistantUserID, insertProduct)
 		require.Error(t, err, "Should fail due to non-existant user")
 
 		var pgErr *pgconn.PgError
@@ -125,19 +131,164 @@ func TestInsertProductForUser(t *testing.T) {
 		require.NoError(t, insertErr)
 		assert.Equal(t, 0, countAfter, "Product should not exist after rollback")
 	})
+
+	t.Run("name variations resolve to the existing product", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		user1 := test.SeedUser(t, pool, "user1", "user1@example.com")
+		user2 := test.SeedUser(t, pool, "user2", "user2@example.com")
+
+		first, err := repo.InsertProductForUser(ctx, user1, "Samsung 980 Pro 2TB")
+		require.NoError(t, err)
+
+		second, err := repo.InsertProductForUser(ctx, user2, "  samsung 980 PRO 2 tb ")
+		require.NoError(t, err)
+
+		assert.Equal(t, first.ID, second.ID)
+		assert.Equal(t, "Samsung 980 Pro 2TB", second.Name, "the existing display name is kept")
+
+		var productCount int
+		require.NoError(t, pool.QueryRow(ctx, "SELECT COUNT(*) FROM products").Scan(&productCount))
+		assert.Equal(t, 1, productCount)
+	})
+
+	t.Run("merged names resolve to the canonical product", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		canonicalID := test.SeedProduct(t, pool, "Sony WH-1000XM5", "")
+		duplicateID := test.SeedProduct(t, pool, "Sony WH1000XM5 Headphones", "")
+
+		_, err := repo.MergeProducts(ctx, duplicateID, canonicalID)
+		require.NoError(t, err)
+
+		product, err := repo.InsertProductForUser(ctx, userID, "sony wh1000xm5 headphones")
+		require.NoError(t, err)
+		assert.Equal(t, canonicalID, product.ID)
+	})
+}
+
+// Integration tests for InsertProductURLForUser SQL func
+func TestInsertProductURLForUser(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	source := types.ProductSource{
+		Platform: 			"amazon",
+		PlatformProductID: 	"B09XS7JWHH",
+		URL: 				"https://www.amazon.com/dp/B09XS7JWHH",
+	}
+
+	t.Run("creates the product and its source", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		product, err := repo.InsertProductURLForUser(ctx, userID, "Sony WH-1000XM5", source)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Sony WH-1000XM5", product.Name)
+		assert.Equal(t, source.URL, product.URL)
+
+		var sourceProductID int
+		var productURL string
+		require.NoError(t, pool.QueryRow(ctx,
+			`SELECT product_id, product_url FROM product_sources WHERE platform = $1 AND platform_product_id = $2`,
+			source.Platform, source.PlatformProductID,
+		).Scan(&sourceProductID, &productURL))
+		assert.Equal(t, product.ID, sourceProductID)
+		assert.Equal(t, source.URL, productURL)
+
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
+		require.NoError(t, err)
+		assert.Len(t, products, 1)
+	})
+
+	t.Run("reuses the product of an existing listing", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		user1 := test.SeedUser(t, pool, "user1", "user1@example.com")
+		user2 := test.SeedUser(t, pool, "user2", "user2@example.com")
+
+		first, err := repo.InsertProductURLForUser(ctx, user1, "Sony WH-1000XM5", source)
+		require.NoError(t, err)
+
+		second, err := repo.InsertProductURLForUser(ctx, user2, "Amazon B09XS7JWHH", source)
+		require.NoError(t, err)
+
+		assert.Equal(t, first.ID, second.ID)
+		assert.Equal(t, "Sony WH-1000XM5", second.Name)
+
+		var sources int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM product_sources`).Scan(&sources))
+		assert.Equal(t, 1, sources)
+	})
+
+	t.Run("adds the listing to a product found by name", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Sony WH-1000XM5", "")
+
+		product, err := repo.InsertProductURLForUser(ctx, userID, "sony wh-1000xm5", source)
+
+		require.NoError(t, err)
+		assert.Equal(t, productID, product.ID)
+	})
+
+	t.Run("keeps listings of the same ASIN from regional stores apart", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		user1 := test.SeedUser(t, pool, "user1", "user1@example.com")
+		user2 := test.SeedUser(t, pool, "user2", "user2@example.com")
+
+		us, err := platform.ParseURL("https://www.amazon.com/dp/B09XS7JWHH")
+		require.NoError(t, err)
+		uk, err := platform.ParseURL("https://www.amazon.co.uk/dp/B09XS7JWHH")
+		require.NoError(t, err)
+
+		_, err = repo.InsertProductURLForUser(ctx, user1, us.FallbackName(), types.ProductSource{Platform: us.Platform, PlatformProductID: us.ProductID, URL: us.URL})
+		require.NoError(t, err)
+
+		product, err := repo.InsertProductURLForUser(ctx, user2, uk.FallbackName(), types.ProductSource{Platform: uk.Platform, PlatformProductID: uk.ProductID, URL: uk.URL})
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://www.amazon.co.uk/dp/B09XS7JWHH", product.URL, "tracks the store the user pasted")
+
+		var urls []string
+		require.NoError(t, pool.QueryRow(ctx, `SELECT array_agg(product_url ORDER BY id) FROM product_sources`).Scan(&urls))
+		assert.Equal(t, []string{"https://www.amazon.com/dp/B09XS7JWHH", "https://www.amazon.co.uk/dp/B09XS7JWHH"}, urls)
+	})
+
+	t.Run("adding the same listing twice is a unique violation and rolls back", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		_, err := repo.InsertProductURLForUser(ctx, userID, "Sony WH-1000XM5", source)
+		require.NoError(t, err)
+
+		_, err = repo.InsertProductURLForUser(ctx, userID, "Sony WH-1000XM5", source)
+
+		var pgErr *pgconn.PgError
+		require.ErrorAs(t, err, &pgErr)
+		assert.Equal(t, "23505", pgErr.Code)
+	})
 }
 
 // Integration tests for FetchUserTrackedProducts SQL func
 func TestFetchUserTrackedProducts(t *testing.T) {
 	pool := testDB.Pool
 	repo := db.NewRepository(pool)
+	ctx := context.Background()
 
 	t.Run("returns empty list for user with no tracked products", func(t *testing.T) {
 		test.CleanupTables(t, pool)
 
 		userID := test.SeedUser(t, pool, "user1", "empty@example.com")
 
-		products, err := repo.FetchUserTrackedProducts(userID)
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
 
 		require.NoError(t, err)
 		assert.Empty(t, products, "Expected no products for user with empty watchlist")
@@ -187,7 +338,7 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 			InStock: 			true,
 		})
 
-		products, err := repo.FetchUserTrackedProducts(userID)
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
 
 		require.NoError(t, err)
 		require.Len(t, products, 1, "Expected 1 product")
@@ -237,7 +388,7 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 				InStock: 		 false,	
 		})
 
-		products, err := repo.FetchUserTrackedProducts(userID)
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
 
 		require.NoError(t, err)
 		require.Len(t,  products, 2, "Expected 2 products")
@@ -274,7 +425,7 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 				URL: 				"https://amazon.com/new",
 		})
 
-		products, err := repo.FetchUserTrackedProducts(userID)
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
 
 		require.NoError(t, err)
 		require.Len(t, products, 1)
@@ -319,7 +470,7 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 				InStock: 			true,	
 		})
 
-		products, err := repo.FetchUserTrackedProducts(user1ID)
+		products, err := repo.FetchUserTrackedProducts(ctx, user1ID)
 
 		require.NoError(t, err)
 		require.Len(t, products, 1, "User 1 only has one product")
@@ -327,7 +478,7 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 		assert.Equal(t, 100.00, products[0].LowestPrice)
 	})
 
-	t.Run("Handles product with multiple prices in different currencies", func(t *testing.T) {
+	t.Run("Converts prices in different currencies to the user's display currency", func(t *testing.T) {
 		test.CleanupTables(t, pool)
 		
 		userID := test.SeedUser(t, pool, "user1", "currency@example.com")		
@@ -347,6 +498,11 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 				URL: 				"https://amazon.co.uk/product",
 		})
 
+		// 1 EUR = 1.10 USD = 0.85 GBP so GBP 79.99 is about USD 103.52
+		today := time.Now()
+		test.SeedExchangeRate(t, pool, today, "USD", 1.10)
+		test.SeedExchangeRate(t, pool, today, "GBP", 0.85)
+
 		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{
 				ProductSourceID: 	usSourceID,
 				Price: 				99.99,
@@ -360,16 +516,149 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 				InStock: 			true,	
 		})
 
-		products, err := repo.FetchUserTrackedProducts(userID)
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
 
 		require.NoError(t, err)
 		require.Len(t, products, 1, "User has one product with multiple currency sources")
 
-		// Note: This test shows a limitation - comparing prices across currencies without conversion
-		// The query will return the lowest numeric value, which may not be the actual cheapest
 		assert.Equal(t, "Global Product", products[0].ProductName)
-		assert.Equal(t, 79.99, products[0].LowestPrice, "GBP 79.99 is lower numerically than USD 99.99")
-		assert.Equal(t, "amazon_uk", products[0].LowestSource)
+		assert.Equal(t, 99.99, products[0].LowestPrice, "GBP 79.99 converts to more than USD 99.99")
+		assert.Equal(t, "amazon_us", products[0].LowestSource)
+		assert.Equal(t, "USD", products[0].Currency)
+
+		// in GBP the US listing is about GBP 77.27 and still the cheapest
+		test.SetDisplayCurrency(t, pool, userID, "GBP")
+
+		products, err = repo.FetchUserTrackedProducts(ctx, userID)
+
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Equal(t, 77.27, products[0].LowestPrice)
+		assert.Equal(t, "amazon_us", products[0].LowestSource)
+		assert.Equal(t, "GBP", products[0].Currency)
+	})
+
+	t.Run("Uses the rate of the day a price was checked", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "currency@example.com")
+		productID := test.SeedProduct(t, pool, "Euro Product", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+		test.SetDisplayCurrency(t, pool, userID, "USD")
+
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+				ProductID: 			productID,
+				Platform: 			"amazon_de",
+				PlatformProductID:  "DE123",
+				URL: 				"https://amazon.de/product",
+		})
+
+		// the rate from the friday before covers a price checked on the weekend
+		friday := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+		sunday := time.Date(2024, 1, 7, 12, 0, 0, 0, time.UTC)
+		test.SeedExchangeRate(t, pool, friday.AddDate(0, 0, -7), "USD", 1.20)
+		test.SeedExchangeRate(t, pool, friday, "USD", 1.10)
+		test.SeedExchangeRate(t, pool, friday.AddDate(0, 0, 3), "USD", 1.00)
+
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{
+				ProductSourceID: 	sourceID,
+				Price: 				100,
+				Currency: 			"EUR",
+				InStock: 			true,
+				CheckedAt: 			&sunday,
+		})
+
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
+
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Equal(t, 110.0, products[0].LowestPrice)
+	})
+
+	t.Run("Prices in currencies without rates are left out", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "currency@example.com")
+		productID := test.SeedProduct(t, pool, "Yen Product", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		usSourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+				ProductID: 			productID,
+				Platform: 			"amazon_us",
+				PlatformProductID:  "US123",
+				URL: 				"https://amazon.com/product",
+		})
+		jpSourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+				ProductID: 			productID,
+				Platform: 			"amazon_jp",
+				PlatformProductID:  "JP123",
+				URL: 				"https://amazon.co.jp/product",
+		})
+
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: usSourceID, Price: 99.99, Currency: "USD", InStock: true})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: jpSourceID, Price: 9999, Currency: "JPY", InStock: true})
+
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
+
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Equal(t, 99.99, products[0].LowestPrice)
+		assert.Equal(t, "amazon_us", products[0].LowestSource)
+	})
+
+	t.Run("Compares landed cost when the user picks it", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "landed@example.com")
+		productID := test.SeedProduct(t, pool, "Heavy Product", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		amazonID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+				ProductID: 			productID,
+				Platform: 			"amazon",
+				PlatformProductID:  "AMZ123",
+				URL: 				"https://amazon.com/product",
+		})
+		ebayID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+				ProductID: 			productID,
+				Platform: 			"ebay",
+				PlatformProductID:  "EBAY123",
+				URL: 				"https://ebay.com/product",
+		})
+
+		// ebay has the lower sticker price but charges shipping
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{
+				ProductSourceID: 	amazonID,
+				Price: 				100,
+				InStock: 			true,
+				EstimatedTax: 		8,
+				Discount: 			10,
+		})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{
+				ProductSourceID: 	ebayID,
+				Price: 				95,
+				InStock: 			true,
+				ShippingCost: 		15,
+				EstimatedTax: 		7.60,
+		})
+
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
+
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Equal(t, 95.0, products[0].LowestPrice)
+		assert.Equal(t, "ebay", products[0].LowestSource)
+		assert.Equal(t, "sticker", products[0].PriceBasis)
+
+		test.SetPriceBasis(t, pool, userID, "landed")
+
+		products, err = repo.FetchUserTrackedProducts(ctx, userID)
+
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Equal(t, 98.0, products[0].LowestPrice, "100 + 8 tax - 10 coupon")
+		assert.Equal(t, "amazon", products[0].LowestSource)
+		assert.Equal(t, "landed", products[0].PriceBasis)
 	})
 
 	t.Run("Handles multiple sources with same lowest price", func(t *testing.T) {
@@ -394,7 +683,7 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 			})
 		}
 
-		products, err := repo.FetchUserTrackedProducts(userID)
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
 
 		require.NoError(t, err)
 		require.Len(t, products, 1)
@@ -405,7 +694,7 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 	t.Run("Handles non-existant user gracefully", func(t *testing.T) {
 		test.CleanupTables(t, pool)
 
-		products, err := repo.FetchUserTrackedProducts(99999)
+		products, err := repo.FetchUserTrackedProducts(ctx, 99999)
 
 		require.NoError(t, err)
 		assert.Empty(t, products)
@@ -416,6 +705,7 @@ func TestFetchUserTrackedProducts(t *testing.T) {
 func TestDeleteProductForUser(t *testing.T) {
 	pool := testDB.Pool
 	repo := db.NewRepository(pool)
+	ctx := context.Background()
 
 	t.Run("Returns nil when delete successful", func(t *testing.T) {
 		test.CleanupTables(t, pool)
@@ -424,7 +714,7 @@ func TestDeleteProductForUser(t *testing.T) {
 		productID := test.SeedProduct(t, pool, "product", "https://imgur.com/123")
 		test.AddProductToWatchlist(t, pool, userID, productID)
 
-		err := repo.DeleteProductForUser(userID, productID)
+		err := repo.DeleteProductForUser(ctx, userID, productID)
 
 		require.NoError(t, err)
 	})
@@ -432,9 +722,10 @@ func TestDeleteProductForUser(t *testing.T) {
 	t.Run("returns error when user doesnt exist to delete for", func(t *testing.T) {
 		test.CleanupTables(t, pool)
 		
-		err := repo.DeleteProductForUser(1, 2)
+		err := repo.DeleteProductForUser(ctx, 1, 2)
 
 		require.Error(t, err)
+		assert.ErrorIs(t, err, store.ErrNotFound)
 		assert.Contains(t, err.Error(), "not found in user's watchlist")
 	})
 
@@ -443,9 +734,214 @@ func TestDeleteProductForUser(t *testing.T) {
 
 		userID := test.SeedUser(t, pool, "user1", "example@example.com")
 
-		err := repo.DeleteProductForUser(userID, 2)
+		err := repo.DeleteProductForUser(ctx, userID, 2)
 
 		require.Error(t, err)
+		assert.ErrorIs(t, err, store.ErrNotFound)
 		assert.Contains(t, err.Error(), "not found in user's watchlist")
 	})
-}
\ No newline at end of file
+}
+// seed a tracked product with one source priced at price, returns the product id
+func seedPricedProduct(t *testing.T, pool *pgxpool.Pool, userID int, name, platform string, price float64, inStock bool) int {
+	t.Helper()
+
+	productID := test.SeedProduct(t, pool, name, "https://example.com/"+name+".jpg")
+	test.AddProductToWatchlist(t, pool, userID, productID)
+
+	sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+		ProductID: 			productID,
+		Platform: 			platform,
+		PlatformProductID: 	name + "_" + platform,
+		URL: 				"https://" + platform + ".com/" + name,
+	})
+	test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{
+		ProductSourceID: 	sourceID,
+		Price: 				price,
+		InStock: 			inStock,
+	})
+
+	return productID
+}
+
+func productNames(products []types.UserProduct) []string {
+	names := []string{}
+	for _, product := range products {
+		names = append(names, product.ProductName)
+	}
+	return names
+}
+
+// Integration tests for FetchUserTrackedProductsPage SQL func
+func TestFetchUserTrackedProductsPage(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	byPrice := types.ProductListQuery{Sort: types.SortByPrice, Order: types.SortAsc, Limit: 2}
+
+	t.Run("pages through products with a stable cursor", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		seedPricedProduct(t, pool, userID, "Keyboard", "amazon", 50, true)
+		seedPricedProduct(t, pool, userID, "Mouse", "newegg", 20, true)
+		seedPricedProduct(t, pool, userID, "Monitor", "amazon", 300, false)
+		// same price as Keyboard, product id breaks the tie
+		seedPricedProduct(t, pool, userID, "Webcam", "bestbuy", 50, true)
+
+		first, err := repo.FetchUserTrackedProductsPage(ctx, userID, byPrice)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Mouse", "Keyboard"}, productNames(first.Products))
+		assert.Equal(t, 4, first.TotalCount)
+		require.NotEmpty(t, first.NextCursor)
+
+		cursor, err := types.DecodeProductCursor(first.NextCursor)
+		require.NoError(t, err)
+		secondQuery := byPrice
+		secondQuery.Cursor = cursor
+
+		second, err := repo.FetchUserTrackedProductsPage(ctx, userID, secondQuery)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Webcam", "Monitor"}, productNames(second.Products))
+		assert.Equal(t, 4, second.TotalCount, "total count ignores the cursor")
+		assert.Empty(t, second.NextCursor, "last page has no next cursor")
+	})
+
+	t.Run("sorts by name descending", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		for _, name := range []string{"B", "C", "A"} {
+			seedPricedProduct(t, pool, userID, name, "amazon", 10, true)
+		}
+
+		page, err := repo.FetchUserTrackedProductsPage(ctx, userID, types.ProductListQuery{
+			Sort: types.SortByName, Order: types.SortDesc, Limit: 10,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"C", "B", "A"}, productNames(page.Products))
+	})
+
+	t.Run("applies filters to the page and the total count", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		seedPricedProduct(t, pool, userID, "RTX 4090", "amazon", 1599, true)
+		seedPricedProduct(t, pool, userID, "RTX 4080", "newegg", 999, false)
+		seedPricedProduct(t, pool, userID, "RX 7900", "amazon", 899, true)
+		seedPricedProduct(t, pool, userID, "100% Cotton", "ebay", 10, true)
+
+		inStock := true
+		minPrice, maxPrice := 500.0, 1600.0
+
+		testCases := []struct {
+			name		string
+			query		types.ProductListQuery
+			expected	[]string
+		}{
+			{"in stock", types.ProductListQuery{InStock: &inStock}, []string{"100% Cotton", "RX 7900", "RTX 4090"}},
+			{"source", types.ProductListQuery{Sources: []string{"newegg", "ebay"}}, []string{"100% Cotton", "RTX 4080"}},
+			{"price range", types.ProductListQuery{MinPrice: &minPrice, MaxPrice: &maxPrice}, []string{"RX 7900", "RTX 4080", "RTX 4090"}},
+			{"name search", types.ProductListQuery{Search: "rtx"}, []string{"RTX 4080", "RTX 4090"}},
+			{"search wildcards are literal", types.ProductListQuery{Search: "0%"}, []string{"100% Cotton"}},
+		}
+
+		for _, tc := range testCases {
+			query := tc.query
+			query.Sort, query.Order, query.Limit = types.SortByPrice, types.SortAsc, 10
+
+			page, err := repo.FetchUserTrackedProductsPage(ctx, userID, query)
+
+			require.NoError(t, err, tc.name)
+			assert.Equal(t, tc.expected, productNames(page.Products), tc.name)
+			assert.Equal(t, len(tc.expected), page.TotalCount, tc.name)
+		}
+	})
+
+	t.Run("sorts by biggest drop from the 30 day high", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		seedPricedProduct(t, pool, userID, "Steady", "amazon", 100, true)
+
+		droppedID := seedPricedProduct(t, pool, userID, "Dropped", "amazon", 80, true)
+		var sourceID int
+		err := pool.QueryRow(ctx, `SELECT id FROM product_sources WHERE product_id = $1`, droppedID).Scan(&sourceID)
+		require.NoError(t, err)
+
+		lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{
+			ProductSourceID: 	sourceID,
+			Price: 				120,
+			InStock: 			true,
+			CheckedAt: 			&lastWeek,
+		})
+
+		page, err := repo.FetchUserTrackedProductsPage(ctx, userID, types.ProductListQuery{
+			Sort: types.SortByBiggestDrop, Order: types.SortDesc, Limit: 10,
+		})
+
+		require.NoError(t, err)
+		require.Len(t, page.Products, 2)
+		assert.Equal(t, "Dropped", page.Products[0].ProductName)
+		assert.Equal(t, 40.0, page.Products[0].PriceDrop)
+		assert.Equal(t, 0.0, page.Products[1].PriceDrop)
+	})
+
+	t.Run("scores deals from price history and sorts by deal score", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		seedPricedProduct(t, pool, userID, "New", "amazon", 50, true)
+
+		seedHistory := func(productID int, prices map[int]float64) {
+			var sourceID int
+			err := pool.QueryRow(ctx, `SELECT id FROM product_sources WHERE product_id = $1`, productID).Scan(&sourceID)
+			require.NoError(t, err)
+
+			for daysAgo, price := range prices {
+				checkedAt := time.Now().Add(-time.Duration(daysAgo) * 24 * time.Hour)
+				test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{
+					ProductSourceID: 	sourceID,
+					Price: 				price,
+					InStock: 			true,
+					CheckedAt: 			&checkedAt,
+				})
+			}
+		}
+
+		// the 40 from over half a year ago is too old to count
+		dealID := seedPricedProduct(t, pool, userID, "Deal", "amazon", 80, true)
+		seedHistory(dealID, map[int]float64{200: 40, 14: 120, 10: 110, 3: 90})
+
+		priceyID := seedPricedProduct(t, pool, userID, "Pricey", "amazon", 120, true)
+		seedHistory(priceyID, map[int]float64{14: 80, 10: 90})
+
+		page, err := repo.FetchUserTrackedProductsPage(ctx, userID, types.ProductListQuery{
+			Sort: types.SortByDealScore, Order: types.SortDesc, Limit: 10,
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"Deal", "Pricey", "New"}, productNames(page.Products))
+
+		deal := page.Products[0]
+		assert.Equal(t, 100.0, deal.DealScore)
+		assert.Equal(t, 0.0, deal.PricePercentile)
+		assert.Equal(t, 0.0, deal.AboveAllTimeLow)
+		assert.Equal(t, "down", deal.PriceTrend)
+		assert.True(t, deal.GoodTimeToBuy)
+
+		pricey := page.Products[1]
+		assert.Equal(t, 16.7, pricey.DealScore)
+		assert.Equal(t, 66.7, pricey.PricePercentile)
+		assert.Equal(t, 50.0, pricey.AboveAllTimeLow)
+		assert.Equal(t, "up", pricey.PriceTrend)
+		assert.False(t, pricey.GoodTimeToBuy)
+
+		fresh := page.Products[2]
+		assert.Equal(t, 0.0, fresh.DealScore, "too little history to score")
+		assert.Equal(t, "", fresh.PriceTrend)
+		assert.False(t, fresh.GoodTimeToBuy)
+	})
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"backend/internal/ratelimit"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// how often full buckets are deleted from rate_limits, same as the memory
+// store's sweeps
+const rateLimitPruneInterval = time.Minute
+
+// Take a token from the rate limit bucket stored in postgres for key so every
+// replica shares the same buckets. The row is locked for the duration of the
+// transaction so concurrent requests for the same key are serialized
+func (r *Repository) TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	var result ratelimit.Result
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		// new keys start with a full bucket
+		insertQuery := `
+			INSERT INTO rate_limits (bucket_key, tokens, updated_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (bucket_key) DO NOTHING`
+
+		_, err := tx.Exec(ctx, insertQuery, key, float64(limit.Requests))
+		if err != nil {
+			return err
+		}
+
+		var tokens float64
+		var elapsed float64
+
+		selectQuery := `
+			SELECT tokens, EXTRACT(EPOCH FROM (NOW() - updated_at))::float8
+			FROM rate_limits
+			WHERE bucket_key = $1
+			FOR UPDATE`
+
+		err = tx.QueryRow(ctx, selectQuery, key).Scan(&tokens, &elapsed)
+		if err != nil {
+			return err
+		}
+
+		tokens, result = ratelimit.Take(tokens, time.Duration(elapsed*float64(time.Second)), limit)
+
+		updateQuery := `
+			UPDATE rate_limits
+			SET tokens = $2, updated_at = NOW(), full_at = NOW() + make_interval(secs => $3)
+			WHERE bucket_key = $1`
+
+		_, err = tx.Exec(ctx, updateQuery, key, tokens, result.Reset.Seconds())
+		return err
+	})
+
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	r.pruneRateLimitsEvery(ctx, rateLimitPruneInterval)
+
+	return result, nil
+}
+
+// Delete the buckets that have refilled completely, every client ip gets
+// its own bucket so the table would otherwise keep growing
+func (r *Repository) PruneRateLimits(ctx context.Context) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM rate_limits WHERE full_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// prune at most once per interval across the requests of this replica, a
+// failed prune only leaves the rows for the next one
+func (r *Repository) pruneRateLimitsEvery(ctx context.Context, interval time.Duration) {
+	now := time.Now().UnixNano()
+	last := r.lastRateLimitPrune.Load()
+	if now-last < int64(interval) || !r.lastRateLimitPrune.CompareAndSwap(last, now) {
+		return
+	}
+
+	if _, err := r.PruneRateLimits(ctx); err != nil {
+		logger.FromContext(ctx).Warn("failed to prune rate limit buckets", "err", err)
+	}
+}
@@ -0,0 +1,99 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/ratelimit"
+	"backend/pkg/test"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for TakeRateLimitToken SQL func
+func TestTakeRateLimitToken(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+	limit := ratelimit.Limit{Requests: 3, Period: time.Hour}
+
+	t.Run("allows a burst up to the limit then denies", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		for i := 0; i < 3; i++ {
+			result, err := repo.TakeRateLimitToken(ctx, "user.login:ip:127.0.0.1", limit)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed, "request %d should be allowed", i+1)
+		}
+
+		result, err := repo.TakeRateLimitToken(ctx, "user.login:ip:127.0.0.1", limit)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+	})
+
+	t.Run("concurrent requests share one bucket", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		allowed := 0
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result, err := repo.TakeRateLimitToken(ctx, "products.add_name:user:1", limit)
+				assert.NoError(t, err)
+
+				mu.Lock()
+				if result.Allowed {
+					allowed++
+				}
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 3, allowed, "only the bucket capacity should be allowed across replicas")
+	})
+}
+
+// Integration tests for PruneRateLimits SQL func
+func TestPruneRateLimits(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	test.CleanupTables(t, pool)
+
+	_, err := repo.TakeRateLimitToken(ctx, "user.login:ip:192.0.2.1", ratelimit.Limit{Requests: 3, Period: time.Hour})
+	require.NoError(t, err)
+	_, err = repo.TakeRateLimitToken(ctx, "user.login:ip:192.0.2.2", ratelimit.Limit{Requests: 3, Period: time.Hour})
+	require.NoError(t, err)
+
+	// the first bucket refilled a minute ago
+	_, err = pool.Exec(ctx, `UPDATE rate_limits SET full_at = NOW() - INTERVAL '1 minute' WHERE bucket_key = $1`, "user.login:ip:192.0.2.1")
+	require.NoError(t, err)
+
+	pruned, err := repo.PruneRateLimits(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+
+	var keys []string
+	rows, err := pool.Query(ctx, `SELECT bucket_key FROM rate_limits`)
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		require.NoError(t, rows.Scan(&key))
+		keys = append(keys, key)
+	}
+	assert.Equal(t, []string{"user.login:ip:192.0.2.2"}, keys)
+}
@@ -1,12 +1,40 @@
 package db
 
-import "github.com/jackc/pgx/v4/pgxpool"
+import (
+	"sync/atomic"
+	"time"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// how long a login session stays valid unless overridden with WithSessionTTL
+const defaultSessionTTL = 24 * time.Hour
 
 type Repository struct {
-	pool *pgxpool.Pool
+	pool 		*pgxpool.Pool
+	sessionTTL	time.Duration
+	// unix nanoseconds of the last prune of full rate limit buckets
+	lastRateLimitPrune	atomic.Int64
+}
+
+type RepositoryOption func(*Repository)
+
+// set how long sessions created by LoginUser stay valid
+func WithSessionTTL(ttl time.Duration) RepositoryOption {
+	return func(r *Repository) {
+		r.sessionTTL = ttl
+	}
 }
 
 // allows us to initialize the db pool in main.go
-func NewRepository(pool *pgxpool.Pool) *Repository {
-	return &Repository{pool: pool}
-}
\ No newline at end of file
+func NewRepository(pool *pgxpool.Pool, opts ...RepositoryOption) *Repository {
+	repo := &Repository{
+		pool: 		pool,
+		sessionTTL: defaultSessionTTL,
+	}
+
+	for _, opt := range opts {
+		opt(repo)
+	}
+
+	return repo
+}
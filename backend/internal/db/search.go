@@ -0,0 +1,78 @@
+package db
+
+import (
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Search the product catalog for names similar to the search text so users
+// can pick an existing product instead of creating a near duplicate. Matches
+// on trigram similarity, full text or substring and ranks by how close the
+// name is plus a small boost for products many users already watch
+func (r *Repository) SearchProducts(ctx context.Context, search string, limit int) ([]types.ProductSearchResult, error) {
+	results := []types.ProductSearchResult{}
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `
+			WITH watchers AS (
+				SELECT product_id, COUNT(*) as watcher_count
+				FROM user_watchlist
+				GROUP BY product_id
+			),
+			matches AS (
+				SELECT
+					p.id, p.product_name,
+					COALESCE(p.image_url, '') as image_url,
+					COALESCE(w.watcher_count, 0) as watcher_count,
+					similarity(p.product_name, $1) as name_similarity,
+					ts_rank(p.search_vector, plainto_tsquery('simple', $1)) as text_rank
+				FROM products p
+				LEFT JOIN watchers w ON p.id = w.product_id
+				WHERE p.product_name % $1
+				OR p.search_vector @@ plainto_tsquery('simple', $1)
+				OR p.product_name ILIKE $2
+			)
+			SELECT
+				id, product_name, image_url, watcher_count,
+				(name_similarity + 0.5 * text_rank + 0.1 * LN(1 + watcher_count))::float8 as score
+			FROM matches
+			ORDER BY score DESC, watcher_count DESC, id ASC
+			LIMIT $3`
+
+		rows, err := tx.Query(ctx, query, search, "%"+escapeLike(search)+"%", limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var result types.ProductSearchResult
+
+			err := rows.Scan(
+				&result.ProductID,
+				&result.ProductName,
+				&result.ImageUrl,
+				&result.WatcherCount,
+				&result.Score,
+			)
+			if err != nil {
+				return err
+			}
+
+			results = append(results, result)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to search products", "search", search, "err", err)
+		return []types.ProductSearchResult{}, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,83 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/pkg/test"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for SearchProducts SQL func
+func TestSearchProducts(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	t.Run("finds near duplicate names", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		test.SeedProduct(t, pool, "NVIDIA GeForce RTX 4090", "")
+		test.SeedProduct(t, pool, "Logitech MX Master 3S", "")
+
+		results, err := repo.SearchProducts(ctx, "rtx 4090 ", 10)
+
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "NVIDIA GeForce RTX 4090", results[0].ProductName)
+		assert.Positive(t, results[0].Score)
+	})
+
+	t.Run("tolerates typos with trigram similarity", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+
+		results, err := repo.SearchProducts(ctx, "mechanicl keyboard", 10)
+
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "Mechanical Keyboard", results[0].ProductName)
+	})
+
+	t.Run("ranks popular products higher for equal matches", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		quietID := test.SeedProduct(t, pool, "Monitor Arm A", "")
+		popularID := test.SeedProduct(t, pool, "Monitor Arm B", "")
+
+		for _, username := range []string{"user1", "user2", "user3"} {
+			userID := test.SeedUser(t, pool, username, username+"@example.com")
+			test.AddProductToWatchlist(t, pool, userID, popularID)
+		}
+
+		results, err := repo.SearchProducts(ctx, "monitor arm", 10)
+
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, popularID, results[0].ProductID)
+		assert.Equal(t, 3, results[0].WatcherCount)
+		assert.Equal(t, quietID, results[1].ProductID)
+		assert.Equal(t, 0, results[1].WatcherCount)
+	})
+
+	t.Run("respects the limit and returns empty list without matches", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		for _, name := range []string{"USB Cable 1m", "USB Cable 2m", "USB Cable 3m"} {
+			test.SeedProduct(t, pool, name, "")
+		}
+
+		results, err := repo.SearchProducts(ctx, "usb cable", 2)
+		require.NoError(t, err)
+		assert.Len(t, results, 2)
+
+		results, err = repo.SearchProducts(ctx, "espresso machine", 10)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+}
@@ -0,0 +1,326 @@
+package db
+
+import (
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// how far back a shared product's price history goes
+const publicHistoryDays = 365
+
+const shareLinkColumns = `id, collection_id, product_id, expires_at, created_at`
+
+func scanShareLink(row pgx.Row) (types.ShareLink, error) {
+	var link types.ShareLink
+
+	err := row.Scan(&link.ID, &link.CollectionID, &link.ProductID, &link.ExpiresAt, &link.CreatedAt)
+	if err != nil {
+		return types.ShareLink{}, err
+	}
+
+	link.Target = types.ShareTargetProduct
+	if link.CollectionID != nil {
+		link.Target = types.ShareTargetCollection
+	}
+
+	return link, nil
+}
+
+// tokens are looked up by their hash so a leaked table doesn't hand out
+// working links
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create a public link with an unguessable token, collections can only be
+// shared by their owner and products have to be on the user's watchlist. The
+// token is only returned here, the database keeps its hash
+func (r *Repository) InsertShareLink(ctx context.Context, userID int, input types.ShareLinkInput) (types.ShareLink, error) {
+	var link types.ShareLink
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		var collectionID, productID *int
+
+		if input.CollectionID > 0 {
+			_, err := requireCollectionRole(ctx, tx, userID, input.CollectionID, isCollectionOwner)
+			if err != nil {
+				return err
+			}
+			collectionID = &input.CollectionID
+		} else {
+			var watched bool
+			err := tx.QueryRow(ctx,
+				`SELECT EXISTS (SELECT 1 FROM user_watchlist WHERE user_id = $1 AND product_id = $2)`,
+				userID, input.ProductID,
+			).Scan(&watched)
+			if err != nil {
+				return err
+			}
+			if !watched {
+				return fmt.Errorf("product %d in watchlist of user %d: %w", input.ProductID, userID, store.ErrNotFound)
+			}
+			productID = &input.ProductID
+		}
+
+		tokenBytes := make([]byte, 32)
+		_, err := rand.Read(tokenBytes)
+		if err != nil {
+			return fmt.Errorf("error generating token: %w", err)
+		}
+
+		var expiresAt *time.Time
+		if input.ExpiresInHours > 0 {
+			expires := time.Now().Add(time.Duration(input.ExpiresInHours) * time.Hour)
+			expiresAt = &expires
+		}
+
+		token := hex.EncodeToString(tokenBytes)
+
+		query := `
+			INSERT INTO share_links (token_hash, user_id, collection_id, product_id, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			RETURNING ` + shareLinkColumns
+
+		link, err = scanShareLink(tx.QueryRow(ctx, query, hashShareToken(token), userID, collectionID, productID, expiresAt))
+		if err != nil {
+			return err
+		}
+
+		link.Token = token
+		return nil
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrForbidden) {
+			logger.FromContext(ctx).Error("failed to insert share link", "user_id", userID, "err", err)
+		}
+		return types.ShareLink{}, err
+	}
+
+	logger.FromContext(ctx).Info("share link created", "user_id", userID, "share_id", link.ID, "target", link.Target)
+
+	return link, nil
+}
+
+// Fetch the user's links that still work, newest first
+func (r *Repository) FetchShareLinks(ctx context.Context, userID int) ([]types.ShareLink, error) {
+	links := []types.ShareLink{}
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `
+			SELECT ` + shareLinkColumns + `
+			FROM share_links
+			WHERE user_id = $1
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+			ORDER BY created_at DESC, id DESC`
+
+		rows, err := tx.Query(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			link, err := scanShareLink(rows)
+			if err != nil {
+				return err
+			}
+
+			links = append(links, link)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to fetch share links", "user_id", userID, "err", err)
+		return []types.ShareLink{}, err
+	}
+
+	return links, nil
+}
+
+// Revoke one of the user's links, the token stops working right away
+func (r *Repository) RevokeShareLink(ctx context.Context, userID, shareID int) error {
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `
+			UPDATE share_links
+			SET revoked_at = NOW()
+			WHERE id = $1
+			AND user_id = $2
+			AND revoked_at IS NULL`
+
+		tag, err := tx.Exec(ctx, query, shareID, userID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("share link %d: %w", shareID, store.ErrNotFound)
+		}
+		return nil
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to revoke share link", "user_id", userID, "share_id", shareID, "err", err)
+		}
+		return err
+	}
+
+	logger.FromContext(ctx).Info("share link revoked", "user_id", userID, "share_id", shareID)
+
+	return nil
+}
+
+// Fetch what a share token exposes, not found when the token is unknown,
+// revoked or expired or the product left the creator's watchlist.
+// Collections are shown with their owner's prices
+func (r *Repository) FetchPublicShare(ctx context.Context, token string) (types.PublicShare, error) {
+	var share types.PublicShare
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		query := `
+			SELECT sl.collection_id, sl.product_id, sl.expires_at, c.user_id
+			FROM share_links sl
+			LEFT JOIN collections c ON sl.collection_id = c.id
+			WHERE sl.token_hash = $1
+			AND sl.revoked_at IS NULL
+			AND (sl.expires_at IS NULL OR sl.expires_at > NOW())
+			AND (
+				sl.product_id IS NULL
+				OR EXISTS (SELECT 1 FROM user_watchlist uw WHERE uw.user_id = sl.user_id AND uw.product_id = sl.product_id)
+			)`
+
+		var collectionID, productID, ownerID *int
+
+		err := tx.QueryRow(ctx, query, hashShareToken(token)).Scan(&collectionID, &productID, &share.ExpiresAt, &ownerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("share link: %w", store.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		if collectionID != nil {
+			share.Target = types.ShareTargetCollection
+			share.Collection, err = fetchPublicCollection(ctx, tx, *ownerID, *collectionID)
+			return err
+		}
+
+		share.Target = types.ShareTargetProduct
+		share.Product, err = fetchPublicProduct(ctx, tx, *productID)
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to fetch public share", "err", err)
+		}
+		return types.PublicShare{}, err
+	}
+
+	return share, nil
+}
+
+// the collection's totals and items as its owner sees them, members and
+// alerts aren't loaded
+func fetchPublicCollection(ctx context.Context, tx pgx.Tx, ownerID, collectionID int) (*types.PublicCollection, error) {
+	summary, err := fetchCollectionSummary(ctx, tx, types.CollectionAccess{CollectionID: collectionID, OwnerID: ownerID})
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &types.PublicCollection{
+		Name: 			summary.Name,
+		Description: 	summary.Description,
+		Total: 			summary.Total,
+		UnpricedCount: 	summary.UnpricedCount,
+		Currency: 		summary.Currency,
+		Items: 			[]types.PublicCollectionItem{},
+	}
+
+	query := trackedProductsCTE + `
+		SELECT t.product_id, t.product_name, t.image_url, t.lowest_price, t.lowest_source, t.in_stock, ci.quantity
+		FROM collection_items ci
+		INNER JOIN tracked t ON t.product_id = ci.product_id
+		WHERE ci.collection_id = $2
+		ORDER BY ci.added_at ASC, ci.product_id ASC`
+
+	rows, err := tx.Query(ctx, query, ownerID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item types.PublicCollectionItem
+
+		err := rows.Scan(&item.ProductID, &item.ProductName, &item.ImageUrl, &item.LowestPrice, &item.LowestSource, &item.InStock, &item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+
+		collection.Items = append(collection.Items, item)
+	}
+
+	return collection, rows.Err()
+}
+
+// every snapshot of the product's sources over the last year, oldest first
+func fetchPublicProduct(ctx context.Context, tx pgx.Tx, productID int) (*types.PublicProduct, error) {
+	product := &types.PublicProduct{History: []types.PublicPricePoint{}}
+
+	err := tx.QueryRow(ctx,
+		`SELECT id, product_name, COALESCE(image_url, '') FROM products WHERE id = $1`,
+		productID,
+	).Scan(&product.ProductID, &product.Name, &product.ImageUrl)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("product %d: %w", productID, store.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT
+			ps.platform, psnap.price::float8, COALESCE(psnap.currency, 'USD'),
+			COALESCE(psnap.in_stock, false), psnap.checked_at
+		FROM price_snapshots psnap
+		INNER JOIN product_sources ps ON psnap.product_source_id = ps.id
+		WHERE ps.product_id = $1
+		AND psnap.price IS NOT NULL
+		AND psnap.checked_at > $2
+		ORDER BY psnap.checked_at ASC, psnap.id ASC`
+
+	rows, err := tx.Query(ctx, query, productID, time.Now().AddDate(0, 0, -publicHistoryDays))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var point types.PublicPricePoint
+
+		err := rows.Scan(&point.Platform, &point.Price, &point.Currency, &point.InStock, &point.CheckedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		product.History = append(product.History, point)
+	}
+
+	return product, rows.Err()
+}
@@ -0,0 +1,157 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for the share link SQL funcs
+func TestShareLinks(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	t.Run("creates, lists and revokes a product link", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		old := time.Now().AddDate(-2, 0, 0)
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 400, InStock: true, CheckedAt: &old})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 299.99, InStock: true})
+
+		link, err := repo.InsertShareLink(ctx, userID, types.ShareLinkInput{ProductID: productID})
+		require.NoError(t, err)
+		assert.Len(t, link.Token, 64)
+		assert.Equal(t, types.ShareTargetProduct, link.Target)
+		assert.Nil(t, link.ExpiresAt)
+
+		links, err := repo.FetchShareLinks(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		assert.Equal(t, link.ID, links[0].ID)
+		assert.Empty(t, links[0].Token, "the token is only shown once")
+
+		var tokenHash string
+		require.NoError(t, pool.QueryRow(ctx, `SELECT token_hash FROM share_links WHERE id = $1`, link.ID).Scan(&tokenHash))
+		sum := sha256.Sum256([]byte(link.Token))
+		assert.Equal(t, hex.EncodeToString(sum[:]), tokenHash, "only the hash is stored")
+
+		_, err = repo.FetchPublicShare(ctx, tokenHash)
+		assert.ErrorIs(t, err, store.ErrNotFound, "the hash isn't a token")
+
+		share, err := repo.FetchPublicShare(ctx, link.Token)
+		require.NoError(t, err)
+		require.NotNil(t, share.Product)
+		assert.Equal(t, "Ryzen 7 7700", share.Product.Name)
+		require.Len(t, share.Product.History, 1, "only the last year of history")
+		assert.Equal(t, 299.99, share.Product.History[0].Price)
+
+		otherID := test.SeedUser(t, pool, "user2", "user2@example.com")
+		assert.ErrorIs(t, repo.RevokeShareLink(ctx, otherID, link.ID), store.ErrNotFound, "only the creator can revoke")
+
+		require.NoError(t, repo.RevokeShareLink(ctx, userID, link.ID))
+		_, err = repo.FetchPublicShare(ctx, link.Token)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		links, err = repo.FetchShareLinks(ctx, userID)
+		require.NoError(t, err)
+		assert.Empty(t, links)
+	})
+
+	t.Run("expired links stop working", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		link, err := repo.InsertShareLink(ctx, userID, types.ShareLinkInput{ProductID: productID, ExpiresInHours: 1})
+		require.NoError(t, err)
+		require.NotNil(t, link.ExpiresAt)
+
+		_, err = repo.FetchPublicShare(ctx, link.Token)
+		require.NoError(t, err)
+
+		_, err = pool.Exec(ctx, `UPDATE share_links SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, link.ID)
+		require.NoError(t, err)
+
+		_, err = repo.FetchPublicShare(ctx, link.Token)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("only the owner shares a collection", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		ownerID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		editorID := test.SeedUser(t, pool, "user2", "user2@example.com")
+		productID := test.SeedProduct(t, pool, "DDR5 16GB", "")
+		test.AddProductToWatchlist(t, pool, ownerID, productID)
+
+		collection, err := repo.InsertCollection(ctx, ownerID, types.CollectionInput{Name: "Homelab build"})
+		require.NoError(t, err)
+		_, err = repo.SetCollectionItem(ctx, ownerID, collection.ID, productID, 2)
+		require.NoError(t, err)
+		_, err = repo.AddCollectionMember(ctx, ownerID, collection.ID, types.CollectionInvite{Username: "user2", Role: types.CollectionRoleEditor})
+		require.NoError(t, err)
+
+		_, err = repo.InsertShareLink(ctx, editorID, types.ShareLinkInput{CollectionID: collection.ID})
+		assert.ErrorIs(t, err, store.ErrForbidden)
+
+		link, err := repo.InsertShareLink(ctx, ownerID, types.ShareLinkInput{CollectionID: collection.ID})
+		require.NoError(t, err)
+
+		share, err := repo.FetchPublicShare(ctx, link.Token)
+		require.NoError(t, err)
+		require.NotNil(t, share.Collection)
+		assert.Equal(t, "Homelab build", share.Collection.Name)
+		require.Len(t, share.Collection.Items, 1)
+		assert.Equal(t, 2, share.Collection.Items[0].Quantity)
+
+		require.NoError(t, repo.DeleteCollection(ctx, ownerID, collection.ID))
+		_, err = repo.FetchPublicShare(ctx, link.Token)
+		assert.ErrorIs(t, err, store.ErrNotFound, "links go away with the collection")
+	})
+
+	t.Run("product links stop working when the product leaves the watchlist", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		link, err := repo.InsertShareLink(ctx, userID, types.ShareLinkInput{ProductID: productID})
+		require.NoError(t, err)
+
+		_, err = pool.Exec(ctx, `DELETE FROM user_watchlist WHERE user_id = $1 AND product_id = $2`, userID, productID)
+		require.NoError(t, err)
+
+		_, err = repo.FetchPublicShare(ctx, link.Token)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("products off the watchlist can't be shared", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Not Watched", "")
+
+		_, err := repo.InsertShareLink(ctx, userID, types.ShareLinkInput{ProductID: productID})
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// cached stats older than this are rebuilt by the stats job even without new
+// snapshots so the 7, 30 and 90 day windows keep moving
+const priceStatsMaxAge = time.Hour
+
+// Fetch the price statistics of a product and each of its sources on the
+// user's price basis from the price_stats summary table, as the stats job
+// last built them. Amounts are converted to the user's display currency with
+// today's rates, rows in a currency without rates keep their own
+func (r *Repository) FetchProductStats(ctx context.Context, userID, productID int) (types.ProductStats, error) {
+	stats := types.ProductStats{ProductID: productID, Sources: []types.PriceStats{}}
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		productQuery := `
+			SELECT
+				COALESCE((SELECT display_currency FROM users WHERE id = $2), $3),
+				COALESCE((SELECT price_basis FROM users WHERE id = $2), $4)
+			FROM products
+			WHERE id = $1`
+
+		var currency string
+		err := tx.QueryRow(ctx, productQuery, productID, userID, types.DefaultDisplayCurrency, types.PriceBasisSticker).Scan(
+			&currency,
+			&stats.PriceBasis,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("product %d: %w", productID, store.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+
+		query := `
+			SELECT
+				pst.product_source_id, COALESCE(ps.platform, ''), cur.currency, pst.snapshot_count,
+				convert_price(pst.current_price, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				convert_price(pst.all_time_low, pst.currency, cur.currency, LOCALTIMESTAMP)::float8, pst.all_time_low_at,
+				convert_price(pst.all_time_high, pst.currency, cur.currency, LOCALTIMESTAMP)::float8, pst.all_time_high_at,
+				convert_price(pst.avg_7d, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				convert_price(pst.median_7d, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				convert_price(pst.stddev_7d, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				convert_price(pst.avg_30d, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				convert_price(pst.median_30d, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				convert_price(pst.stddev_30d, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				convert_price(pst.avg_90d, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				convert_price(pst.median_90d, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				convert_price(pst.stddev_90d, pst.currency, cur.currency, LOCALTIMESTAMP)::float8,
+				pst.current_vs_avg_30d::float8, pst.refreshed_at
+			FROM price_stats pst
+			LEFT JOIN product_sources ps ON pst.product_source_id = ps.id
+			CROSS JOIN LATERAL (
+				SELECT CASE
+					WHEN convert_price(1, pst.currency, $3, LOCALTIMESTAMP) IS NULL THEN pst.currency
+					ELSE $3
+				END as currency
+			) cur
+			WHERE pst.product_id = $1
+			AND pst.price_basis = $2
+			ORDER BY pst.product_source_id ASC NULLS FIRST`
+
+		rows, err := tx.Query(ctx, query, productID, stats.PriceBasis, currency)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row types.PriceStats
+
+			err := rows.Scan(
+				&row.SourceID, &row.Platform, &row.Currency, &row.SnapshotCount,
+				&row.CurrentPrice, &row.AllTimeLow, &row.AllTimeLowAt,
+				&row.AllTimeHigh, &row.AllTimeHighAt,
+				&row.Avg7d, &row.Median7d, &row.StdDev7d,
+				&row.Avg30d, &row.Median30d, &row.StdDev30d,
+				&row.Avg90d, &row.Median90d, &row.StdDev90d,
+				&row.CurrentVsAvg30d, &row.RefreshedAt,
+			)
+			if err != nil {
+				return err
+			}
+
+			if row.SourceID == nil {
+				stats.Overall = &row
+				continue
+			}
+			stats.Sources = append(stats.Sources, row)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logger.FromContext(ctx).Error("failed to fetch product stats", "user_id", userID, "product_id", productID, "err", err)
+		}
+		return types.ProductStats{}, err
+	}
+
+	return stats, nil
+}
+
+// Rebuild the stats of every product whose rows are stale or older than
+// priceStatsMaxAge, or that has snapshots but no stats yet. Each product is
+// rebuilt in its own transaction so one failure doesn't hold back the rest,
+// returns how many products were rebuilt
+func (r *Repository) RefreshPriceStats(ctx context.Context) (int, error) {
+	query := `
+		SELECT product_id
+		FROM price_stats
+		GROUP BY product_id
+		HAVING BOOL_OR(stale OR refreshed_at < $1)
+		UNION
+		SELECT DISTINCT ps.product_id
+		FROM product_sources ps
+		INNER JOIN price_snapshots psnap ON psnap.product_source_id = ps.id
+		WHERE NOT EXISTS (SELECT 1 FROM price_stats pst WHERE pst.product_id = ps.product_id)
+		ORDER BY product_id ASC`
+
+	rows, err := r.pool.Query(ctx, query, time.Now().Add(-priceStatsMaxAge))
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to find stale price stats", "err", err)
+		return 0, err
+	}
+
+	var productIDs []int
+	for rows.Next() {
+		var productID int
+		if err := rows.Scan(&productID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("failed to find stale price stats", "err", err)
+		return 0, err
+	}
+
+	refreshed := 0
+	var errs []error
+	for _, productID := range productIDs {
+		err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, `SELECT refresh_price_stats($1)`, productID)
+			return err
+		})
+		if err != nil {
+			logger.FromContext(ctx).Error("failed to refresh price stats", "product_id", productID, "err", err)
+			errs = append(errs, fmt.Errorf("product %d: %w", productID, err))
+			continue
+		}
+		refreshed++
+	}
+
+	return refreshed, errors.Join(errs...)
+}
@@ -0,0 +1,186 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for the price stats SQL funcs
+func TestFetchProductStats(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	daysAgo := func(days int) *time.Time {
+		at := time.Now().AddDate(0, 0, -days)
+		return &at
+	}
+
+	// run the stats job
+	refresh := func(t *testing.T) int {
+		refreshed, err := repo.RefreshPriceStats(ctx)
+		require.NoError(t, err)
+		return refreshed
+	}
+
+	t.Run("computes lows, highs, averages and medians per source and product", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		amazonID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		neweggID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "newegg", URL: "https://newegg.com/ryzen"})
+
+		// amazon: 400 two hundred days ago, then 300, 320 and 310 this month
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 400, InStock: true, CheckedAt: daysAgo(200)})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 300, InStock: true, CheckedAt: daysAgo(20)})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 320, InStock: true, CheckedAt: daysAgo(10)})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 310, InStock: true, CheckedAt: daysAgo(1)})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: neweggID, Price: 290, InStock: true, CheckedAt: daysAgo(2)})
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		assert.Equal(t, 1, refresh(t))
+
+		stats, err := repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.Equal(t, types.PriceBasisSticker, stats.PriceBasis)
+		require.Len(t, stats.Sources, 2)
+
+		amazon := stats.Sources[0]
+		assert.Equal(t, "amazon", amazon.Platform)
+		assert.Equal(t, "USD", amazon.Currency)
+		assert.Equal(t, 4, amazon.SnapshotCount)
+		assert.Equal(t, 310.0, *amazon.CurrentPrice)
+		assert.Equal(t, 300.0, *amazon.AllTimeLow)
+		assert.Equal(t, 400.0, *amazon.AllTimeHigh)
+		assert.WithinDuration(t, *daysAgo(200), *amazon.AllTimeHighAt, time.Minute)
+		assert.Equal(t, 310.0, *amazon.Avg7d)
+		assert.Equal(t, 310.0, *amazon.Avg30d)
+		assert.Equal(t, 310.0, *amazon.Median30d)
+		assert.InDelta(t, 8.16, *amazon.StdDev30d, 0.01)
+		assert.Equal(t, 0.0, *amazon.CurrentVsAvg30d)
+
+		require.NotNil(t, stats.Overall)
+		assert.Equal(t, 5, stats.Overall.SnapshotCount)
+		assert.Equal(t, 290.0, *stats.Overall.CurrentPrice, "lowest latest price across sources")
+		assert.Equal(t, 290.0, *stats.Overall.AllTimeLow)
+		assert.Equal(t, 305.0, *stats.Overall.Avg30d)
+		assert.Equal(t, 305.0, *stats.Overall.Median30d)
+		assert.InDelta(t, -4.92, *stats.Overall.CurrentVsAvg30d, 0.01)
+	})
+
+	t.Run("windows without snapshots are nil", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 300, InStock: true, CheckedAt: daysAgo(60)})
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		refresh(t)
+
+		stats, err := repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		require.NotNil(t, stats.Overall)
+		assert.Nil(t, stats.Overall.Avg7d)
+		assert.Nil(t, stats.Overall.Avg30d)
+		assert.Nil(t, stats.Overall.CurrentVsAvg30d)
+		assert.Equal(t, 300.0, *stats.Overall.Avg90d)
+	})
+
+	t.Run("new snapshots mark the cached stats stale for the next job run", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 300, InStock: true})
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+
+		stats, err := repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.Nil(t, stats.Overall, "reads never build stats")
+
+		assert.Equal(t, 1, refresh(t))
+		assert.Equal(t, 0, refresh(t), "fresh stats are left alone")
+
+		stats, err = repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.Equal(t, 300.0, *stats.Overall.AllTimeLow)
+
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 250, InStock: true})
+
+		var stale bool
+		require.NoError(t, pool.QueryRow(ctx, `SELECT BOOL_AND(stale) FROM price_stats WHERE product_id = $1`, productID).Scan(&stale))
+		assert.True(t, stale)
+
+		stats, err = repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.Equal(t, 300.0, *stats.Overall.AllTimeLow, "stale stats are served until the job runs")
+
+		assert.Equal(t, 1, refresh(t))
+
+		stats, err = repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.Equal(t, 250.0, *stats.Overall.AllTimeLow)
+		assert.Equal(t, 2, stats.Overall.SnapshotCount)
+	})
+
+	t.Run("reads are on the user's price basis in their display currency", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/ryzen"})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 100, ShippingCost: 25, InStock: true})
+		test.SeedExchangeRate(t, pool, time.Now(), "USD", 1.25)
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		refresh(t)
+
+		stats, err := repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.Equal(t, "USD", stats.Overall.Currency)
+		assert.Equal(t, 100.0, *stats.Overall.AllTimeLow)
+
+		test.SetPriceBasis(t, pool, userID, types.PriceBasisLanded)
+		test.SetDisplayCurrency(t, pool, userID, "EUR")
+
+		stats, err = repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.Equal(t, types.PriceBasisLanded, stats.PriceBasis)
+		assert.Equal(t, "EUR", stats.Overall.Currency)
+		assert.Equal(t, 100.0, *stats.Overall.AllTimeLow, "125 USD landed is 100 EUR")
+		require.Len(t, stats.Sources, 1)
+		assert.Equal(t, "EUR", stats.Sources[0].Currency)
+		assert.Equal(t, 100.0, *stats.Sources[0].CurrentPrice)
+
+		test.SetDisplayCurrency(t, pool, userID, "JPY")
+
+		stats, err = repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.Equal(t, "USD", stats.Overall.Currency, "currencies without rates keep the stored one")
+		assert.Equal(t, 125.0, *stats.Overall.AllTimeLow)
+	})
+
+	t.Run("products without prices have no overall stats", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		productID := test.SeedProduct(t, pool, "Ryzen 7 7700", "")
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		assert.Equal(t, 0, refresh(t))
+
+		stats, err := repo.FetchProductStats(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.Nil(t, stats.Overall)
+		assert.Empty(t, stats.Sources)
+
+		_, err = repo.FetchProductStats(ctx, userID, productID+100)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
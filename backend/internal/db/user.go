@@ -2,21 +2,21 @@ package db
 
 import (
 	"backend/pkg/db"
+	"backend/pkg/logger"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
-	"time"
 	"github.com/jackc/pgx/v4"
 	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 // Create a new user account in the database with the provided
 // username and password strings
-func (r *Repository) InsertNewUser(username, email, password string) error {
-	ctx := context.Background()
+func (r *Repository) InsertNewUser(ctx context.Context, username, email, password string) error {
 	createdAt := time.Now()
-	
+
 	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
 		err := ValidateExistsUserTable(ctx, tx, "username", username)
 		if err != nil {
@@ -46,6 +46,7 @@ func (r *Repository) InsertNewUser(username, email, password string) error {
 		return nil
 	})
 	if err != nil {
+		logger.FromContext(ctx).Warn("failed to create new user", "username", username, "err", err)
 		return err
 	}
 
@@ -54,8 +55,7 @@ func (r *Repository) InsertNewUser(username, email, password string) error {
 
 // attempt to login to an user account using the username and password provided
 // return the session token if successful
-func (r *Repository) LoginUser(username, password string) (string, error) {
-	ctx := context.Background()
+func (r *Repository) LoginUser(ctx context.Context, username, password string) (string, error) {
 	var sessionToken string
 
 	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
@@ -86,7 +86,7 @@ func (r *Repository) LoginUser(username, password string) (string, error) {
 			INSERT INTO sessions (username, token, expires_at)
 			VALUES ($1, $2, $3)
 		`
-		expiresAt := time.Now().Add(time.Hour)
+		expiresAt := time.Now().Add(r.sessionTTL)
 		_, err = tx.Exec(ctx, insertSessionToken, username, sessionToken, expiresAt)
 		if err != nil {
 			return fmt.Errorf("error creating session: %w", err)
@@ -96,6 +96,7 @@ func (r *Repository) LoginUser(username, password string) (string, error) {
 	})
 
 	if err != nil {
+		logger.FromContext(ctx).Warn("failed to login user", "username", username, "err", err)
 		return "", err
 	}
 
@@ -15,12 +15,13 @@ import (
 func TestInsertNewUser(t *testing.T) {
 	pool := testDB.Pool
 	repo := db.NewRepository(pool)
+	ctx := context.Background()
 
 	t.Run("Trying to insert a new username that already exists", func(t *testing.T) {
 		test.CleanupTables(t, pool)
 	
 		test.SeedUser(t, pool, "username1", "idk@gmail.com")
-		err := repo.InsertNewUser("username1", "123@gmail.com", "password")
+		err := repo.InsertNewUser(ctx, "username1", "123@gmail.com", "password")
 
 		assert.Equal(t, "username already exists", err.Error(), "should return error")
 	})
@@ -29,7 +30,7 @@ func TestInsertNewUser(t *testing.T) {
 		test.CleanupTables(t, pool)
 
 		test.SeedUser(t, pool, "username1", "idk@gmail.com")
-		err := repo.InsertNewUser("username2", "idk@gmail.com", "password")
+		err := repo.InsertNewUser(ctx, "username2", "idk@gmail.com", "password")
 
 		assert.Equal(t, "email already exists", err.Error(), "should return error")
 	})
@@ -38,7 +39,7 @@ func TestInsertNewUser(t *testing.T) {
 		test.CleanupTables(t, pool)
 
 		test.SeedUser(t, pool, "username2", "idk@gmail.com")
-		err := repo.InsertNewUser("username1", "123@gmail.com", "password")
+		err := repo.InsertNewUser(ctx, "username1", "123@gmail.com", "password")
 
 		assert.Equal(t, nil, err, "should return nil or no error")
 	})
@@ -47,7 +48,7 @@ func TestInsertNewUser(t *testing.T) {
 		test.CleanupTables(t, pool)
 		var exists bool
 
-		err := repo.InsertNewUser("username1", "123@gmail.com", "password")
+		err := repo.InsertNewUser(ctx, "username1", "123@gmail.com", "password")
 
 		assert.Equal(t, nil, err, "should return nil or no error")
 
@@ -62,7 +63,7 @@ func TestInsertNewUser(t *testing.T) {
 		test.CleanupTables(t, pool)
 		var password string
 
-		err := repo.InsertNewUser("username1", "123@gmail.com", "password")
+		err := repo.InsertNewUser(ctx, "username1", "123@gmail.com", "password")
 
 		assert.Equal(t, nil, err, "should return nil or no error")
 
@@ -78,12 +79,13 @@ func TestInsertNewUser(t *testing.T) {
 func TestLoginUser(t *testing.T) {
 	pool := testDB.Pool
 	repo := db.NewRepository(pool)
+	ctx := context.Background()
 
 	t.Run("Invalid password should raise an error", func(t *testing.T) {
 		test.CleanupTables(t, pool)
-		repo.InsertNewUser("user1", "123@gmail.com", "password123")
+		repo.InsertNewUser(ctx, "user1", "123@gmail.com", "password123")
 
-		sessionToken, err := repo.LoginUser("user1", "invalidpass")
+		sessionToken, err := repo.LoginUser(ctx, "user1", "invalidpass")
 
 		assert.NotEqual(t, nil, err, "should return an error")
 		assert.Equal(t, "", sessionToken, "session token should be empty")
@@ -91,9 +93,9 @@ func TestLoginUser(t *testing.T) {
 
 	t.Run("Invalid username should raise an error", func(t *testing.T) {
 		test.CleanupTables(t, pool)
-		repo.InsertNewUser("user1", "123@gmail.com", "password123")
+		repo.InsertNewUser(ctx, "user1", "123@gmail.com", "password123")
 
-		sessionToken, err := repo.LoginUser("user123", "password123")
+		sessionToken, err := repo.LoginUser(ctx, "user123", "password123")
 
 		assert.NotEqual(t, nil, err, "should return an error")
 		assert.Equal(t, "", sessionToken, "session token should be empty")
@@ -102,9 +104,9 @@ func TestLoginUser(t *testing.T) {
 	t.Run("Correct username and password should create a new session in database and return it", func(t *testing.T) {
 		test.CleanupTables(t, pool)
 		var sessionExists bool
-		repo.InsertNewUser("user1", "123@gmail.com", "password123")
+		repo.InsertNewUser(ctx, "user1", "123@gmail.com", "password123")
 
-		sessionToken, err := repo.LoginUser("user1", "password123")
+		sessionToken, err := repo.LoginUser(ctx, "user1", "password123")
 
 		assert.Equal(t, nil, err, "should not return an error")
 
@@ -2,6 +2,7 @@ package db
 
 import (
 	"backend/pkg/db"
+	"backend/pkg/logger"
 	"context"
 	"fmt"
 	"github.com/jackc/pgx/v4"
@@ -20,21 +21,24 @@ func ValidateExistsUserTable(ctx context.Context, tx pgx.Tx, column, val string)
 
 	if valueExists {
 		return fmt.Errorf("%s already exists", column)
-	}	
+	}
 
 	return nil
 }
 
-
 // Use the provided sessionToken from frontend request and attempt to fetch the userId
 // and username if the sessionToken is valid
-func (r *Repository) ValidateSession(sessionToken string) (int, string, error) {
-	ctx := context.Background()
+func (r *Repository) ValidateSession(ctx context.Context, sessionToken string) (int, string, error) {
 	var userId int
 	var username string
 
 	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
-		query := `SELECT id, username FROM sessions WHERE token = $1`
+		query := `
+			SELECT u.id, u.username
+			FROM sessions s
+			INNER JOIN users u ON s.username = u.username
+			WHERE s.token = $1
+			AND s.expires_at > NOW()`
 
 		err := tx.QueryRow(ctx, query, sessionToken).Scan(&userId, &username)
 		if err != nil {
@@ -45,8 +49,30 @@ func (r *Repository) ValidateSession(sessionToken string) (int, string, error) {
 	})
 
 	if err != nil {
+		logger.FromContext(ctx).Debug("session validation failed", "err", err)
 		return 0, "", err
 	}
 
 	return userId, username, nil
-}
\ No newline at end of file
+}
+
+// Check if the user has the admin flag set, used to guard admin only routes
+func (r *Repository) IsAdminUser(ctx context.Context, userID int) (bool, error) {
+	var isAdmin bool
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `SELECT is_admin FROM users WHERE id = $1`, userID).Scan(&isAdmin)
+		if err != nil {
+			return fmt.Errorf("error fetching admin flag for user: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.FromContext(ctx).Debug("admin check failed", "user_id", userID, "err", err)
+		return false, err
+	}
+
+	return isAdmin, nil
+}
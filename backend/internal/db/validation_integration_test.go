@@ -11,6 +11,7 @@ import (
 
 	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 
@@ -51,16 +52,17 @@ func TestValidateExistsUserTable(t *testing.T) {
 func TestValidateSession(t *testing.T) {
 	pool := testDB.Pool
 	repo := db.NewRepository(pool)
+	ctx := context.Background()
 
 	t.Run("Should return an error if nonexistant session token is provided", func(t *testing.T) {
 		test.CleanupTables(t, pool)
 
-		repo.InsertNewUser("user1", "idk@gmail.com", "pass123")
-		_, err := repo.LoginUser("user1", "pass123")
+		repo.InsertNewUser(ctx, "user1", "idk@gmail.com", "pass123")
+		_, err := repo.LoginUser(ctx, "user1", "pass123")
 
 		assert.Equal(t, nil, err)
 
-		userId, username, err := repo.ValidateSession("23udweu")
+		userId, username, err := repo.ValidateSession(ctx, "23udweu")
 
 		assert.NotEqual(t, nil, err, "Should return a non nil error")
 		assert.Equal(t, 0, userId, "validate session returns 0 userId on error")
@@ -70,16 +72,63 @@ func TestValidateSession(t *testing.T) {
 	t.Run("Should return correct userId and username if correct session token is provided", func(t *testing.T) {
 		test.CleanupTables(t, pool)
 
-		repo.InsertNewUser("user1", "idk@gmail.com", "pass123")
-		sessionToken, err := repo.LoginUser("user1", "pass123")
+		repo.InsertNewUser(ctx, "user1", "idk@gmail.com", "pass123")
+		sessionToken, err := repo.LoginUser(ctx, "user1", "pass123")
 
 		assert.Equal(t, nil, err)
 
-		userId, username, err := repo.ValidateSession(sessionToken)
+		userId, username, err := repo.ValidateSession(ctx, sessionToken)
 
 		assert.Equal(t, nil, err, "Should return a nil error")
 		assert.Equal(t, 1, userId)
 		assert.Equal(t, "user1", username)
 	})
+
+	t.Run("Should return an error if the session has expired", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		repo.InsertNewUser(ctx, "user1", "idk@gmail.com", "pass123")
+		sessionToken, err := repo.LoginUser(ctx, "user1", "pass123")
+		assert.Equal(t, nil, err)
+
+		_, err = pool.Exec(ctx, `UPDATE sessions SET expires_at = NOW() - INTERVAL '1 minute' WHERE token = $1`, sessionToken)
+		assert.Equal(t, nil, err)
+
+		userId, _, err := repo.ValidateSession(ctx, sessionToken)
+
+		assert.NotEqual(t, nil, err, "Expired sessions should not validate")
+		assert.Equal(t, 0, userId)
+	})
 }
 
+// Integration tests for IsAdminUser SQL func
+func TestIsAdminUser(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	t.Run("returns the admin flag of the user", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		adminID := test.SeedUser(t, pool, "admin", "admin@example.com")
+
+		_, err := pool.Exec(ctx, `UPDATE users SET is_admin = TRUE WHERE id = $1`, adminID)
+		require.NoError(t, err)
+
+		isAdmin, err := repo.IsAdminUser(ctx, userID)
+		require.NoError(t, err)
+		assert.False(t, isAdmin)
+
+		isAdmin, err = repo.IsAdminUser(ctx, adminID)
+		require.NoError(t, err)
+		assert.True(t, isAdmin)
+	})
+
+	t.Run("returns an error for unknown users", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		_, err := repo.IsAdminUser(ctx, 99999)
+		assert.Error(t, err)
+	})
+}
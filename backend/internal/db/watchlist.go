@@ -0,0 +1,148 @@
+package db
+
+import (
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const watchlistEntryColumns = `
+	product_id, added_at,
+	COALESCE(acceptable_conditions, '{}'),
+	COALESCE(min_seller_rating, 0)::float8,
+	allow_unrated_sellers,
+	COALESCE(target_price, 0)::float8,
+	COALESCE(notes, ''),
+	COALESCE(preferred_platforms, '{}'),
+	COALESCE(excluded_platforms, '{}'),
+	paused,
+	COALESCE(tags, '{}')`
+
+// Change the settings of a product in the user's watchlist, only the fields
+// set on the update are written. Returns the entry with every setting
+func (r *Repository) UpdateWatchlistEntry(ctx context.Context, userID, productID int, update types.WatchlistUpdate) (types.WatchlistEntry, error) {
+	var entry types.WatchlistEntry
+
+	err := db.WithTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		entry, err = updateWatchlistEntry(ctx, tx, userID, productID, update)
+		return err
+	})
+
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrConflict) {
+			logger.FromContext(ctx).Error("failed to update watchlist entry", "user_id", userID, "product_id", productID, "err", err)
+		}
+		return types.WatchlistEntry{}, err
+	}
+
+	logger.FromContext(ctx).Debug("updated watchlist entry", "user_id", userID, "product_id", productID)
+
+	return entry, nil
+}
+
+func updateWatchlistEntry(ctx context.Context, tx pgx.Tx, userID, productID int, update types.WatchlistUpdate) (types.WatchlistEntry, error) {
+	var entry types.WatchlistEntry
+
+	args := []interface{}{userID, productID}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	sets := []string{}
+	// empty lists and 0 numbers turn a setting off, stored as NULL
+	if update.AcceptableConditions != nil {
+		sets = append(sets, "acceptable_conditions = "+addArg(nullIfEmpty(*update.AcceptableConditions))+"::varchar[]")
+	}
+	if update.MinSellerRating != nil {
+		sets = append(sets, "min_seller_rating = "+addArg(nullIfZero(*update.MinSellerRating))+"::numeric")
+	}
+	if update.AllowUnratedSellers != nil {
+		sets = append(sets, "allow_unrated_sellers = "+addArg(*update.AllowUnratedSellers))
+	}
+	if update.TargetPrice != nil {
+		sets = append(sets, "target_price = "+addArg(nullIfZero(*update.TargetPrice))+"::numeric")
+	}
+	if update.Notes != nil {
+		sets = append(sets, "notes = NULLIF("+addArg(*update.Notes)+", '')")
+	}
+	if update.PreferredPlatforms != nil {
+		sets = append(sets, "preferred_platforms = "+addArg(nullIfEmpty(*update.PreferredPlatforms))+"::varchar[]")
+	}
+	if update.ExcludedPlatforms != nil {
+		sets = append(sets, "excluded_platforms = "+addArg(nullIfEmpty(*update.ExcludedPlatforms))+"::varchar[]")
+	}
+	if update.Paused != nil {
+		sets = append(sets, "paused = "+addArg(*update.Paused))
+	}
+	if update.Tags != nil {
+		sets = append(sets, "tags = "+addArg(nullIfEmpty(*update.Tags))+"::varchar[]")
+	}
+
+	query := `
+		SELECT ` + watchlistEntryColumns + `
+		FROM user_watchlist
+		WHERE user_id = $1 AND product_id = $2`
+	if len(sets) > 0 {
+		query = `
+			UPDATE user_watchlist
+			SET ` + strings.Join(sets, ", ") + `
+			WHERE user_id = $1 AND product_id = $2
+			RETURNING ` + watchlistEntryColumns
+	}
+
+	err := tx.QueryRow(ctx, query, args...).Scan(
+		&entry.ProductID,
+		&entry.AddedAt,
+		&entry.AcceptableConditions,
+		&entry.MinSellerRating,
+		&entry.AllowUnratedSellers,
+		&entry.TargetPrice,
+		&entry.Notes,
+		&entry.PreferredPlatforms,
+		&entry.ExcludedPlatforms,
+		&entry.Paused,
+		&entry.Tags,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return types.WatchlistEntry{}, fmt.Errorf("product %d in watchlist of user %d: %w", productID, userID, store.ErrNotFound)
+	}
+	if err != nil {
+		return types.WatchlistEntry{}, err
+	}
+
+	// the update merged with the stored settings, the caller's transaction
+	// rolls it back on a conflict
+	for _, platform := range entry.PreferredPlatforms {
+		if slices.Contains(entry.ExcludedPlatforms, platform) {
+			return types.WatchlistEntry{}, fmt.Errorf("platform %s of product %d both preferred and excluded: %w", platform, productID, store.ErrConflict)
+		}
+	}
+
+	return entry, nil
+}
+
+// nil for an empty list so it is written as NULL
+func nullIfEmpty(values []string) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// nil for zero so it is written as NULL
+func nullIfZero(value float64) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,404 @@
+//go:build integration
+
+package db_test
+
+import (
+	"backend/internal/db"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/test"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for UpdateWatchlistEntry SQL func
+func TestUpdateWatchlistEntry(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	list := func(values ...string) *[]string { return &values }
+	number := func(value float64) *float64 { return &value }
+
+	t.Run("stores the settings and leaves unset fields alone", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		entry, err := repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{})
+		require.NoError(t, err)
+		assert.Equal(t, productID, entry.ProductID)
+		assert.Empty(t, entry.AcceptableConditions)
+		assert.Zero(t, entry.MinSellerRating)
+
+		entry, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{
+			AcceptableConditions: 	list(types.ConditionNew, types.ConditionRefurbished),
+			MinSellerRating: 		number(97.5),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"new", "refurbished"}, entry.AcceptableConditions)
+		assert.Equal(t, 97.5, entry.MinSellerRating)
+
+		entry, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{
+			MinSellerRating: number(0),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"new", "refurbished"}, entry.AcceptableConditions)
+		assert.Zero(t, entry.MinSellerRating)
+
+		entry, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{
+			AcceptableConditions: list(),
+		})
+		require.NoError(t, err)
+		assert.Empty(t, entry.AcceptableConditions)
+	})
+
+	t.Run("stores target price, notes, platforms and pause flag", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		notes := "wait for black friday"
+		paused := true
+		entry, err := repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{
+			TargetPrice: 			number(49.99),
+			Notes: 					&notes,
+			PreferredPlatforms: 	list("bestbuy"),
+			ExcludedPlatforms: 		list("ebay"),
+			Paused: 				&paused,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 49.99, entry.TargetPrice)
+		assert.Equal(t, notes, entry.Notes)
+		assert.Equal(t, []string{"bestbuy"}, entry.PreferredPlatforms)
+		assert.Equal(t, []string{"ebay"}, entry.ExcludedPlatforms)
+		assert.True(t, entry.Paused)
+
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Equal(t, 49.99, products[0].TargetPrice)
+		assert.True(t, products[0].Paused)
+
+		empty := ""
+		entry, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{
+			TargetPrice: 	number(0),
+			Notes: 			&empty,
+		})
+		require.NoError(t, err)
+		assert.Zero(t, entry.TargetPrice)
+		assert.Empty(t, entry.Notes)
+		assert.Equal(t, []string{"ebay"}, entry.ExcludedPlatforms)
+	})
+
+	t.Run("rejects platforms that end up both preferred and excluded", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		_, err := repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{PreferredPlatforms: list("ebay")})
+		require.NoError(t, err)
+
+		_, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{
+			ExcludedPlatforms: 	list("amazon", "ebay"),
+			TargetPrice: 		number(30),
+		})
+		assert.ErrorIs(t, err, store.ErrConflict)
+
+		entry, err := repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{})
+		require.NoError(t, err)
+		assert.Empty(t, entry.ExcludedPlatforms, "the conflicting update is rolled back")
+		assert.Zero(t, entry.TargetPrice)
+
+		entry, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{
+			PreferredPlatforms: 	list(),
+			ExcludedPlatforms: 		list("ebay"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ebay"}, entry.ExcludedPlatforms)
+	})
+
+	t.Run("alerts of a paused entry don't trigger", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/dp/B0KEYBOARD"})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: 40, InStock: true})
+		test.SeedAlertRule(t, pool, test.AlertRuleConfig{UserID: userID, ProductID: productID, RuleType: types.AlertPriceBelow, Threshold: 50, Active: true})
+
+		detail, err := repo.FetchProductDetail(ctx, userID, productID)
+		require.NoError(t, err)
+		require.Len(t, detail.Alerts, 1)
+		assert.True(t, detail.Alerts[0].Triggered)
+
+		paused := true
+		_, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{Paused: &paused})
+		require.NoError(t, err)
+
+		detail, err = repo.FetchProductDetail(ctx, userID, productID)
+		require.NoError(t, err)
+		assert.False(t, detail.Alerts[0].Triggered)
+		assert.Equal(t, 40.0, detail.Alerts[0].CurrentPrice, "still evaluated for display")
+	})
+
+	t.Run("excluded platforms never count and preferred ones win ties", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		for platform, price := range map[string]float64{"ebay": 40, "amazon": 60, "bestbuy": 60} {
+			sourceID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+				ProductID: 	productID,
+				Platform: 	platform,
+				URL: 		"https://" + platform + ".com/keyboard",
+			})
+			test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sourceID, Price: price, InStock: true})
+		}
+
+		_, err := repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{
+			ExcludedPlatforms: 	list("ebay"),
+			PreferredPlatforms: list("bestbuy"),
+		})
+		require.NoError(t, err)
+
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Equal(t, 60.0, products[0].LowestPrice)
+		assert.Equal(t, "bestbuy", products[0].LowestSource)
+
+		detail, err := repo.FetchProductDetail(ctx, userID, productID)
+		require.NoError(t, err)
+		require.Len(t, detail.Sources, 3)
+		assert.Equal(t, "ebay", detail.Sources[2].Platform)
+		assert.False(t, detail.Sources[2].Acceptable)
+	})
+
+	t.Run("products outside the watchlist are not found", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		otherID := test.SeedUser(t, pool, "user2", "user2@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, otherID, productID)
+
+		_, err := repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{MinSellerRating: number(90)})
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("filters offers out of the lowest price", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		amazonID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID: 	productID,
+			Platform: 	"amazon",
+			URL: 		"https://amazon.com/dp/B0KEYBOARD",
+		})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 80, InStock: true})
+
+		usedID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID: 	productID,
+			Platform: 	"ebay",
+			URL: 		"https://ebay.com/itm/1",
+			Condition: 	types.ConditionUsed,
+		})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: usedID, Price: 40, InStock: true, SellerRating: 99.8})
+
+		sketchyID := test.SeedProductSource(t, pool, test.ProductSourceConfig{
+			ProductID: 	productID,
+			Platform: 	"ebay",
+			URL: 		"https://ebay.com/itm/2",
+		})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: sketchyID, Price: 60, InStock: true, SellerName: "deals4u", SellerRating: 82})
+
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Equal(t, 40.0, products[0].LowestPrice, "every offer counts without filters")
+
+		_, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{
+			AcceptableConditions: list(types.ConditionNew),
+		})
+		require.NoError(t, err)
+
+		products, err = repo.FetchUserTrackedProducts(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 60.0, products[0].LowestPrice, "used offer is skipped")
+
+		_, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{MinSellerRating: number(95)})
+		require.NoError(t, err)
+
+		products, err = repo.FetchUserTrackedProducts(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 80.0, products[0].LowestPrice, "low rated seller is skipped")
+		assert.Equal(t, "amazon", products[0].LowestSource)
+
+		detail, err := repo.FetchProductDetail(ctx, userID, productID)
+		require.NoError(t, err)
+		require.Len(t, detail.Sources, 3)
+		assert.True(t, detail.Sources[0].Acceptable)
+		assert.Equal(t, "https://amazon.com/dp/B0KEYBOARD", detail.URL, "links to the cheapest acceptable offer")
+		assert.False(t, detail.Sources[1].Acceptable)
+		assert.False(t, detail.Sources[2].Acceptable)
+		assert.Equal(t, types.ConditionUsed, detail.Sources[1].Condition)
+		assert.Equal(t, "deals4u", detail.Sources[2].SellerName)
+	})
+
+	t.Run("unrated marketplace sellers only pass the rating threshold when allowed", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		// sold by amazon itself, there's no seller to rate
+		amazonID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "amazon", URL: "https://amazon.com/dp/B0KEYBOARD"})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: amazonID, Price: 80, InStock: true})
+
+		unratedID := test.SeedProductSource(t, pool, test.ProductSourceConfig{ProductID: productID, Platform: "ebay", URL: "https://ebay.com/itm/3"})
+		test.SeedPriceSnapshot(t, pool, test.PriceSnapshotConfig{ProductSourceID: unratedID, Price: 55, InStock: true, SellerName: "newshop"})
+
+		entry, err := repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{MinSellerRating: number(95)})
+		require.NoError(t, err)
+		assert.False(t, entry.AllowUnratedSellers)
+
+		products, err := repo.FetchUserTrackedProducts(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, products, 1)
+		assert.Equal(t, 80.0, products[0].LowestPrice, "seller without a rating fails the threshold")
+		assert.Equal(t, "amazon", products[0].LowestSource)
+
+		allow := true
+		entry, err = repo.UpdateWatchlistEntry(ctx, userID, productID, types.WatchlistUpdate{AllowUnratedSellers: &allow})
+		require.NoError(t, err)
+		assert.True(t, entry.AllowUnratedSellers)
+
+		products, err = repo.FetchUserTrackedProducts(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, 55.0, products[0].LowestPrice)
+		assert.Equal(t, "ebay", products[0].LowestSource)
+	})
+}
+
+// Integration tests for BatchWatchlist SQL func
+func TestBatchWatchlist(t *testing.T) {
+	pool := testDB.Pool
+	repo := db.NewRepository(pool)
+	ctx := context.Background()
+
+	number := func(value float64) *float64 { return &value }
+
+	watched := func(t *testing.T, userID int) int {
+		var count int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM user_watchlist WHERE user_id = $1`, userID).Scan(&count))
+		return count
+	}
+
+	statuses := func(result types.WatchlistBatchResult) []string {
+		out := []string{}
+		for _, opResult := range result.Results {
+			out = append(out, opResult.Status)
+		}
+		return out
+	}
+
+	t.Run("adds, updates and deletes in one batch", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		keepID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		dropID := test.SeedProduct(t, pool, "Old Mouse", "")
+		test.AddProductToWatchlist(t, pool, userID, keepID)
+		test.AddProductToWatchlist(t, pool, userID, dropID)
+
+		result, err := repo.BatchWatchlist(ctx, userID, types.WatchlistBatch{
+			Mode: 		types.BatchModeAtomic,
+			Operations: []types.WatchlistOperation{
+				{Op: types.BatchOpAdd, ProductName: "Ryzen 7 7700"},
+				{Op: types.BatchOpAdd, ProductName: "Amazon B09XS7JWHH", Source: &types.ProductSource{Platform: "amazon", PlatformProductID: "B09XS7JWHH", URL: "https://www.amazon.com/dp/B09XS7JWHH"}},
+				{Op: types.BatchOpUpdate, ProductID: keepID, Update: &types.WatchlistUpdate{TargetPrice: number(80)}},
+				{Op: types.BatchOpDelete, ProductID: dropID},
+			},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, 4, result.Succeeded)
+		assert.Equal(t, []string{types.BatchStatusOK, types.BatchStatusOK, types.BatchStatusOK, types.BatchStatusOK}, statuses(result))
+		assert.NotZero(t, result.Results[0].ProductID)
+		require.NotNil(t, result.Results[2].Entry)
+		assert.Equal(t, 80.0, result.Results[2].Entry.TargetPrice)
+
+		assert.Equal(t, 3, watched(t, userID))
+	})
+
+	t.Run("atomic batches roll back at the first failure", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		result, err := repo.BatchWatchlist(ctx, userID, types.WatchlistBatch{
+			Mode: 		types.BatchModeAtomic,
+			Operations: []types.WatchlistOperation{
+				{Op: types.BatchOpAdd, ProductName: "Ryzen 7 7700"},
+				{Op: types.BatchOpDelete, ProductID: productID + 100},
+				{Op: types.BatchOpDelete, ProductID: productID},
+			},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.Committed)
+		assert.Equal(t, 0, result.Succeeded)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, []string{types.BatchStatusRolledBack, types.BatchStatusFailed, types.BatchStatusSkipped}, statuses(result))
+		assert.Equal(t, "product not found in user's watchlist", result.Results[1].Error)
+
+		assert.Equal(t, 1, watched(t, userID), "nothing was saved")
+	})
+
+	t.Run("best effort batches keep what worked", func(t *testing.T) {
+		test.CleanupTables(t, pool)
+
+		userID := test.SeedUser(t, pool, "user1", "user1@example.com")
+		productID := test.SeedProduct(t, pool, "Mechanical Keyboard", "")
+		test.AddProductToWatchlist(t, pool, userID, productID)
+
+		result, err := repo.BatchWatchlist(ctx, userID, types.WatchlistBatch{
+			Mode: 		types.BatchModeBestEffort,
+			Operations: []types.WatchlistOperation{
+				{Op: types.BatchOpAdd, ProductName: "Mechanical Keyboard"},
+				{Op: types.BatchOpUpdate, ProductID: productID + 100, Update: &types.WatchlistUpdate{TargetPrice: number(80)}},
+				{Op: types.BatchOpAdd, ProductName: "Ryzen 7 7700"},
+				{Op: types.BatchOpUpdate, ProductID: productID, Update: &types.WatchlistUpdate{TargetPrice: number(1e12)}},
+			},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, 1, result.Succeeded)
+		assert.Equal(t, 3, result.Failed)
+		assert.Equal(t, []string{types.BatchStatusFailed, types.BatchStatusFailed, types.BatchStatusOK, types.BatchStatusFailed}, statuses(result))
+		assert.Equal(t, "product already in user's watchlist", result.Results[0].Error)
+		assert.Equal(t, "product not found in user's watchlist", result.Results[1].Error)
+		assert.Equal(t, "database error", result.Results[3].Error)
+
+		assert.Equal(t, 2, watched(t, userID))
+	})
+}
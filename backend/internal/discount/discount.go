@@ -0,0 +1,65 @@
+package discount
+
+import (
+	"backend/internal/types"
+	"math"
+)
+
+// claimed discounts more than this many percentage points above the one
+// measured against the reference price are flagged as inflated
+const InflationTolerance = 10.0
+
+// list prices the source charged within this share count as charged, so
+// rounding on the store's side doesn't flag a real list price
+const listPriceSlack = 0.01
+
+// prices a source charged before an offer, 0 when there were no snapshots
+// in the window
+type History struct {
+	LowestPrice30d	float64	// lowest price in the 30 days before the offer
+	HighestPrice90d	float64	// highest price in the 90 days before the offer
+}
+
+// Check a discount an offer claims from its list price against the source's
+// price history. Returns nil when the list price isn't above the price so no
+// discount is claimed. The discount is misleading when the source never
+// charged the list price in the last 90 days, or when it's more than
+// InflationTolerance points bigger than the drop from the lowest price of
+// the 30 days before, the prior price the EU Omnibus directive requires
+// sales to be advertised against
+func Check(listPrice, price float64, history History) *types.DiscountCheck {
+	if price <= 0 || listPrice <= price {
+		return nil
+	}
+
+	check := &types.DiscountCheck{
+		ListPrice: 			listPrice,
+		ClaimedPercent: 	percentBelow(price, listPrice),
+		ReferencePrice: 	history.LowestPrice30d,
+		HighestPrice90d: 	history.HighestPrice90d,
+	}
+
+	// without recent history the claim can't be verified either way
+	if history.LowestPrice30d <= 0 || history.HighestPrice90d <= 0 {
+		return check
+	}
+
+	check.Verified = true
+	check.ActualPercent = percentBelow(price, history.LowestPrice30d)
+
+	switch {
+	case history.HighestPrice90d < listPrice*(1-listPriceSlack):
+		check.Misleading = true
+		check.Reason = types.DiscountReasonNeverCharged
+	case check.ClaimedPercent-check.ActualPercent > InflationTolerance:
+		check.Misleading = true
+		check.Reason = types.DiscountReasonInflated
+	}
+
+	return check
+}
+
+// percent the price is below the reference, rounded to one decimal
+func percentBelow(price, reference float64) float64 {
+	return math.Round((reference-price)/reference*1000) / 10
+}
@@ -0,0 +1,72 @@
+//go:build unit
+
+package discount_test
+
+import (
+	"backend/internal/discount"
+	"backend/internal/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unit tests for Check function
+func TestCheck(t *testing.T) {
+	t.Run("no discount claimed", func(t *testing.T) {
+		history := discount.History{LowestPrice30d: 100, HighestPrice90d: 120}
+
+		assert.Nil(t, discount.Check(0, 80, history), "no list price")
+		assert.Nil(t, discount.Check(80, 80, history), "list price equal to the price")
+		assert.Nil(t, discount.Check(70, 80, history), "list price below the price")
+		assert.Nil(t, discount.Check(100, 0, history), "no price")
+	})
+
+	t.Run("genuine discount", func(t *testing.T) {
+		check := discount.Check(100, 75, discount.History{LowestPrice30d: 99, HighestPrice90d: 100})
+
+		require.NotNil(t, check)
+		assert.Equal(t, 25.0, check.ClaimedPercent)
+		assert.Equal(t, 99.0, check.ReferencePrice)
+		assert.Equal(t, 24.2, check.ActualPercent)
+		assert.True(t, check.Verified)
+		assert.False(t, check.Misleading)
+		assert.Empty(t, check.Reason)
+	})
+
+	t.Run("list price never charged", func(t *testing.T) {
+		check := discount.Check(200, 100, discount.History{LowestPrice30d: 110, HighestPrice90d: 120})
+
+		require.NotNil(t, check)
+		assert.Equal(t, 50.0, check.ClaimedPercent)
+		assert.True(t, check.Misleading)
+		assert.Equal(t, types.DiscountReasonNeverCharged, check.Reason)
+	})
+
+	t.Run("list price within rounding of the highest price", func(t *testing.T) {
+		check := discount.Check(100, 90, discount.History{LowestPrice30d: 99.5, HighestPrice90d: 99.5})
+
+		require.NotNil(t, check)
+		assert.False(t, check.Misleading)
+	})
+
+	t.Run("price raised before the sale", func(t *testing.T) {
+		// charged the list price months ago but sat at 80 for the last month
+		check := discount.Check(150, 90, discount.History{LowestPrice30d: 80, HighestPrice90d: 150})
+
+		require.NotNil(t, check)
+		assert.Equal(t, 40.0, check.ClaimedPercent)
+		assert.Equal(t, -12.5, check.ActualPercent)
+		assert.True(t, check.Misleading)
+		assert.Equal(t, types.DiscountReasonInflated, check.Reason)
+	})
+
+	t.Run("unverified without history", func(t *testing.T) {
+		check := discount.Check(200, 100, discount.History{})
+
+		require.NotNil(t, check)
+		assert.Equal(t, 50.0, check.ClaimedPercent)
+		assert.False(t, check.Verified)
+		assert.False(t, check.Misleading)
+	})
+}
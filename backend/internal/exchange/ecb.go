@@ -0,0 +1,147 @@
+package exchange
+
+import (
+	"backend/internal/types"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// date formats used by the ECB files, the daily csv writes "05 January 2024"
+// while the historical csv and the xml use iso dates
+var dateFormats = []string{"2006-01-02", "02 January 2006", "2 January 2006"}
+
+// ECB reference rate xml, rates are units of the currency per 1 EUR
+type ecbEnvelope struct {
+	Days []struct {
+		Time 	string `xml:"time,attr"`
+		Rates 	[]struct {
+			Currency 	string `xml:"currency,attr"`
+			Rate 		string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// Parse an ECB rates file picking the format from the file extension
+func ParseFile(name string, r io.Reader) ([]types.ExchangeRate, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".xml":
+		return ParseECBXML(r)
+	case ".csv":
+		return ParseECBCSV(r)
+	}
+	return nil, fmt.Errorf("unsupported rates file %s, expected .xml or .csv", name)
+}
+
+// Parse the eurofxref xml files (daily, 90 day and historical)
+func ParseECBXML(r io.Reader) ([]types.ExchangeRate, error) {
+	var envelope ecbEnvelope
+	err := xml.NewDecoder(r).Decode(&envelope)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding rates xml: %w", err)
+	}
+
+	rates := []types.ExchangeRate{}
+	for _, day := range envelope.Days {
+		date, err := parseDate(day.Time)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rate := range day.Rates {
+			parsed, err := newRate(date, rate.Currency, rate.Rate)
+			if err != nil {
+				return nil, err
+			}
+			rates = append(rates, parsed)
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.New("no rates found in xml")
+	}
+
+	return rates, nil
+}
+
+// Parse the eurofxref csv files, a Date column followed by one column per
+// currency. Blank and N/A cells are currencies the ECB didn't quote that day
+func ParseECBCSV(r io.Reader) ([]types.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading rates csv header: %w", err)
+	}
+	if len(header) < 2 || !strings.EqualFold(strings.TrimSpace(header[0]), "date") {
+		return nil, errors.New("rates csv must start with a Date column")
+	}
+
+	rates := []types.ExchangeRate{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading rates csv: %w", err)
+		}
+
+		date, err := parseDate(record[0])
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 1; i < len(record) && i < len(header); i++ {
+			value := strings.TrimSpace(record[i])
+			if value == "" || value == "N/A" {
+				continue
+			}
+
+			parsed, err := newRate(date, header[i], value)
+			if err != nil {
+				return nil, err
+			}
+			rates = append(rates, parsed)
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.New("no rates found in csv")
+	}
+
+	return rates, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, format := range dateFormats {
+		date, err := time.Parse(format, value)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid rate date %q", value)
+}
+
+func newRate(date time.Time, currency, value string) (types.ExchangeRate, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 {
+		return types.ExchangeRate{}, fmt.Errorf("invalid currency code %q", currency)
+	}
+
+	rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || rate <= 0 {
+		return types.ExchangeRate{}, fmt.Errorf("invalid rate %q for %s on %s", value, currency, date.Format("2006-01-02"))
+	}
+
+	return types.ExchangeRate{Date: date, Currency: currency, Rate: rate}, nil
+}
@@ -0,0 +1,113 @@
+//go:build unit
+
+package exchange_test
+
+import (
+	"backend/internal/exchange"
+	"backend/internal/types"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ecbXML = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender>
+		<gesmes:name>European Central Bank</gesmes:name>
+	</gesmes:Sender>
+	<Cube>
+		<Cube time="2024-01-05">
+			<Cube currency="USD" rate="1.0921"/>
+			<Cube currency="GBP" rate="0.86053"/>
+		</Cube>
+		<Cube time="2024-01-04">
+			<Cube currency="USD" rate="1.0953"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func date(value string) time.Time {
+	parsed, _ := time.Parse("2006-01-02", value)
+	return parsed
+}
+
+// unit tests for ParseECBXML function
+func TestParseECBXML(t *testing.T) {
+	t.Run("parses every day and currency", func(t *testing.T) {
+		rates, err := exchange.ParseECBXML(strings.NewReader(ecbXML))
+
+		require.NoError(t, err)
+		assert.Equal(t, []types.ExchangeRate{
+			{Date: date("2024-01-05"), Currency: "USD", Rate: 1.0921},
+			{Date: date("2024-01-05"), Currency: "GBP", Rate: 0.86053},
+			{Date: date("2024-01-04"), Currency: "USD", Rate: 1.0953},
+		}, rates)
+	})
+
+	t.Run("rejects files without rates or with bad values", func(t *testing.T) {
+		for _, body := range []string{
+			`<Envelope><Cube></Cube></Envelope>`,
+			`<Envelope><Cube><Cube time="yesterday"><Cube currency="USD" rate="1.09"/></Cube></Cube></Envelope>`,
+			`<Envelope><Cube><Cube time="2024-01-05"><Cube currency="USD" rate="-1"/></Cube></Cube></Envelope>`,
+			`not xml`,
+		} {
+			_, err := exchange.ParseECBXML(strings.NewReader(body))
+			assert.Error(t, err, body)
+		}
+	})
+}
+
+// unit tests for ParseECBCSV function
+func TestParseECBCSV(t *testing.T) {
+	t.Run("parses the daily csv", func(t *testing.T) {
+		body := "Date, USD, JPY, GBP, \n05 January 2024, 1.0921, 158.17, 0.86053, \n"
+
+		rates, err := exchange.ParseECBCSV(strings.NewReader(body))
+
+		require.NoError(t, err)
+		assert.Equal(t, []types.ExchangeRate{
+			{Date: date("2024-01-05"), Currency: "USD", Rate: 1.0921},
+			{Date: date("2024-01-05"), Currency: "JPY", Rate: 158.17},
+			{Date: date("2024-01-05"), Currency: "GBP", Rate: 0.86053},
+		}, rates)
+	})
+
+	t.Run("skips currencies not quoted on a day in the historical csv", func(t *testing.T) {
+		body := "Date,USD,CYP,\n2024-01-05,1.0921,N/A,\n2024-01-04,1.0953,,\n"
+
+		rates, err := exchange.ParseECBCSV(strings.NewReader(body))
+
+		require.NoError(t, err)
+		assert.Equal(t, []types.ExchangeRate{
+			{Date: date("2024-01-05"), Currency: "USD", Rate: 1.0921},
+			{Date: date("2024-01-04"), Currency: "USD", Rate: 1.0953},
+		}, rates)
+	})
+
+	t.Run("rejects malformed csv", func(t *testing.T) {
+		for _, body := range []string{
+			"",
+			"Currency,USD\n2024-01-05,1.09\n",
+			"Date,USD\n",
+			"Date,USD\nsoon,1.09\n",
+			"Date,USD\n2024-01-05,abc\n",
+		} {
+			_, err := exchange.ParseECBCSV(strings.NewReader(body))
+			assert.Error(t, err, body)
+		}
+	})
+}
+
+// unit tests for ParseFile function
+func TestParseFile(t *testing.T) {
+	rates, err := exchange.ParseFile("eurofxref.XML", strings.NewReader(ecbXML))
+	require.NoError(t, err)
+	assert.Len(t, rates, 3)
+
+	_, err = exchange.ParseFile("rates.json", strings.NewReader("{}"))
+	assert.Error(t, err)
+}
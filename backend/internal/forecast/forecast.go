@@ -0,0 +1,206 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	MethodHolt 			= "holt"
+	MethodHoltWinters 	= "holt_winters"
+)
+
+const (
+	// days of history needed for any forecast
+	MinHistory = 7
+	// weekly seasonality is fitted from this many days on, fewer than three
+	// weeks can't tell a weekly pattern from noise
+	seasonalHistory = 21
+	season = 7
+	// trends flatten out by this factor per step so a falling price isn't
+	// extrapolated to zero over a month
+	damping = 0.9
+	// z score of the confidence bands
+	z95 = 1.96
+)
+
+var ErrNotEnoughHistory = errors.New("not enough price history to forecast")
+
+// smoothing factors tried when fitting, the combination with the smallest
+// squared one step ahead error wins
+var (
+	levelFactors 	= []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+	trendFactors 	= []float64{0.01, 0.05, 0.1, 0.2, 0.3}
+	seasonFactors 	= []float64{0.01, 0.05, 0.1, 0.2, 0.3}
+)
+
+// a forecast value Step days after the last observed one with its 95%
+// confidence band, never below 0
+type Point struct {
+	Step 	int
+	Value 	float64
+	Lower 	float64
+	Upper 	float64
+}
+
+type Result struct {
+	Method 	string
+	Points 	[]Point
+}
+
+// Project a daily series horizon days ahead with damped exponential
+// smoothing. Holt's linear method models level and trend, from three weeks
+// of history Holt-Winters adds an additive weekly season. The bands come from
+// the standard deviation of the one step ahead errors widening with the
+// square root of the step
+func Forecast(values []float64, horizon int) (Result, error) {
+	if len(values) < MinHistory {
+		return Result{}, ErrNotEnoughHistory
+	}
+
+	fit := fitHolt(values)
+	method := MethodHolt
+	if len(values) >= seasonalHistory {
+		fit = fitHoltWinters(values)
+		method = MethodHoltWinters
+	}
+
+	result := Result{Method: method, Points: make([]Point, 0, horizon)}
+	for step := 1; step <= horizon; step++ {
+		value := fit.project(step)
+		band := z95 * fit.sigma * math.Sqrt(float64(step))
+		result.Points = append(result.Points, Point{
+			Step: 	step,
+			Value: 	math.Max(value, 0),
+			Lower: 	math.Max(value-band, 0),
+			Upper: 	math.Max(value+band, 0),
+		})
+	}
+
+	return result, nil
+}
+
+// First point whose forecast value is below the threshold
+func (r Result) FirstBelow(threshold float64) (Point, bool) {
+	for _, point := range r.Points {
+		if point.Value < threshold {
+			return point, true
+		}
+	}
+	return Point{}, false
+}
+
+// final state of a fitted model
+type model struct {
+	level 		float64
+	trend 		float64
+	// last season of seasonal offsets, seasonal[0] applies to the day after
+	// the last observed one. Empty for Holt's method
+	seasonal 	[]float64
+	sse 		float64
+	sigma 		float64
+}
+
+func (m model) project(step int) float64 {
+	// damped trend sums damping^1 through damping^step
+	trendSum := damping * (1 - math.Pow(damping, float64(step))) / (1 - damping)
+	value := m.level + trendSum*m.trend
+	if len(m.seasonal) > 0 {
+		value += m.seasonal[(step-1)%len(m.seasonal)]
+	}
+	return value
+}
+
+func fitHolt(values []float64) model {
+	best := model{sse: math.Inf(1)}
+	for _, alpha := range levelFactors {
+		for _, beta := range trendFactors {
+			if m := holt(values, alpha, beta); m.sse < best.sse {
+				best = m
+			}
+		}
+	}
+	return best
+}
+
+func fitHoltWinters(values []float64) model {
+	best := model{sse: math.Inf(1)}
+	for _, alpha := range levelFactors {
+		for _, beta := range trendFactors {
+			for _, gamma := range seasonFactors {
+				if m := holtWinters(values, alpha, beta, gamma); m.sse < best.sse {
+					best = m
+				}
+			}
+		}
+	}
+	return best
+}
+
+func holt(values []float64, alpha, beta float64) model {
+	level := values[0]
+	trend := values[1] - values[0]
+
+	var sse float64
+	for _, value := range values[1:] {
+		predicted := level + damping*trend
+		sse += (value - predicted) * (value - predicted)
+
+		prevLevel := level
+		level = alpha*value + (1-alpha)*predicted
+		trend = beta*(level-prevLevel) + (1-beta)*damping*trend
+	}
+
+	return model{
+		level: 	level,
+		trend: 	trend,
+		sse: 	sse,
+		sigma: 	math.Sqrt(sse / float64(len(values)-1)),
+	}
+}
+
+// the first season sets the level and offsets, the second the trend
+func holtWinters(values []float64, alpha, beta, gamma float64) model {
+	first, second := mean(values[:season]), mean(values[season:2*season])
+	level := first
+	trend := (second - first) / season
+
+	seasonal := make([]float64, season)
+	for i := range season {
+		seasonal[i] = values[i] - first
+	}
+
+	var sse float64
+	for t := season; t < len(values); t++ {
+		offset := seasonal[t%season]
+		predicted := level + damping*trend + offset
+		sse += (values[t] - predicted) * (values[t] - predicted)
+
+		prevLevel := level
+		level = alpha*(values[t]-offset) + (1-alpha)*(level+damping*trend)
+		trend = beta*(level-prevLevel) + (1-beta)*damping*trend
+		seasonal[t%season] = gamma*(values[t]-level) + (1-gamma)*offset
+	}
+
+	// rotate so the offsets start at the day after the last observed one
+	next := make([]float64, season)
+	for i := range season {
+		next[i] = seasonal[(len(values)+i)%season]
+	}
+
+	return model{
+		level: 		level,
+		trend: 		trend,
+		seasonal: 	next,
+		sse: 		sse,
+		sigma: 		math.Sqrt(sse / float64(len(values)-season)),
+	}
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
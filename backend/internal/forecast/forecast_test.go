@@ -0,0 +1,99 @@
+//go:build unit
+
+package forecast_test
+
+import (
+	"backend/internal/forecast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unit tests for Forecast function
+func TestForecast(t *testing.T) {
+	t.Run("needs a week of history", func(t *testing.T) {
+		_, err := forecast.Forecast([]float64{100, 100, 100, 100, 100, 100}, 30)
+		assert.ErrorIs(t, err, forecast.ErrNotEnoughHistory)
+	})
+
+	t.Run("flat prices stay flat with no spread", func(t *testing.T) {
+		values := []float64{100, 100, 100, 100, 100, 100, 100, 100, 100, 100}
+
+		result, err := forecast.Forecast(values, 30)
+
+		require.NoError(t, err)
+		assert.Equal(t, forecast.MethodHolt, result.Method)
+		require.Len(t, result.Points, 30)
+		for _, point := range result.Points {
+			assert.InDelta(t, 100, point.Value, 0.001)
+			assert.InDelta(t, 100, point.Lower, 0.001)
+			assert.InDelta(t, 100, point.Upper, 0.001)
+		}
+		assert.Equal(t, 1, result.Points[0].Step)
+		assert.Equal(t, 30, result.Points[29].Step)
+	})
+
+	t.Run("falling prices keep falling and level off", func(t *testing.T) {
+		values := []float64{}
+		for day := range 14 {
+			values = append(values, 200-float64(day)*2)
+		}
+
+		result, err := forecast.Forecast(values, 30)
+
+		require.NoError(t, err)
+		first, last := result.Points[0], result.Points[29]
+		assert.Less(t, first.Value, 174.0)
+		assert.Less(t, last.Value, first.Value)
+		assert.Greater(t, last.Value, 174.0-30*2.0, "damped trend falls slower than a straight line")
+	})
+
+	t.Run("bands widen with the horizon and never go below 0", func(t *testing.T) {
+		values := []float64{10, 14, 6, 12, 4, 13, 5, 11, 3, 12}
+
+		result, err := forecast.Forecast(values, 30)
+
+		require.NoError(t, err)
+		first, last := result.Points[0], result.Points[29]
+		assert.Greater(t, last.Upper-last.Lower, first.Upper-first.Lower)
+		for _, point := range result.Points {
+			assert.GreaterOrEqual(t, point.Lower, 0.0)
+			assert.LessOrEqual(t, point.Lower, point.Value)
+			assert.GreaterOrEqual(t, point.Upper, point.Value)
+		}
+	})
+
+	t.Run("picks up a weekly pattern from three weeks on", func(t *testing.T) {
+		// weekend sale on the last two days of every week
+		week := []float64{100, 100, 100, 100, 100, 80, 80}
+		values := []float64{}
+		for range 4 {
+			values = append(values, week...)
+		}
+
+		result, err := forecast.Forecast(values, 14)
+
+		require.NoError(t, err)
+		assert.Equal(t, forecast.MethodHoltWinters, result.Method)
+		for i, point := range result.Points {
+			assert.InDelta(t, week[i%7], point.Value, 2, "step %d", point.Step)
+		}
+	})
+}
+
+// unit tests for Result.FirstBelow method
+func TestFirstBelow(t *testing.T) {
+	result := forecast.Result{Points: []forecast.Point{
+		{Step: 1, Value: 110},
+		{Step: 2, Value: 99.99},
+		{Step: 3, Value: 90},
+	}}
+
+	point, ok := result.FirstBelow(100)
+	require.True(t, ok)
+	assert.Equal(t, 2, point.Step)
+
+	_, ok = result.FirstBelow(90)
+	assert.False(t, ok)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/types"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// POST route to add an alert rule on a product of the logged in user's
+// watchlist. type is price_below, percent_drop, back_in_stock or
+// predicted_below, threshold is a price in the user's display currency or a
+// percent up to 100 for percent_drop and is ignored for back_in_stock.
+// price_basis (sticker or landed) defaults to the user's preference
+func (h *ProductHandler) CreateAlert(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || productID < 1 {
+		http.Error(w, "Invalid product id: must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	var payload types.AlertRuleInput
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch payload.Type {
+	case types.AlertBackInStock:
+		payload.Threshold = nil
+	case types.AlertPercentDrop:
+		if *payload.Threshold > 100 {
+			http.Error(w, "Invalid threshold: a percent drop can't be over 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rule, dbErr := h.products.InsertAlertRule(r.Context(), user.UserId, productID, payload)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Product not found in user's watchlist")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+// DELETE route to remove one of the logged in user's alert rules
+func (h *ProductHandler) DeleteAlert(w http.ResponseWriter, r *http.Request) {
+	alertID, ok := pathID(w, r, "alert_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	dbErr := h.products.DeleteAlertRule(r.Context(), user.UserId, alertID)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Alert rule not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,122 @@
+//go:build unit
+
+package handler_test
+
+import (
+	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"backend/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveAlert(mock *handler.MockProductStore, method, path, body string, withUser bool) *httptest.ResponseRecorder {
+	h := handler.NewProductHandler(mock)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/products/{id}/alerts", h.CreateAlert)
+	mux.HandleFunc("DELETE /api/v1/alerts/{alert_id}", h.DeleteAlert)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if withUser {
+		req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+	}
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+	return w
+}
+
+// Unit tests for the CreateAlert Handler function
+func TestCreateAlertHandler(t *testing.T) {
+	t.Run("invalid product id returns 400", func(t *testing.T) {
+		for _, path := range []string{"/api/v1/products/abc/alerts", "/api/v1/products/0/alerts"} {
+			w := serveAlert(&handler.MockProductStore{}, http.MethodPost, path, `{"type": "price_below", "threshold": 10}`, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		}
+	})
+
+	t.Run("missing user returns 401", func(t *testing.T) {
+		w := serveAlert(&handler.MockProductStore{}, http.MethodPost, "/api/v1/products/4/alerts", `{"type": "price_below", "threshold": 10}`, false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("invalid payloads return 400", func(t *testing.T) {
+		for _, body := range []string{
+			`not json`,
+			`{"threshold": 10}`,
+			`{"type": "price_above", "threshold": 10}`,
+			`{"type": "price_below"}`,
+			`{"type": "predicted_below", "threshold": -1}`,
+			`{"type": "percent_drop", "threshold": 101}`,
+			`{"type": "price_below", "threshold": 10, "price_basis": "net"}`,
+		} {
+			mock := &handler.MockProductStore{}
+			w := serveAlert(mock, http.MethodPost, "/api/v1/products/4/alerts", body, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+			assert.Empty(t, mock.LastAlertInput.Type, body)
+		}
+	})
+
+	t.Run("creates a predicted_below rule on its price basis", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serveAlert(mock, http.MethodPost, "/api/v1/products/4/alerts", `{"type": "predicted_below", "threshold": 89.5, "price_basis": "landed"}`, true)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var rule types.AlertRule
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rule))
+		assert.Equal(t, types.AlertPredictedBelow, rule.Type)
+		assert.Equal(t, 89.5, rule.Threshold)
+		assert.Equal(t, types.PriceBasisLanded, rule.PriceBasis)
+		assert.True(t, rule.Active)
+	})
+
+	t.Run("back_in_stock ignores the threshold", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serveAlert(mock, http.MethodPost, "/api/v1/products/4/alerts", `{"type": "back_in_stock", "threshold": 10}`, true)
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Nil(t, mock.LastAlertInput.Threshold)
+	})
+
+	t.Run("store errors map to 404 and 500", func(t *testing.T) {
+		mock := &handler.MockProductStore{AlertErr: fmt.Errorf("product 4: %w", store.ErrNotFound)}
+		w := serveAlert(mock, http.MethodPost, "/api/v1/products/4/alerts", `{"type": "price_below", "threshold": 10}`, true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		mock = &handler.MockProductStore{AlertErr: errors.New("db error")}
+		w = serveAlert(mock, http.MethodPost, "/api/v1/products/4/alerts", `{"type": "price_below", "threshold": 10}`, true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+// Unit tests for the DeleteAlert Handler function
+func TestDeleteAlertHandler(t *testing.T) {
+	t.Run("invalid alert id returns 400", func(t *testing.T) {
+		w := serveAlert(&handler.MockProductStore{}, http.MethodDelete, "/api/v1/alerts/abc", "", true)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("missing user returns 401", func(t *testing.T) {
+		w := serveAlert(&handler.MockProductStore{}, http.MethodDelete, "/api/v1/alerts/2", "", false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("deletes the rule", func(t *testing.T) {
+		w := serveAlert(&handler.MockProductStore{}, http.MethodDelete, "/api/v1/alerts/2", "", true)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("someone else's rule returns 404", func(t *testing.T) {
+		mock := &handler.MockProductStore{AlertErr: fmt.Errorf("alert rule 2: %w", store.ErrNotFound)}
+		w := serveAlert(mock, http.MethodDelete, "/api/v1/alerts/2", "", true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
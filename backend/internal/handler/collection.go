@@ -0,0 +1,383 @@
+package handler
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"backend/internal/types"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const maxCollectionItemQuantity = 1000
+
+type CollectionHandler struct {
+	collections	store.CollectionStore
+	validate	*validator.Validate
+}
+
+func NewCollectionHandler(collections store.CollectionStore) *CollectionHandler {
+	return &CollectionHandler{
+		collections: 	collections,
+		validate: 		validator.New(),
+	}
+}
+
+// GET route to list the logged in user's collections and the ones shared with
+// them, with their item counts and the combined lowest price of their items
+func (h *CollectionHandler) GetCollections(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	collections, dbErr := h.collections.FetchCollections(r.Context(), user.UserId)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, collections)
+}
+
+// GET route to fetch a collection the logged in user owns or is a member of
+// with its items, members and the alerts everyone with access set on the items
+func (h *CollectionHandler) GetCollection(w http.ResponseWriter, r *http.Request) {
+	collectionID, ok := pathID(w, r, "collection_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	collection, dbErr := h.collections.FetchCollection(r.Context(), user.UserId, collectionID)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Collection not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, collection)
+}
+
+// POST route to create an empty collection, names are unique per user
+func (h *CollectionHandler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	var payload types.CollectionInput
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+	payload.Description = strings.TrimSpace(payload.Description)
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collection, dbErr := h.collections.InsertCollection(r.Context(), user.UserId, payload)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, collection)
+}
+
+// PATCH route for the owner to rename a collection or change its description
+func (h *CollectionHandler) UpdateCollection(w http.ResponseWriter, r *http.Request) {
+	collectionID, ok := pathID(w, r, "collection_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	var payload types.CollectionUpdate
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if payload.Name != nil {
+		name := strings.TrimSpace(*payload.Name)
+		if name == "" {
+			http.Error(w, "Collection name can't be empty", http.StatusBadRequest)
+			return
+		}
+		payload.Name = &name
+	}
+	if payload.Description != nil {
+		description := strings.TrimSpace(*payload.Description)
+		payload.Description = &description
+	}
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collection, dbErr := h.collections.UpdateCollection(r.Context(), user.UserId, collectionID, payload)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Collection not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, collection)
+}
+
+// DELETE route for the owner to delete a collection, its products stay in
+// the watchlist
+func (h *CollectionHandler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	collectionID, ok := pathID(w, r, "collection_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	dbErr := h.collections.DeleteCollection(r.Context(), user.UserId, collectionID)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Collection not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT route for the owner or an editor to add a product from the owner's
+// watchlist to a collection, the optional quantity (default 1) counts towards the
+// collection total
+func (h *CollectionHandler) PutCollectionItem(w http.ResponseWriter, r *http.Request) {
+	collectionID, ok := pathID(w, r, "collection_id")
+	if !ok {
+		return
+	}
+	productID, ok := pathID(w, r, "product_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	payload := struct {
+		Quantity	int	`json:"quantity" validate:"gte=1,lte=1000"`
+	}{Quantity: 1}
+
+	// the body is optional
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+	}
+
+	err := h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, "Invalid quantity: must be between 1 and "+strconv.Itoa(maxCollectionItemQuantity), http.StatusBadRequest)
+		return
+	}
+
+	collection, dbErr := h.collections.SetCollectionItem(r.Context(), user.UserId, collectionID, productID, payload.Quantity)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Collection or product in owner's watchlist not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, collection)
+}
+
+// DELETE route for the owner or an editor to remove a product from a collection
+func (h *CollectionHandler) DeleteCollectionItem(w http.ResponseWriter, r *http.Request) {
+	collectionID, ok := pathID(w, r, "collection_id")
+	if !ok {
+		return
+	}
+	productID, ok := pathID(w, r, "product_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	dbErr := h.collections.DeleteCollectionItem(r.Context(), user.UserId, collectionID, productID)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Product not found in collection")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST route for the owner to share a collection with another user found by
+// username or email, as a viewer who can read it or an editor who can also
+// change its items. Sharing again with the same user changes their role
+func (h *CollectionHandler) AddCollectionMember(w http.ResponseWriter, r *http.Request) {
+	collectionID, ok := pathID(w, r, "collection_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	var payload types.CollectionInvite
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	payload.Username = strings.TrimSpace(payload.Username)
+	payload.Email = strings.TrimSpace(payload.Email)
+	payload.Role = strings.ToLower(strings.TrimSpace(payload.Role))
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if payload.Username == user.Username {
+		http.Error(w, "Can't share a collection with yourself", http.StatusBadRequest)
+		return
+	}
+
+	member, dbErr := h.collections.AddCollectionMember(r.Context(), user.UserId, collectionID, payload)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Collection or user not found")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, member)
+}
+
+// PATCH route for the owner to change a member's role
+func (h *CollectionHandler) UpdateCollectionMember(w http.ResponseWriter, r *http.Request) {
+	collectionID, ok := pathID(w, r, "collection_id")
+	if !ok {
+		return
+	}
+	memberID, ok := pathID(w, r, "user_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Role	string	`json:"role" validate:"required,oneof=viewer editor"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	payload.Role = strings.ToLower(strings.TrimSpace(payload.Role))
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	member, dbErr := h.collections.UpdateCollectionMember(r.Context(), user.UserId, collectionID, memberID, payload.Role)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Collection member not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, member)
+}
+
+// DELETE route to stop sharing a collection with a member, members can
+// remove themselves to leave a collection shared with them
+func (h *CollectionHandler) DeleteCollectionMember(w http.ResponseWriter, r *http.Request) {
+	collectionID, ok := pathID(w, r, "collection_id")
+	if !ok {
+		return
+	}
+	memberID, ok := pathID(w, r, "user_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	dbErr := h.collections.DeleteCollectionMember(r.Context(), user.UserId, collectionID, memberID)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Collection member not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET route to list the tags on the logged in user's watchlist with how many
+// products have each of them
+func (h *CollectionHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	tags, dbErr := h.collections.FetchUserTags(r.Context(), user.UserId)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tags)
+}
@@ -0,0 +1,222 @@
+//go:build unit
+
+package handler_test
+
+import (
+	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"backend/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Unit tests for the CollectionHandler functions
+func TestCollectionHandlers(t *testing.T) {
+	serve := func(mock *handler.MockCollectionStore, method, path, body string, withUser bool) *httptest.ResponseRecorder {
+		h := handler.NewCollectionHandler(mock)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/collections", h.GetCollections)
+		mux.HandleFunc("POST /api/v1/collections", h.CreateCollection)
+		mux.HandleFunc("GET /api/v1/collections/{collection_id}", h.GetCollection)
+		mux.HandleFunc("PATCH /api/v1/collections/{collection_id}", h.UpdateCollection)
+		mux.HandleFunc("DELETE /api/v1/collections/{collection_id}", h.DeleteCollection)
+		mux.HandleFunc("PUT /api/v1/collections/{collection_id}/items/{product_id}", h.PutCollectionItem)
+		mux.HandleFunc("DELETE /api/v1/collections/{collection_id}/items/{product_id}", h.DeleteCollectionItem)
+		mux.HandleFunc("GET /api/v1/watchlist/tags", h.GetTags)
+		mux.HandleFunc("POST /api/v1/collections/{collection_id}/members", h.AddCollectionMember)
+		mux.HandleFunc("PATCH /api/v1/collections/{collection_id}/members/{user_id}", h.UpdateCollectionMember)
+		mux.HandleFunc("DELETE /api/v1/collections/{collection_id}/members/{user_id}", h.DeleteCollectionMember)
+
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if withUser {
+			req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("every route requires a logged in user", func(t *testing.T) {
+		routes := [][2]string{
+			{http.MethodGet, "/api/v1/collections"},
+			{http.MethodPost, "/api/v1/collections"},
+			{http.MethodGet, "/api/v1/collections/1"},
+			{http.MethodPatch, "/api/v1/collections/1"},
+			{http.MethodDelete, "/api/v1/collections/1"},
+			{http.MethodPut, "/api/v1/collections/1/items/2"},
+			{http.MethodDelete, "/api/v1/collections/1/items/2"},
+			{http.MethodGet, "/api/v1/watchlist/tags"},
+			{http.MethodPost, "/api/v1/collections/1/members"},
+			{http.MethodPatch, "/api/v1/collections/1/members/2"},
+			{http.MethodDelete, "/api/v1/collections/1/members/2"},
+		}
+
+		for _, route := range routes {
+			w := serve(&handler.MockCollectionStore{}, route[0], route[1], `{"name": "Homelab"}`, false)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, route[0]+" "+route[1])
+		}
+	})
+
+	t.Run("invalid ids return 400", func(t *testing.T) {
+		paths := [][2]string{
+			{http.MethodGet, "/api/v1/collections/abc"},
+			{http.MethodDelete, "/api/v1/collections/0"},
+			{http.MethodPut, "/api/v1/collections/1/items/abc"},
+			{http.MethodDelete, "/api/v1/collections/-1/items/2"},
+		}
+
+		for _, route := range paths {
+			w := serve(&handler.MockCollectionStore{}, route[0], route[1], ``, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, route[0]+" "+route[1])
+		}
+	})
+
+	t.Run("creates a collection with a trimmed name", func(t *testing.T) {
+		mock := &handler.MockCollectionStore{}
+		w := serve(mock, http.MethodPost, "/api/v1/collections", `{"name": "  Homelab build ", "description": "rack parts"}`, true)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, types.CollectionInput{Name: "Homelab build", Description: "rack parts"}, mock.LastInput)
+
+		var collection types.Collection
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+		assert.Equal(t, "Homelab build", collection.Name)
+	})
+
+	t.Run("rejects invalid collections", func(t *testing.T) {
+		payloads := []string{``, `{}`, `{"name": "   "}`, `{"name": "` + strings.Repeat("a", 101) + `"}`}
+
+		for _, payload := range payloads {
+			w := serve(&handler.MockCollectionStore{}, http.MethodPost, "/api/v1/collections", payload, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+		}
+
+		w := serve(&handler.MockCollectionStore{}, http.MethodPatch, "/api/v1/collections/1", `{"name": ""}`, true)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("duplicate names return 409", func(t *testing.T) {
+		mock := &handler.MockCollectionStore{InsertErr: &pgconn.PgError{Code: "23505"}}
+		w := serve(mock, http.MethodPost, "/api/v1/collections", `{"name": "Homelab"}`, true)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("updates only the given fields", func(t *testing.T) {
+		mock := &handler.MockCollectionStore{}
+		w := serve(mock, http.MethodPatch, "/api/v1/collections/1", `{"description": " new rack "}`, true)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, mock.LastUpdate.Name)
+		require.NotNil(t, mock.LastUpdate.Description)
+		assert.Equal(t, "new rack", *mock.LastUpdate.Description)
+	})
+
+	t.Run("item quantity defaults to one", func(t *testing.T) {
+		mock := &handler.MockCollectionStore{}
+		w := serve(mock, http.MethodPut, "/api/v1/collections/1/items/2", ``, true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, mock.LastQuantity)
+
+		w = serve(mock, http.MethodPut, "/api/v1/collections/1/items/2", `{"quantity": 4}`, true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 4, mock.LastQuantity)
+
+		for _, payload := range []string{`{"quantity": 0}`, `{"quantity": 1001}`, `{"quantity": "two"}`} {
+			w = serve(mock, http.MethodPut, "/api/v1/collections/1/items/2", payload, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+		}
+	})
+
+	t.Run("deletes return 204", func(t *testing.T) {
+		w := serve(&handler.MockCollectionStore{}, http.MethodDelete, "/api/v1/collections/1", ``, true)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = serve(&handler.MockCollectionStore{}, http.MethodDelete, "/api/v1/collections/1/items/2", ``, true)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("shares a collection by username or email", func(t *testing.T) {
+		mock := &handler.MockCollectionStore{}
+		w := serve(mock, http.MethodPost, "/api/v1/collections/1/members", `{"username": " user2 ", "role": "Editor"}`, true)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, types.CollectionInvite{Username: "user2", Role: types.CollectionRoleEditor}, mock.LastInvite)
+
+		w = serve(mock, http.MethodPost, "/api/v1/collections/1/members", `{"email": "user2@example.com", "role": "viewer"}`, true)
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "user2@example.com", mock.LastInvite.Email)
+	})
+
+	t.Run("rejects invalid invites", func(t *testing.T) {
+		payloads := []string{
+			``,
+			`{"role": "viewer"}`,
+			`{"username": "user2"}`,
+			`{"username": "user2", "role": "owner"}`,
+			`{"email": "not-an-email", "role": "viewer"}`,
+			`{"username": "user1", "role": "viewer"}`,
+		}
+
+		for _, payload := range payloads {
+			w := serve(&handler.MockCollectionStore{}, http.MethodPost, "/api/v1/collections/1/members", payload, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+		}
+	})
+
+	t.Run("changes and removes members", func(t *testing.T) {
+		mock := &handler.MockCollectionStore{}
+		w := serve(mock, http.MethodPatch, "/api/v1/collections/1/members/2", `{"role": "viewer"}`, true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, types.CollectionRoleViewer, mock.LastRole)
+
+		w = serve(mock, http.MethodPatch, "/api/v1/collections/1/members/2", `{"role": "admin"}`, true)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = serve(mock, http.MethodDelete, "/api/v1/collections/1/members/2", ``, true)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("role errors return 403", func(t *testing.T) {
+		forbidden := fmt.Errorf("viewer of collection 1: %w", store.ErrForbidden)
+
+		w := serve(&handler.MockCollectionStore{ItemErr: forbidden}, http.MethodPut, "/api/v1/collections/1/items/2", ``, true)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = serve(&handler.MockCollectionStore{UpdateErr: forbidden}, http.MethodPatch, "/api/v1/collections/1", `{"name": "Rack"}`, true)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = serve(&handler.MockCollectionStore{MemberErr: forbidden}, http.MethodPost, "/api/v1/collections/1/members", `{"username": "user3", "role": "viewer"}`, true)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("store errors map to 404 and 500", func(t *testing.T) {
+		notFound := fmt.Errorf("collection 1: %w", store.ErrNotFound)
+
+		w := serve(&handler.MockCollectionStore{FetchErr: notFound}, http.MethodGet, "/api/v1/collections/1", ``, true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serve(&handler.MockCollectionStore{ItemErr: notFound}, http.MethodPut, "/api/v1/collections/1/items/2", ``, true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serve(&handler.MockCollectionStore{DeleteErr: notFound}, http.MethodDelete, "/api/v1/collections/1", ``, true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serve(&handler.MockCollectionStore{FetchErr: errors.New("db error")}, http.MethodGet, "/api/v1/collections", ``, true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		w = serve(&handler.MockCollectionStore{TagsErr: errors.New("db error")}, http.MethodGet, "/api/v1/watchlist/tags", ``, true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"backend/internal/middleware"
+	"encoding/json"
+	"net/http"
+)
+
+// GET route returning the csrf token for the client, the CSRF middleware
+// sets the matching cookie if the client didn't have one yet
+func CSRFToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	err := json.NewEncoder(w).Encode(map[string]string{
+		"csrf_token": middleware.CSRFTokenFromContext(r.Context()),
+	})
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/logger"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var exportCSVHeader = []string{
+	"product_id", "product_name", "added_at", "target_price", "notes", "tags", "paused",
+	"source_id", "platform", "product_url", "condition", "seller_name", "seller_rating",
+	"price", "currency", "shipping_cost", "estimated_tax", "discount", "in_stock", "checked_at",
+}
+
+type ExportHandler struct {
+	exports	store.ExportStore
+}
+
+func NewExportHandler(exports store.ExportStore) *ExportHandler {
+	return &ExportHandler{
+		exports: 	exports,
+	}
+}
+
+// GET route streaming the logged in user's watchlist with the full price
+// history of every source as a download. format is csv (default), json or
+// ndjson
+func (h *ExportHandler) ExportWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = types.ExportFormatCSV
+	}
+
+	encoder, ok := newExportEncoder(format, w)
+	if !ok {
+		http.Error(w, "Invalid format: must be csv, json or ndjson", http.StatusBadRequest)
+		return
+	}
+
+	// headers go out with the first row so a failing query can still answer 500
+	started := false
+	start := func() error {
+		filename := fmt.Sprintf("watchlist-%s.%s", time.Now().UTC().Format("2006-01-02"), format)
+
+		w.Header().Set("Content-Type", encoder.contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+
+		started = true
+		return encoder.open()
+	}
+
+	dbErr := h.exports.ExportWatchlist(r.Context(), user.UserId, func(row types.ExportRow) error {
+		if !started {
+			err := start()
+			if err != nil {
+				return err
+			}
+		}
+		return encoder.write(row)
+	})
+
+	if dbErr == nil && !started {
+		dbErr = start()
+	}
+	if dbErr == nil {
+		dbErr = encoder.close()
+	}
+
+	if dbErr != nil {
+		if !started {
+			logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		// the status is already sent, abort so the client sees a broken
+		// download instead of a file that looks complete
+		logger.FromContext(r.Context()).Error("export aborted", "err", dbErr)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// writes export rows to the response in one of the formats
+type exportEncoder struct {
+	contentType	string
+	open		func() error
+	write		func(types.ExportRow) error
+	close		func() error
+}
+
+func newExportEncoder(format string, w io.Writer) (exportEncoder, bool) {
+	switch format {
+	case types.ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		return exportEncoder{
+			contentType: 	"text/csv; charset=utf-8",
+			open: 			func() error { return writer.Write(exportCSVHeader) },
+			write: 			func(row types.ExportRow) error { return writer.Write(exportCSVRecord(row)) },
+			close: 			func() error {
+				writer.Flush()
+				return writer.Error()
+			},
+		}, true
+
+	case types.ExportFormatJSON:
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered)
+		first := true
+		return exportEncoder{
+			contentType: 	"application/json",
+			open: 			func() error {
+				_, err := buffered.WriteString("[")
+				return err
+			},
+			write: 			func(row types.ExportRow) error {
+				if !first {
+					_, err := buffered.WriteString(",")
+					if err != nil {
+						return err
+					}
+				}
+				first = false
+				return encoder.Encode(row)
+			},
+			close: 			func() error {
+				_, err := buffered.WriteString("]\n")
+				if err != nil {
+					return err
+				}
+				return buffered.Flush()
+			},
+		}, true
+
+	case types.ExportFormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered)
+		return exportEncoder{
+			contentType: 	"application/x-ndjson",
+			open: 			func() error { return nil },
+			write: 			func(row types.ExportRow) error { return encoder.Encode(row) },
+			close: 			buffered.Flush,
+		}, true
+	}
+
+	return exportEncoder{}, false
+}
+
+// one csv line, nil fields are left empty and tags are joined with ;
+func exportCSVRecord(row types.ExportRow) []string {
+	return []string{
+		strconv.Itoa(row.ProductID),
+		csvText(row.ProductName),
+		csvTime(row.AddedAt),
+		csvFloat(row.TargetPrice),
+		csvText(row.Notes),
+		csvText(strings.Join(row.Tags, ";")),
+		strconv.FormatBool(row.Paused),
+		csvInt(row.SourceID),
+		csvString(row.Platform),
+		csvString(row.ProductURL),
+		csvString(row.Condition),
+		csvString(row.SellerName),
+		csvFloat(row.SellerRating),
+		csvFloat(row.Price),
+		csvString(row.Currency),
+		csvFloat(row.ShippingCost),
+		csvFloat(row.EstimatedTax),
+		csvFloat(row.Discount),
+		csvBool(row.InStock),
+		csvTime(row.CheckedAt),
+	}
+}
+
+// scraped names and user notes could start like a spreadsheet formula,
+// prefix those with a quote so they open as plain text
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func csvString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return csvText(*value)
+}
+
+func csvInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func csvFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+func csvBool(value *bool) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*value)
+}
+
+func csvTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,118 @@
+//go:build unit
+
+package handler_test
+
+import (
+	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/types"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Unit tests for the ExportHandler functions
+func TestExportWatchlist(t *testing.T) {
+	price, platform, checkedAt := 299.99, "amazon", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := []types.ExportRow{
+		{ProductID: 1, ProductName: "Ryzen 7 7700", Tags: []string{"cpu", "build"}, Platform: &platform, Price: &price, CheckedAt: &checkedAt},
+		{ProductID: 2, ProductName: "=HYPERLINK(\"x\")", Tags: []string{}},
+	}
+
+	serve := func(mock *handler.MockExportStore, query string, withUser bool) *httptest.ResponseRecorder {
+		h := handler.NewExportHandler(mock)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/export"+query, nil)
+		if withUser {
+			req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+		}
+		w := httptest.NewRecorder()
+
+		h.ExportWatchlist(w, req)
+		return w
+	}
+
+	t.Run("requires a logged in user", func(t *testing.T) {
+		w := serve(&handler.MockExportStore{}, "", false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		w := serve(&handler.MockExportStore{}, "?format=xml", true)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("csv by default with a header and neutralized formulas", func(t *testing.T) {
+		w := serve(&handler.MockExportStore{Rows: rows}, "", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Regexp(t, `^attachment; filename=watchlist-\d{4}-\d{2}-\d{2}\.csv$`, w.Header().Get("Content-Disposition"))
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, "product_id", records[0][0])
+		assert.Equal(t, []string{"1", "Ryzen 7 7700"}, records[1][:2])
+		assert.Equal(t, "cpu;build", records[1][5])
+		assert.Equal(t, "amazon", records[1][8])
+		assert.Equal(t, "299.99", records[1][13])
+		assert.Equal(t, "2026-01-02T03:04:05Z", records[1][19])
+		assert.Equal(t, "'=HYPERLINK(\"x\")", records[2][1])
+		assert.Equal(t, "", records[2][13], "missing snapshot fields are empty")
+	})
+
+	t.Run("json is one array", func(t *testing.T) {
+		w := serve(&handler.MockExportStore{Rows: rows}, "?format=json", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "watchlist-")
+
+		var exported []types.ExportRow
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&exported))
+		require.Len(t, exported, 2)
+		assert.Equal(t, 299.99, *exported[0].Price)
+		assert.Nil(t, exported[1].Price)
+	})
+
+	t.Run("ndjson is one object per line", func(t *testing.T) {
+		w := serve(&handler.MockExportStore{Rows: rows}, "?format=ndjson", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		var row types.ExportRow
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &row))
+		assert.Equal(t, 2, row.ProductID)
+	})
+
+	t.Run("empty watchlists still export", func(t *testing.T) {
+		w := serve(&handler.MockExportStore{}, "?format=json", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+
+		w = serve(&handler.MockExportStore{}, "?format=csv", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Body.String(), "product_id,product_name"))
+	})
+
+	t.Run("errors before the first row answer 500", func(t *testing.T) {
+		w := serve(&handler.MockExportStore{Err: errors.New("connection reset")}, "", true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("errors mid stream abort the response", func(t *testing.T) {
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			serve(&handler.MockExportStore{Rows: rows, Err: errors.New("connection reset")}, "", true)
+		})
+	})
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"backend/internal/store"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// positive id from the path value, writes a 400 when it isn't one
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || id < 1 {
+		http.Error(w, "Invalid "+strings.ReplaceAll(name, "_", " ")+": must be a positive number", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// not found errors become a 404 with the message and forbidden errors a 403,
+// everything else is logged and mapped like the other handlers do. An empty
+// message leaves not found errors to the default mapping
+func writeStoreError(w http.ResponseWriter, r *http.Request, dbErr error, notFound string) {
+	if notFound != "" && errors.Is(dbErr, store.ErrNotFound) {
+		http.Error(w, notFound, http.StatusNotFound)
+		return
+	}
+	if errors.Is(dbErr, store.ErrForbidden) {
+		http.Error(w, "Forbidden: your role doesn't allow this", http.StatusForbidden)
+		return
+	}
+	logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+	if db.HandleDatabaseErrors(w, dbErr) {
+		return
+	}
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encodeErr := json.NewEncoder(w).Encode(body)
+	if encodeErr != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
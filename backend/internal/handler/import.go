@@ -0,0 +1,293 @@
+package handler
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/platform"
+	"backend/internal/types"
+	"backend/pkg/logger"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxImportBytes	= 1 << 20
+	maxImportRows	= 200
+)
+
+// csv headers the import understands besides name, url, target_price and
+// tags, so an export can be uploaded again
+var importColumnAliases = map[string]string{
+	"product_name": 	"name",
+	"product_url": 		"url",
+}
+
+// one parsed row of the upload with the line it came from, err is set when
+// the row couldn't be read
+type importLine struct {
+	line	int
+	row		types.ImportRow
+	err		string
+}
+
+// POST route to add many products to the logged in user's watchlist from a
+// csv (Content-Type text/csv) or json file. CSV files need a header with a
+// name or url column and may have target_price and tags (separated by ; or ,)
+// columns, json files are an array of {name, url, target_price, tags}. Rows
+// are validated one by one and the report has the outcome of every row.
+// Products already on the watchlist are updated rather than duplicated so
+// uploading the same file again is safe
+func (h *ProductHandler) ImportWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var lines []importLine
+	var err error
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		lines, err = parseImportCSV(body)
+	case "application/json", "":
+		lines, err = parseImportJSON(body)
+	default:
+		http.Error(w, "Unsupported content type: must be text/csv or application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Import file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(lines) == 0 {
+		http.Error(w, "Import file has no rows", http.StatusBadRequest)
+		return
+	}
+	if len(lines) > maxImportRows {
+		http.Error(w, fmt.Sprintf("Import file has too many rows: at most %d", maxImportRows), http.StatusBadRequest)
+		return
+	}
+
+	report := types.ImportReport{Rows: []types.ImportResult{}}
+	items := []types.ImportItem{}
+
+	for _, line := range lines {
+		item, invalid := h.importItem(line)
+		if invalid != "" {
+			report.Rows = append(report.Rows, types.ImportResult{Line: line.line, Status: types.ImportStatusInvalid, Error: invalid})
+			continue
+		}
+		items = append(items, item)
+	}
+
+	if len(items) > 0 {
+		results, dbErr := h.products.ImportWatchlist(r.Context(), user.UserId, items)
+		if dbErr != nil {
+			logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		report.Rows = append(report.Rows, results...)
+	}
+
+	slices.SortFunc(report.Rows, func(a, b types.ImportResult) int {
+		return a.Line - b.Line
+	})
+
+	for _, result := range report.Rows {
+		switch result.Status {
+		case types.ImportStatusCreated:
+			report.Created++
+		case types.ImportStatusUpdated:
+			report.Updated++
+		case types.ImportStatusUnchanged:
+			report.Unchanged++
+		case types.ImportStatusInvalid:
+			report.Invalid++
+		case types.ImportStatusFailed:
+			report.Failed++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// validate one row and turn its url into a product source, returns why the
+// row is invalid otherwise
+func (h *ProductHandler) importItem(line importLine) (types.ImportItem, string) {
+	if line.err != "" {
+		return types.ImportItem{}, line.err
+	}
+
+	row := line.row
+	row.Name = strings.TrimSpace(row.Name)
+	row.URL = strings.TrimSpace(row.URL)
+	if row.Tags != nil {
+		row.Tags = uniqueLower(row.Tags)
+	}
+
+	err := h.validate.Struct(row)
+	if err != nil {
+		return types.ImportItem{}, err.Error()
+	}
+
+	item := types.ImportItem{
+		Line: 			line.line,
+		Name: 			row.Name,
+		TargetPrice: 	row.TargetPrice,
+		Tags: 			row.Tags,
+	}
+
+	if row.URL != "" {
+		listing, err := platform.ParseURL(row.URL)
+		if err != nil {
+			return types.ImportItem{}, err.Error()
+		}
+
+		if item.Name == "" {
+			item.Name = listing.Name
+		}
+		if item.Name == "" {
+			item.Name = listing.FallbackName()
+		}
+
+		item.Source = &types.ProductSource{
+			Platform: 			listing.Platform,
+			PlatformProductID: 	listing.ProductID,
+			URL: 				listing.URL,
+		}
+	}
+
+	return item, ""
+}
+
+func parseImportJSON(body io.Reader) ([]importLine, error) {
+	var rows []types.ImportRow
+
+	err := json.NewDecoder(body).Decode(&rows)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, err
+		}
+		return nil, errors.New("invalid JSON payload: must be an array of rows")
+	}
+
+	lines := make([]importLine, 0, len(rows))
+	for i, row := range rows {
+		lines = append(lines, importLine{line: i + 1, row: row})
+	}
+
+	return lines, nil
+}
+
+func parseImportCSV(body io.Reader) ([]importLine, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if alias, ok := importColumnAliases[name]; ok {
+			name = alias
+		}
+		if _, seen := columns[name]; !seen {
+			columns[name] = i
+		}
+	}
+
+	_, hasName := columns["name"]
+	_, hasURL := columns["url"]
+	if !hasName && !hasURL {
+		return nil, errors.New("invalid CSV: header needs a name or url column")
+	}
+
+	lines := []importLine{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		cell := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return csvUnquote(strings.TrimSpace(record[i]))
+		}
+
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		lineNumber, _ := reader.FieldPos(0)
+		line := importLine{
+			line: 	lineNumber,
+			row: 	types.ImportRow{Name: cell("name"), URL: cell("url"), Tags: splitTags(cell("tags"))},
+		}
+
+		if price := cell("target_price"); price != "" {
+			targetPrice, err := strconv.ParseFloat(price, 64)
+			if err != nil || math.IsNaN(targetPrice) || math.IsInf(targetPrice, 0) {
+				line.err = "target_price must be a number"
+			} else {
+				line.row.TargetPrice = &targetPrice
+			}
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// undo the quote the export puts in front of cells that look like formulas
+func csvUnquote(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+func splitTags(value string) []string {
+	tags := []string{}
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,142 @@
+//go:build unit
+
+package handler_test
+
+import (
+	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/types"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Unit tests for the ImportWatchlist handler
+func TestImportWatchlist(t *testing.T) {
+	serve := func(mock *handler.MockProductStore, contentType, body string, withUser bool) *httptest.ResponseRecorder {
+		h := handler.NewProductHandler(mock)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/watchlist/import", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if withUser {
+			req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+		}
+		w := httptest.NewRecorder()
+
+		h.ImportWatchlist(w, req)
+		return w
+	}
+
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) types.ImportReport {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var report types.ImportReport
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return report
+	}
+
+	t.Run("requires a logged in user", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{}, "application/json", `[{"name": "Ryzen 7 7700"}]`, false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("imports csv rows and reports invalid ones", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		body := "Name,URL,Target_Price,Tags\n" +
+			"Ryzen 7 7700,,250,CPU; build\n" +
+			",https://www.amazon.com/dp/B09XS7JWHH,,\n" +
+			",,,\n" +
+			"x,,,\n" +
+			"DDR5 16GB,,cheap,\n" +
+			",https://example.com/item,,\n"
+
+		report := decode(t, serve(mock, "text/csv", body, true))
+		assert.Equal(t, 2, report.Created)
+		assert.Equal(t, 3, report.Invalid)
+		require.Len(t, report.Rows, 5, "blank lines are skipped")
+
+		assert.Equal(t, 2, report.Rows[0].Line)
+		assert.Equal(t, types.ImportStatusCreated, report.Rows[0].Status)
+		assert.Equal(t, 5, report.Rows[2].Line)
+		assert.Equal(t, types.ImportStatusInvalid, report.Rows[2].Status)
+		assert.Equal(t, "target_price must be a number", report.Rows[3].Error)
+		assert.Equal(t, 7, report.Rows[4].Line)
+
+		require.Len(t, mock.LastImportItems, 2)
+		first := mock.LastImportItems[0]
+		assert.Equal(t, "Ryzen 7 7700", first.Name)
+		assert.Equal(t, 250.0, *first.TargetPrice)
+		assert.Equal(t, []string{"cpu", "build"}, first.Tags)
+		assert.Nil(t, first.Source)
+
+		second := mock.LastImportItems[1]
+		require.NotNil(t, second.Source)
+		assert.Equal(t, "amazon", second.Source.Platform)
+		assert.Equal(t, "B09XS7JWHH", second.Source.PlatformProductID)
+		assert.NotEmpty(t, second.Name, "name falls back to one from the url")
+	})
+
+	t.Run("accepts an export csv", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		body := "product_id,product_name,target_price,tags,product_url\n" +
+			"1,'=Ryzen 7 7700,250,cpu;build,https://www.amazon.com/dp/B09XS7JWHH\n"
+
+		report := decode(t, serve(mock, "text/csv; charset=utf-8", body, true))
+		assert.Equal(t, 1, report.Created)
+		require.Len(t, mock.LastImportItems, 1)
+		assert.Equal(t, "=Ryzen 7 7700", mock.LastImportItems[0].Name)
+		require.NotNil(t, mock.LastImportItems[0].Source)
+	})
+
+	t.Run("imports json rows", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		body := `[
+			{"name": "Ryzen 7 7700", "target_price": 250, "tags": ["CPU", "cpu"]},
+			{"target_price": 10},
+			{"name": "DDR5 16GB", "target_price": -1}
+		]`
+
+		report := decode(t, serve(mock, "application/json", body, true))
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 2, report.Invalid)
+		assert.Equal(t, []int{1, 2, 3}, []int{report.Rows[0].Line, report.Rows[1].Line, report.Rows[2].Line})
+
+		require.Len(t, mock.LastImportItems, 1)
+		assert.Equal(t, []string{"cpu"}, mock.LastImportItems[0].Tags)
+	})
+
+	t.Run("rejects files it can't read", func(t *testing.T) {
+		tests := []struct {
+			name			string
+			contentType		string
+			body			string
+			wantCode		int
+		}{
+			{"invalid json", "application/json", `{"name": "x"}`, http.StatusBadRequest},
+			{"empty json", "application/json", `[]`, http.StatusBadRequest},
+			{"csv without name or url", "text/csv", "price,tags\n1,a\n", http.StatusBadRequest},
+			{"empty csv", "text/csv", "", http.StatusBadRequest},
+			{"csv header only", "text/csv", "name\n", http.StatusBadRequest},
+			{"unsupported type", "application/xml", `<rows/>`, http.StatusUnsupportedMediaType},
+			{"too many rows", "text/csv", "name\n" + strings.Repeat("Ryzen 7 7700\n", 201), http.StatusBadRequest},
+			{"too large", "text/csv", "name\n" + strings.Repeat("a", 1<<20), http.StatusRequestEntityTooLarge},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := serve(&handler.MockProductStore{}, tt.contentType, tt.body, true)
+				assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			})
+		}
+	})
+
+	t.Run("store errors answer 500", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{ImportErr: errors.New("context canceled")}, "application/json", `[{"name": "Ryzen 7 7700"}]`, true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -1,25 +1,234 @@
 package handler
 
-import "backend/internal/types"
+import (
+	"backend/internal/types"
+	"context"
+	"strings"
+)
 
 type MockProductStore struct {
 	InsertProductErr	error
+	InsertURLErr		error
 	FetchProductsErr	error
 	DeleteProductErr	error
+	FetchDetailErr		error
+	SearchProductsErr	error
+	MergeProductsErr	error
+	WatchlistErr		error
+	AccessErr			error
+
+	// returned by FetchCollectionAccess
+	Access				types.CollectionAccess
+
+	// query passed to the last FetchUserTrackedProductsPage call
+	LastListQuery		types.ProductListQuery
+	// name and source passed to the last InsertProductURLForUser call
+	LastProductName		string
+	LastSource			types.ProductSource
+	// update passed to the last UpdateWatchlistEntry call
+	LastWatchlistUpdate	types.WatchlistUpdate
+	ImportErr			error
+	// items passed to the last ImportWatchlist call
+	LastImportItems		[]types.ImportItem
+	BatchErr			error
+	// batch passed to the last BatchWatchlist call
+	LastBatch			types.WatchlistBatch
+	StatsErr			error
+	// returned by FetchProductStats
+	Stats				types.ProductStats
+	ForecastErr			error
+	// returned by FetchProductForecast
+	Forecast			types.ProductForecast
+	AlertErr			error
+	// input passed to the last InsertAlertRule call
+	LastAlertInput		types.AlertRuleInput
+}
+type MockCollectionStore struct {
+	FetchErr	error
+	InsertErr	error
+	UpdateErr	error
+	DeleteErr	error
+	ItemErr		error
+	TagsErr		error
+	MemberErr	error
+
+	// arguments of the last InsertCollection, UpdateCollection and SetCollectionItem calls
+	LastInput		types.CollectionInput
+	LastUpdate		types.CollectionUpdate
+	LastQuantity	int
+	LastInvite		types.CollectionInvite
+	LastRole		string
+}
+type MockShareStore struct {
+	InsertErr	error
+	FetchErr	error
+	RevokeErr	error
+	PublicErr	error
+
+	// input passed to the last InsertShareLink call
+	LastInput	types.ShareLinkInput
+	// returned by FetchPublicShare
+	Share		types.PublicShare
+}
+type MockExportStore struct {
+	// rows handed to the callback before Err is returned
+	Rows	[]types.ExportRow
+	Err		error
 }
 type MockUserStore struct{
 	InsertUserErr	error
 	LoginUserErr	error
+	PreferencesErr	error
+
+	// preferences passed to the last UpdateUserPreferences call
+	LastPreferences	types.UserPreferences
 }
 
-func (m *MockProductStore) InsertProductForUser(userID int, productName string) (types.Product, error) {
+func (m *MockProductStore) InsertProductForUser(ctx context.Context, userID int, productName string) (types.Product, error) {
 	return types.Product{}, m.InsertProductErr
 }
-func (m *MockProductStore) FetchUserTrackedProducts(userID int) ([]types.UserProduct, error) {
+func (m *MockProductStore) InsertProductURLForUser(ctx context.Context, userID int, productName string, source types.ProductSource) (types.Product, error) {
+	m.LastProductName = productName
+	m.LastSource = source
+	return types.Product{Name: productName, URL: source.URL}, m.InsertURLErr
+}
+func (m *MockProductStore) FetchUserTrackedProducts(ctx context.Context, userID int) ([]types.UserProduct, error) {
 	return []types.UserProduct{}, m.FetchProductsErr
 }
-func (m *MockProductStore) DeleteProductForUser(userID, productID int) error { return m.DeleteProductErr }
+func (m *MockProductStore) FetchUserTrackedProductsPage(ctx context.Context, userID int, query types.ProductListQuery) (types.ProductPage, error) {
+	m.LastListQuery = query
+	return types.ProductPage{Products: []types.UserProduct{}}, m.FetchProductsErr
+}
+func (m *MockProductStore) FetchProductDetail(ctx context.Context, userID, productID int) (types.Product, error) {
+	return types.Product{ID: productID, Prices: []types.PriceData{}, Sources: []types.ProductOffer{}, Alerts: []types.ProductAlert{}}, m.FetchDetailErr
+}
+func (m *MockProductStore) DeleteProductForUser(ctx context.Context, userID, productID int) error { return m.DeleteProductErr }
+func (m *MockProductStore) SearchProducts(ctx context.Context, search string, limit int) ([]types.ProductSearchResult, error) {
+	return []types.ProductSearchResult{}, m.SearchProductsErr
+}
+func (m *MockProductStore) UpdateWatchlistEntry(ctx context.Context, userID, productID int, update types.WatchlistUpdate) (types.WatchlistEntry, error) {
+	m.LastWatchlistUpdate = update
+	return types.WatchlistEntry{
+		ProductID: 				productID,
+		AcceptableConditions: 	[]string{},
+		PreferredPlatforms: 	[]string{},
+		ExcludedPlatforms: 		[]string{},
+		Tags: 					[]string{},
+	}, m.WatchlistErr
+}
+func (m *MockProductStore) ImportWatchlist(ctx context.Context, userID int, items []types.ImportItem) ([]types.ImportResult, error) {
+	m.LastImportItems = items
+	results := []types.ImportResult{}
+	for _, item := range items {
+		results = append(results, types.ImportResult{Line: item.Line, Status: types.ImportStatusCreated, ProductName: item.Name})
+	}
+	return results, m.ImportErr
+}
+func (m *MockProductStore) BatchWatchlist(ctx context.Context, userID int, batch types.WatchlistBatch) (types.WatchlistBatchResult, error) {
+	m.LastBatch = batch
+	result := types.WatchlistBatchResult{Mode: batch.Mode, Committed: true, Results: []types.WatchlistOperationResult{}}
+	for i, op := range batch.Operations {
+		result.Results = append(result.Results, types.WatchlistOperationResult{Index: i, Op: op.Op, Status: types.BatchStatusOK, ProductID: op.ProductID})
+		result.Succeeded++
+	}
+	return result, m.BatchErr
+}
+func (m *MockProductStore) FetchProductStats(ctx context.Context, userID, productID int) (types.ProductStats, error) {
+	return m.Stats, m.StatsErr
+}
+func (m *MockProductStore) FetchProductForecast(ctx context.Context, userID, productID int) (types.ProductForecast, error) {
+	return m.Forecast, m.ForecastErr
+}
+func (m *MockProductStore) InsertAlertRule(ctx context.Context, userID, productID int, input types.AlertRuleInput) (types.AlertRule, error) {
+	m.LastAlertInput = input
+	rule := types.AlertRule{ID: 1, Type: input.Type, PriceBasis: input.PriceBasis, Active: true}
+	if input.Threshold != nil {
+		rule.Threshold = *input.Threshold
+	}
+	if rule.PriceBasis == "" {
+		rule.PriceBasis = types.PriceBasisSticker
+	}
+	return rule, m.AlertErr
+}
+func (m *MockProductStore) DeleteAlertRule(ctx context.Context, userID, alertID int) error {
+	return m.AlertErr
+}
+func (m *MockProductStore) FetchCollectionAccess(ctx context.Context, userID, collectionID int) (types.CollectionAccess, error) {
+	return m.Access, m.AccessErr
+}
+func (m *MockProductStore) MergeProducts(ctx context.Context, duplicateID, canonicalID int) (types.ProductMerge, error) {
+	return types.ProductMerge{DuplicateID: duplicateID, CanonicalID: canonicalID}, m.MergeProductsErr
+}
 
-func (m *MockUserStore) InsertNewUser(username, email, password string) error { return m.InsertUserErr }
-func (m *MockUserStore) LoginUser(username, password string) (string, error) { return "", m.LoginUserErr }
+func (m *MockUserStore) InsertNewUser(ctx context.Context, username, email, password string) error { return m.InsertUserErr }
+func (m *MockUserStore) LoginUser(ctx context.Context, username, password string) (string, error) { return "", m.LoginUserErr }
+func (m *MockUserStore) FetchUserPreferences(ctx context.Context, userID int) (types.UserPreferences, error) {
+	return types.UserPreferences{DisplayCurrency: types.DefaultDisplayCurrency, PriceBasis: types.PriceBasisSticker}, m.PreferencesErr
+}
+func (m *MockUserStore) UpdateUserPreferences(ctx context.Context, userID int, preferences types.UserPreferences) (types.UserPreferences, error) {
+	m.LastPreferences = preferences
+	return preferences, m.PreferencesErr
+}
+
+func (m *MockCollectionStore) FetchCollections(ctx context.Context, userID int) ([]types.Collection, error) {
+	return []types.Collection{}, m.FetchErr
+}
+func (m *MockCollectionStore) FetchCollection(ctx context.Context, userID, collectionID int) (types.Collection, error) {
+	return types.Collection{ID: collectionID, Items: []types.CollectionItem{}}, m.FetchErr
+}
+func (m *MockCollectionStore) InsertCollection(ctx context.Context, userID int, input types.CollectionInput) (types.Collection, error) {
+	m.LastInput = input
+	return types.Collection{ID: 1, Name: input.Name, Description: input.Description, Items: []types.CollectionItem{}}, m.InsertErr
+}
+func (m *MockCollectionStore) UpdateCollection(ctx context.Context, userID, collectionID int, update types.CollectionUpdate) (types.Collection, error) {
+	m.LastUpdate = update
+	return types.Collection{ID: collectionID, Items: []types.CollectionItem{}}, m.UpdateErr
+}
+func (m *MockCollectionStore) DeleteCollection(ctx context.Context, userID, collectionID int) error {
+	return m.DeleteErr
+}
+func (m *MockCollectionStore) SetCollectionItem(ctx context.Context, userID, collectionID, productID, quantity int) (types.Collection, error) {
+	m.LastQuantity = quantity
+	return types.Collection{ID: collectionID, Items: []types.CollectionItem{}}, m.ItemErr
+}
+func (m *MockCollectionStore) DeleteCollectionItem(ctx context.Context, userID, collectionID, productID int) error {
+	return m.ItemErr
+}
+func (m *MockCollectionStore) FetchUserTags(ctx context.Context, userID int) ([]types.TagCount, error) {
+	return []types.TagCount{}, m.TagsErr
+}
+func (m *MockCollectionStore) AddCollectionMember(ctx context.Context, userID, collectionID int, invite types.CollectionInvite) (types.CollectionMember, error) {
+	m.LastInvite = invite
+	return types.CollectionMember{UserID: 2, Username: invite.Username, Role: invite.Role}, m.MemberErr
+}
+func (m *MockCollectionStore) UpdateCollectionMember(ctx context.Context, userID, collectionID, memberID int, role string) (types.CollectionMember, error) {
+	m.LastRole = role
+	return types.CollectionMember{UserID: memberID, Role: role}, m.MemberErr
+}
+func (m *MockCollectionStore) DeleteCollectionMember(ctx context.Context, userID, collectionID, memberID int) error {
+	return m.MemberErr
+}
+
+func (m *MockShareStore) InsertShareLink(ctx context.Context, userID int, input types.ShareLinkInput) (types.ShareLink, error) {
+	m.LastInput = input
+	return types.ShareLink{ID: 1, Token: strings.Repeat("a", 64)}, m.InsertErr
+}
+func (m *MockShareStore) FetchShareLinks(ctx context.Context, userID int) ([]types.ShareLink, error) {
+	return []types.ShareLink{}, m.FetchErr
+}
+func (m *MockShareStore) RevokeShareLink(ctx context.Context, userID, shareID int) error {
+	return m.RevokeErr
+}
+func (m *MockShareStore) FetchPublicShare(ctx context.Context, token string) (types.PublicShare, error) {
+	return m.Share, m.PublicErr
+}
 
+func (m *MockExportStore) ExportWatchlist(ctx context.Context, userID int, each func(types.ExportRow) error) error {
+	for _, row := range m.Rows {
+		err := each(row)
+		if err != nil {
+			return err
+		}
+	}
+	return m.Err
+}
@@ -2,23 +2,38 @@ package handler
 
 import (
 	"backend/pkg/db"
+	"backend/internal/middleware"
+	"backend/internal/platform"
 	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/logger"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"github.com/go-playground/validator/v10"
 )
 
+const (
+	defaultSearchLimit	= 10
+	maxSearchLimit		= 50
+)
+
 type ProductHandler struct {
 	products	store.ProductStore
 	validate 	*validator.Validate
 }
 
 func NewProductHandler(products store.ProductStore) *ProductHandler {
+	validate := validator.New()
+	validate.RegisterValidation("platform", func(fl validator.FieldLevel) bool {
+		return platform.Supported(fl.Field().String())
+	})
+
 	return &ProductHandler{
 		products: 	products,
-		validate: 	validator.New(),
+		validate: 	validate,
 	}
 }
 
@@ -41,10 +56,76 @@ func (h *ProductHandler) AddProductName(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !authorizeWatchlistUser(w, r, payload.UserId) {
+		return
+	}
+
+	product, dbErr := h.products.InsertProductForUser(r.Context(), payload.UserId, payload.ProductName)
+	if dbErr != nil {
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+		if db.HandleDatabaseErrors(w, dbErr) {
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeErr := json.NewEncoder(w).Encode(product)
+	if encodeErr != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// POST route to add a product for a user from an Amazon, eBay, Newegg or Best Buy
+// url, the listing becomes a source of the product so it can be scraped right away.
+// product_name is optional and defaults to a name guessed from the url
+func (h *ProductHandler) AddProductURL(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		UserId		int `json:"user_id" validate:"required,gte=0"`
+		URL 		string `json:"url" validate:"required,max=2048"`
+		ProductName string `json:"product_name" validate:"omitempty,min=2"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	listing, err := platform.ParseURL(payload.URL)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	productName := payload.ProductName
+	if productName == "" {
+		productName = listing.Name
+	}
+	if productName == "" {
+		productName = listing.FallbackName()
+	}
 
-	product, dbErr := h.products.InsertProductForUser(payload.UserId, payload.ProductName)
+	source := types.ProductSource{
+		Platform: 			listing.Platform,
+		PlatformProductID: 	listing.ProductID,
+		URL: 				listing.URL,
+	}
+
+	if !authorizeWatchlistUser(w, r, payload.UserId) {
+		return
+	}
+
+	product, dbErr := h.products.InsertProductURLForUser(r.Context(), payload.UserId, productName, source)
 	if dbErr != nil {
-		log.Println(dbErr)
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
 		if db.HandleDatabaseErrors(w, dbErr) {
 			return
 		}
@@ -60,7 +141,11 @@ func (h *ProductHandler) AddProductName(w http.ResponseWriter, r *http.Request)
 }
 
 // GET route to fetch a list of the user's tracked products with product metadata like
-// name, lowest price, lowest source, available from the database
+// name, lowest price, lowest source, available from the database. Supports sort, order,
+// limit and cursor params plus in_stock, source, min_price, max_price, q, collection
+// and tag filters (products need every tag). Other users can only list the products
+// of a collection shared with them, the total count and next page cursor are returned in the X-Total-Count and
+// X-Next-Cursor headers
 func (h *ProductHandler) GetUserTrackedProducts(w http.ResponseWriter, r *http.Request) {
 	user_id := r.PathValue("id")
 	if user_id == "" {
@@ -74,9 +159,40 @@ func (h *ProductHandler) GetUserTrackedProducts(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	productList, dbErr := h.products.FetchUserTrackedProducts(userID)
+	query, err := parseProductListQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	// members of a shared collection can list the owner's products in it
+	if user.UserId != userID {
+		if query.CollectionID == nil {
+			http.Error(w, "Forbidden: can't access another user's watchlist", http.StatusForbidden)
+			return
+		}
+
+		access, dbErr := h.products.FetchCollectionAccess(r.Context(), user.UserId, *query.CollectionID)
+		if dbErr != nil && !errors.Is(dbErr, store.ErrNotFound) {
+			logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if dbErr != nil || access.OwnerID != userID {
+			http.Error(w, "Forbidden: can't access another user's watchlist", http.StatusForbidden)
+			return
+		}
+	}
+
+	page, dbErr := h.products.FetchUserTrackedProductsPage(r.Context(), userID, query)
 	if dbErr != nil {
-		log.Println(dbErr)
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
 		if db.HandleDatabaseErrors(w, dbErr) {
 			return
 		}
@@ -84,8 +200,140 @@ func (h *ProductHandler) GetUserTrackedProducts(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.TotalCount))
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	encodeErr := json.NewEncoder(w).Encode(productList)
+	encodeErr := json.NewEncoder(w).Encode(page.Products)
+	if encodeErr != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// GET route to fetch one product with its sources and their latest prices,
+// price stats and the logged in user's alert rules for it
+func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || productID < 1 {
+		http.Error(w, "Invalid product id: must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	product, dbErr := h.products.FetchProductDetail(r.Context(), user.UserId, productID)
+	if dbErr != nil {
+		if errors.Is(dbErr, store.ErrNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+		if db.HandleDatabaseErrors(w, dbErr) {
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeErr := json.NewEncoder(w).Encode(product)
+	if encodeErr != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// GET route for the price statistics of a product across its sources and per
+// source: all time low and high with when they were seen, 7, 30 and 90 day
+// average, median and standard deviation, and the current price against the
+// 30 day average in percent. Stats are on the user's price basis in their
+// display currency and as fresh as the last run of the stats job
+func (h *ProductHandler) GetProductStats(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || productID < 1 {
+		http.Error(w, "Invalid product id: must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	stats, dbErr := h.products.FetchProductStats(r.Context(), user.UserId, productID)
+	if dbErr != nil {
+		if errors.Is(dbErr, store.ErrNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+		if db.HandleDatabaseErrors(w, dbErr) {
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeErr := json.NewEncoder(w).Encode(stats)
+	if encodeErr != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// GET route for the sub resources of a product, /api/v1/products/{id}/stats
+// and /api/v1/products/{id}/forecast. It is mounted as one catch all pattern
+// since products/{id}/stats on its own would conflict with
+// products/get/{id...} on the mux
+func (h *ProductHandler) GetProductResource(w http.ResponseWriter, r *http.Request) {
+	switch r.PathValue("resource") {
+	case "stats":
+		h.GetProductStats(w, r)
+	case "forecast":
+		h.GetProductForecast(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GET route for the 30 day price forecast of a product with daily confidence
+// bands on the user's price basis in their display currency, and the user's
+// predicted_below alerts with the first day each is forecast to trigger
+func (h *ProductHandler) GetProductForecast(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || productID < 1 {
+		http.Error(w, "Invalid product id: must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	forecast, dbErr := h.products.FetchProductForecast(r.Context(), user.UserId, productID)
+	if dbErr != nil {
+		if errors.Is(dbErr, store.ErrNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+		if db.HandleDatabaseErrors(w, dbErr) {
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeErr := json.NewEncoder(w).Encode(forecast)
 	if encodeErr != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
@@ -112,11 +360,14 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return 
 	}
 
+	if !authorizeWatchlistUser(w, r, payload.UserId) {
+		return
+	}
 
-	dbErr := h.products.DeleteProductForUser(payload.UserId, payload.ProductID)
+	dbErr := h.products.DeleteProductForUser(r.Context(), payload.UserId, payload.ProductID)
 	if dbErr != nil {
-		if dbErr.Error() == "product not found in user's watchlist" {
-			http.Error(w, dbErr.Error(), http.StatusNotFound)
+		if errors.Is(dbErr, store.ErrNotFound) {
+			http.Error(w, "product not found in user's watchlist", http.StatusNotFound)
 		} else {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
@@ -128,4 +379,96 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	if encodeErr != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
-}
\ No newline at end of file
+}
+
+// GET route to search the product catalog by name with the q param so users
+// can pick an existing product before adding a new one, optional limit param
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	search := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(search) < 2 || len(search) > maxSearchLength {
+		http.Error(w, "q must be between 2 and 100 characters", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			http.Error(w, "Invalid limit: must be between 1 and 50", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	results, dbErr := h.products.SearchProducts(r.Context(), search, limit)
+	if dbErr != nil {
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+		if db.HandleDatabaseErrors(w, dbErr) {
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeErr := json.NewEncoder(w).Encode(results)
+	if encodeErr != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// POST admin route to merge a duplicate product into the canonical one,
+// the duplicate's watchers and sources move over and its name becomes an alias
+func (h *ProductHandler) MergeProducts(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		DuplicateID	int `json:"duplicate_id" validate:"required,gt=0"`
+		CanonicalID	int `json:"canonical_id" validate:"required,gt=0,nefield=DuplicateID"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	merge, dbErr := h.products.MergeProducts(r.Context(), payload.DuplicateID, payload.CanonicalID)
+	if dbErr != nil {
+		if errors.Is(dbErr, store.ErrNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+		if db.HandleDatabaseErrors(w, dbErr) {
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeErr := json.NewEncoder(w).Encode(merge)
+	if encodeErr != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// the user_id of a request has to be the logged in user, writes a 401 or 403
+// and returns false when it isn't
+func authorizeWatchlistUser(w http.ResponseWriter, r *http.Request, userID int) bool {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return false
+	}
+	if user.UserId != userID {
+		http.Error(w, "Forbidden: can't change another user's watchlist", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -5,6 +5,7 @@ package handler_test
 import (
 	"backend/internal/db"
 	"backend/internal/handler"
+	"backend/internal/middleware"
 	"backend/pkg/test"
 	"bytes"
 	"encoding/json"
@@ -31,6 +32,11 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// request carrying the logged in user like AuthMiddleware would set it
+func asUser(req *http.Request, userID int) *http.Request {
+	return req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: userID, Username: "username1"}))
+}
+
 // Integration tests for InsertProductForUser route handler
 func TestAddProductNameHandlerIntegration(t *testing.T) {
 	pool := testDB.Pool
@@ -52,7 +58,7 @@ func TestAddProductNameHandlerIntegration(t *testing.T) {
 
 		productRepo := db.NewRepository(pool)
 		h := handler.NewProductHandler(productRepo)
-		h.AddProductName(w, req)
+		h.AddProductName(w, asUser(req, userID))
 
 		assert.Equal(t, http.StatusOK, w.Code, "Success should return 200")
 
@@ -90,7 +96,7 @@ func TestGetUserTrackedProductsIntegration(t *testing.T) {
 
 		productRepo := db.NewRepository(pool)
 		h := handler.NewProductHandler(productRepo)
-		h.GetUserTrackedProducts(w, req)
+		h.GetUserTrackedProducts(w, asUser(req, userID))
 
 		assert.Equal(t, http.StatusOK, w.Code, "Success should return 200")
 
@@ -137,7 +143,7 @@ func TestDeleteProductrIntegration(t *testing.T) {
 
 		productRepo := db.NewRepository(pool)
 		h := handler.NewProductHandler(productRepo)
-		h.DeleteProduct(w, req)
+		h.DeleteProduct(w, asUser(req, userID))
 
 		assert.Equal(t, http.StatusOK, w.Code, "Success should return 200")
 
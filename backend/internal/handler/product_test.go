@@ -4,14 +4,21 @@ package handler_test
 
 import (
 	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"backend/internal/types"
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Unit tests for the AddProductName Handler function
@@ -67,7 +74,7 @@ func TestAddProductNameHandler(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 
-		mockHandler.AddProductName(w, req)
+		mockHandler.AddProductName(w, withUser(req))
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code, "It should return http status 500")
 	})
@@ -111,7 +118,7 @@ func TestGetUserTrackedProductsHandler(t *testing.T) {
 		mock := &handler.MockProductStore{FetchProductsErr: errors.New("db error")}
 		mockHandler := handler.NewProductHandler(mock)
 
-		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/get/2", nil)
+		req := withUser(httptest.NewRequest(http.MethodGet, "/api/v1/products/get/1", nil))
 		w := httptest.NewRecorder()
 
 		mux := http.NewServeMux()
@@ -122,6 +129,181 @@ func TestGetUserTrackedProductsHandler(t *testing.T) {
 	})
 }
 
+// Unit tests for the sort, filter and paging params of GetUserTrackedProducts
+func TestGetUserTrackedProductsQueryParams(t *testing.T) {
+	serve := func(mock *handler.MockProductStore, rawQuery string) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/products/get/{id...}", handler.NewProductHandler(mock).GetUserTrackedProducts)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/products/get/1?"+rawQuery, nil)))
+		return w
+	}
+
+	t.Run("defaults to newest first", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serve(mock, "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, types.SortByAddedAt, mock.LastListQuery.Sort)
+		assert.Equal(t, types.SortDesc, mock.LastListQuery.Order)
+		assert.Equal(t, 50, mock.LastListQuery.Limit)
+		assert.Nil(t, mock.LastListQuery.Cursor)
+		assert.Equal(t, "0", w.Header().Get("X-Total-Count"))
+	})
+
+	t.Run("parses sort and filters", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serve(mock, "sort=price&limit=10&in_stock=true&source=amazon,newegg&source=ebay&min_price=10&max_price=99.5&q=+rtx+")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		query := mock.LastListQuery
+		assert.Equal(t, types.SortByPrice, query.Sort)
+		assert.Equal(t, types.SortAsc, query.Order, "price sorts cheapest first by default")
+		assert.Equal(t, 10, query.Limit)
+		assert.True(t, *query.InStock)
+		assert.Equal(t, []string{"amazon", "newegg", "ebay"}, query.Sources)
+		assert.Equal(t, 10.0, *query.MinPrice)
+		assert.Equal(t, 99.5, *query.MaxPrice)
+		assert.Equal(t, "rtx", query.Search)
+	})
+
+	t.Run("deal score sorts best deals first", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serve(mock, "sort=deal_score")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, types.SortByDealScore, mock.LastListQuery.Sort)
+		assert.Equal(t, types.SortDesc, mock.LastListQuery.Order)
+	})
+
+	t.Run("parses collection and tag filters", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serve(mock, "collection=3&tag=GPU,+homelab&tag=deals")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, mock.LastListQuery.CollectionID)
+		assert.Equal(t, 3, *mock.LastListQuery.CollectionID)
+		assert.Equal(t, []string{"gpu", "homelab", "deals"}, mock.LastListQuery.Tags)
+	})
+
+	t.Run("accepts a cursor for the same ordering", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		cursor := types.ProductCursor{Sort: types.SortByName, Order: types.SortAsc, Value: "gpu", ProductID: 4}.Encode()
+		w := serve(mock, "sort=name&cursor="+cursor)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, mock.LastListQuery.Cursor)
+		assert.Equal(t, 4, mock.LastListQuery.Cursor.ProductID)
+		assert.Equal(t, "gpu", mock.LastListQuery.Cursor.Value)
+	})
+
+	t.Run("tampered cursor values return 400", func(t *testing.T) {
+		cursors := []types.ProductCursor{
+			{Sort: types.SortByPrice, Order: types.SortAsc, Value: "1; DROP TABLE products", ProductID: 4},
+			{Sort: types.SortByPrice, Order: types.SortAsc, Value: "NaN", ProductID: 4},
+			{Sort: types.SortByAddedAt, Order: types.SortDesc, Value: "yesterday", ProductID: 4},
+		}
+
+		for _, cursor := range cursors {
+			mock := &handler.MockProductStore{}
+			w := serve(mock, "sort="+cursor.Sort+"&order="+cursor.Order+"&cursor="+cursor.Encode())
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, cursor.Value)
+			assert.Contains(t, w.Body.String(), "invalid cursor", cursor.Value)
+			assert.Nil(t, mock.LastListQuery.Cursor, "the store is never called")
+		}
+	})
+
+	t.Run("invalid params return 400", func(t *testing.T) {
+		priceCursor := types.ProductCursor{Sort: types.SortByPrice, Order: types.SortAsc, Value: "1", ProductID: 4}.Encode()
+		invalidQueries := []string{
+			"sort=popularity",
+			"order=sideways",
+			"limit=0",
+			"limit=101",
+			"cursor=not-a-cursor",
+			"sort=name&cursor=" + priceCursor,
+			"in_stock=maybe",
+			"min_price=-1",
+			"min_price=50&max_price=10",
+			"q=" + strings.Repeat("a", 101),
+			"collection=abc",
+			"collection=0",
+		}
+
+		for _, rawQuery := range invalidQueries {
+			w := serve(&handler.MockProductStore{}, rawQuery)
+			assert.Equal(t, http.StatusBadRequest, w.Code, rawQuery)
+		}
+	})
+}
+
+// Unit tests for the session checks of the user_id based product routes
+func TestWatchlistAuthorization(t *testing.T) {
+	list := func(mock *handler.MockProductStore, path string, withSession bool) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/products/get/{id...}", handler.NewProductHandler(mock).GetUserTrackedProducts)
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if withSession {
+			req = withUser(req)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("missing session returns 401", func(t *testing.T) {
+		w := list(&handler.MockProductStore{}, "/api/v1/products/get/1", false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/products/add/name", strings.NewReader(`{"user_id": 1, "product_name": "gpu"}`))
+		w = httptest.NewRecorder()
+		handler.NewProductHandler(&handler.MockProductStore{}).AddProductName(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("changing another user's watchlist returns 403", func(t *testing.T) {
+		h := handler.NewProductHandler(&handler.MockProductStore{})
+
+		w := httptest.NewRecorder()
+		h.AddProductName(w, withUser(httptest.NewRequest(http.MethodPost, "/api/v1/products/add/name", strings.NewReader(`{"user_id": 2, "product_name": "gpu"}`))))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = httptest.NewRecorder()
+		h.AddProductURL(w, withUser(httptest.NewRequest(http.MethodPost, "/api/v1/products/add/url", strings.NewReader(`{"user_id": 2, "url": "https://www.amazon.com/dp/B09XS7JWHH"}`))))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = httptest.NewRecorder()
+		h.DeleteProduct(w, withUser(httptest.NewRequest(http.MethodDelete, "/api/v1/products/delete", strings.NewReader(`{"user_id": 2, "product_id": 3}`))))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("other users' lists need a collection shared by them", func(t *testing.T) {
+		w := list(&handler.MockProductStore{}, "/api/v1/products/get/2", true)
+		assert.Equal(t, http.StatusForbidden, w.Code, "no collection")
+
+		mock := &handler.MockProductStore{AccessErr: fmt.Errorf("collection 5: %w", store.ErrNotFound)}
+		w = list(mock, "/api/v1/products/get/2?collection=5", true)
+		assert.Equal(t, http.StatusForbidden, w.Code, "not a member")
+
+		mock = &handler.MockProductStore{Access: types.CollectionAccess{CollectionID: 5, OwnerID: 3, Role: types.CollectionRoleViewer}}
+		w = list(mock, "/api/v1/products/get/2?collection=5", true)
+		assert.Equal(t, http.StatusForbidden, w.Code, "collection of a different owner")
+
+		mock = &handler.MockProductStore{Access: types.CollectionAccess{CollectionID: 5, OwnerID: 2, Role: types.CollectionRoleViewer}}
+		w = list(mock, "/api/v1/products/get/2?collection=5", true)
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, mock.LastListQuery.CollectionID)
+		assert.Equal(t, 5, *mock.LastListQuery.CollectionID)
+
+		mock = &handler.MockProductStore{AccessErr: errors.New("db error")}
+		w = list(mock, "/api/v1/products/get/2?collection=5", true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
 // Unit tests for the DeleteProductHandler function
 func TestDeleteProductHandler(t *testing.T) {
 	t.Run("Empty request body", func(t *testing.T) {
@@ -164,7 +346,7 @@ func TestDeleteProductHandler(t *testing.T) {
 	})
 
 	t.Run("item to be deleted isnt found returns 404", func(t *testing.T) {
-		mock := &handler.MockProductStore{DeleteProductErr: errors.New("product not found in user's watchlist")}
+		mock := &handler.MockProductStore{DeleteProductErr: fmt.Errorf("product 2 not found in user's watchlist: %w", store.ErrNotFound)}
 		mockHandler := handler.NewProductHandler(mock)
 
 		payload := map[string]interface{}{"user_id": 1, "product_id": 2}
@@ -174,7 +356,7 @@ func TestDeleteProductHandler(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 
-		mockHandler.DeleteProduct(w, req)
+		mockHandler.DeleteProduct(w, withUser(req))
 
 		assert.Equal(t, http.StatusNotFound, w.Code, "It should return http status 404")
 	})
@@ -190,9 +372,364 @@ func TestDeleteProductHandler(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 
-		mockHandler.DeleteProduct(w, req)
+		mockHandler.DeleteProduct(w, withUser(req))
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code, "It should return http status 500")
 	})
 }
 
+// Unit tests for the SearchProducts Handler function
+func TestSearchProductsHandler(t *testing.T) {
+	t.Run("missing or too short query returns 400", func(t *testing.T) {
+		for _, rawQuery := range []string{"", "q=", "q=a", "q=++a++", "q=gpu&limit=0", "q=gpu&limit=51", "q=gpu&limit=ten"} {
+			mockHandler := handler.NewProductHandler(&handler.MockProductStore{})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/products/search?"+rawQuery, nil)
+			w := httptest.NewRecorder()
+
+			mockHandler.SearchProducts(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, rawQuery)
+		}
+	})
+
+	t.Run("returns a json list", func(t *testing.T) {
+		mockHandler := handler.NewProductHandler(&handler.MockProductStore{})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/search?q=rtx+4090", nil)
+		w := httptest.NewRecorder()
+
+		mockHandler.SearchProducts(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, "[]", w.Body.String())
+	})
+
+	t.Run("db error returns 500", func(t *testing.T) {
+		mock := &handler.MockProductStore{SearchProductsErr: errors.New("db error")}
+		mockHandler := handler.NewProductHandler(mock)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/search?q=gpu", nil)
+		w := httptest.NewRecorder()
+
+		mockHandler.SearchProducts(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code, "It should return http status 500")
+	})
+}
+
+// Unit tests for the AddProductURL Handler function
+func TestAddProductURLHandler(t *testing.T) {
+	t.Run("invalid payloads and unsupported urls return 400", func(t *testing.T) {
+		payloads := []string{
+			``,
+			`{"user_id": 1}`,
+			`{"user_id": 1, "url": "https://www.walmart.com/ip/123456"}`,
+			`{"user_id": 1, "url": "https://www.amazon.com/s?k=headphones"}`,
+			`{"user_id": 1, "url": "https://amzn.to/3xYz"}`,
+			`{"user_id": 1, "url": "https://www.amazon.com/dp/B09XS7JWHH", "product_name": "a"}`,
+		}
+
+		for _, payload := range payloads {
+			mockHandler := handler.NewProductHandler(&handler.MockProductStore{})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/products/add/url", strings.NewReader(payload))
+			w := httptest.NewRecorder()
+
+			mockHandler.AddProductURL(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+		}
+	})
+
+	t.Run("stores the canonical url and guessed name", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		mockHandler := handler.NewProductHandler(mock)
+
+		payload := `{"user_id": 1, "url": "https://www.bestbuy.com/site/apple-airpods-pro-2nd-generation/6447382.p?skuId=6447382&utm_source=feed"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/products/add/url", strings.NewReader(payload))
+		w := httptest.NewRecorder()
+
+		mockHandler.AddProductURL(w, withUser(req))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "apple airpods pro 2nd generation", mock.LastProductName)
+		assert.Equal(t, types.ProductSource{
+			Platform: 			"bestbuy",
+			PlatformProductID: 	"6447382",
+			URL: 				"https://www.bestbuy.com/site/6447382.p?skuId=6447382",
+		}, mock.LastSource)
+
+		var product types.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, "https://www.bestbuy.com/site/6447382.p?skuId=6447382", product.URL)
+	})
+
+	t.Run("prefers the given product name and falls back to the platform id", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		mockHandler := handler.NewProductHandler(mock)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/products/add/url",
+			strings.NewReader(`{"user_id": 1, "url": "https://www.amazon.com/dp/B09XS7JWHH", "product_name": "Sony XM5"}`))
+		mockHandler.AddProductURL(httptest.NewRecorder(), withUser(req))
+		assert.Equal(t, "Sony XM5", mock.LastProductName)
+
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/products/add/url",
+			strings.NewReader(`{"user_id": 1, "url": "https://www.amazon.com/dp/B09XS7JWHH"}`))
+		mockHandler.AddProductURL(httptest.NewRecorder(), withUser(req))
+		assert.Equal(t, "Amazon B09XS7JWHH", mock.LastProductName)
+	})
+
+	t.Run("db error returns 500", func(t *testing.T) {
+		mock := &handler.MockProductStore{InsertURLErr: errors.New("db error")}
+		mockHandler := handler.NewProductHandler(mock)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/products/add/url",
+			strings.NewReader(`{"user_id": 1, "url": "https://www.ebay.com/itm/256348591234"}`))
+		w := httptest.NewRecorder()
+
+		mockHandler.AddProductURL(w, withUser(req))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+// Unit tests for the GetProduct Handler function
+func TestGetProductHandler(t *testing.T) {
+	serve := func(mock *handler.MockProductStore, path string, withUser bool) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/products/{id}", handler.NewProductHandler(mock).GetProduct)
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if withUser {
+			req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("invalid product id returns 400", func(t *testing.T) {
+		for _, path := range []string{"/api/v1/products/abc", "/api/v1/products/0", "/api/v1/products/-3"} {
+			w := serve(&handler.MockProductStore{}, path, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		}
+	})
+
+	t.Run("missing user returns 401", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{}, "/api/v1/products/4", false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("returns the product", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{}, "/api/v1/products/4", true)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var product types.Product
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+		assert.Equal(t, 4, product.ID)
+		assert.NotNil(t, product.Sources)
+		assert.NotNil(t, product.Alerts)
+	})
+
+	t.Run("missing product returns 404", func(t *testing.T) {
+		mock := &handler.MockProductStore{FetchDetailErr: fmt.Errorf("product 4: %w", store.ErrNotFound)}
+		w := serve(mock, "/api/v1/products/4", true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("db error returns 500", func(t *testing.T) {
+		mock := &handler.MockProductStore{FetchDetailErr: errors.New("db error")}
+		w := serve(mock, "/api/v1/products/4", true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+// Unit tests for the GetProductStats Handler function
+func TestGetProductStatsHandler(t *testing.T) {
+	serve := func(mock *handler.MockProductStore, path string, withUser bool) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/products/{id}/{resource...}", handler.NewProductHandler(mock).GetProductResource)
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if withUser {
+			req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("invalid product id returns 400", func(t *testing.T) {
+		for _, path := range []string{"/api/v1/products/abc/stats", "/api/v1/products/0/stats"} {
+			w := serve(&handler.MockProductStore{}, path, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		}
+	})
+
+	t.Run("missing user returns 401", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{}, "/api/v1/products/4/stats", false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("returns the stats", func(t *testing.T) {
+		low := 199.99
+		mock := &handler.MockProductStore{Stats: types.ProductStats{
+			ProductID: 	4,
+			Overall: 	&types.PriceStats{Currency: "USD", AllTimeLow: &low},
+			Sources: 	[]types.PriceStats{},
+		}}
+		w := serve(mock, "/api/v1/products/4/stats", true)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var stats types.ProductStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		require.NotNil(t, stats.Overall)
+		assert.Equal(t, 199.99, *stats.Overall.AllTimeLow)
+		assert.Nil(t, stats.Overall.Avg7d)
+	})
+
+	t.Run("store errors map to 404 and 500", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{StatsErr: fmt.Errorf("product 4: %w", store.ErrNotFound)}, "/api/v1/products/4/stats", true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serve(&handler.MockProductStore{StatsErr: errors.New("db error")}, "/api/v1/products/4/stats", true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+// Unit tests for the GetProductForecast Handler function
+func TestGetProductForecastHandler(t *testing.T) {
+	serve := func(mock *handler.MockProductStore, path string, withUser bool) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/products/{id}/{resource...}", handler.NewProductHandler(mock).GetProductResource)
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if withUser {
+			req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("invalid product id returns 400", func(t *testing.T) {
+		for _, path := range []string{"/api/v1/products/abc/forecast", "/api/v1/products/0/forecast"} {
+			w := serve(&handler.MockProductStore{}, path, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		}
+	})
+
+	t.Run("missing user returns 401", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{}, "/api/v1/products/4/forecast", false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("unknown product resource returns 404", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{}, "/api/v1/products/4/history", true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("returns the forecast", func(t *testing.T) {
+		predictedAt := time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+		predictedPrice := 89.5
+		mock := &handler.MockProductStore{Forecast: types.ProductForecast{
+			ProductID: 		4,
+			Currency: 		"USD",
+			Method: 		"holt",
+			HistoryDays: 	10,
+			Points: 		[]types.ForecastPoint{{Date: predictedAt, Price: 89.5, Lower: 80, Upper: 99}},
+			Alerts: 		[]types.ForecastAlert{{
+				AlertRule: 		types.AlertRule{ID: 2, Type: types.AlertPredictedBelow, Threshold: 90, Active: true},
+				PredictedAt: 	&predictedAt,
+				PredictedPrice: &predictedPrice,
+			}},
+		}}
+		w := serve(mock, "/api/v1/products/4/forecast", true)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var forecast types.ProductForecast
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &forecast))
+		require.Len(t, forecast.Points, 1)
+		assert.Equal(t, 80.0, forecast.Points[0].Lower)
+		require.Len(t, forecast.Alerts, 1)
+		assert.Equal(t, types.AlertPredictedBelow, forecast.Alerts[0].Type)
+		assert.Equal(t, predictedAt, *forecast.Alerts[0].PredictedAt)
+	})
+
+	t.Run("store errors map to 404 and 500", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{ForecastErr: fmt.Errorf("product 4: %w", store.ErrNotFound)}, "/api/v1/products/4/forecast", true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serve(&handler.MockProductStore{ForecastErr: errors.New("db error")}, "/api/v1/products/4/forecast", true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+// Unit tests for the MergeProducts Handler function
+func TestMergeProductsHandler(t *testing.T) {
+	t.Run("invalid payloads return 400", func(t *testing.T) {
+		payloads := []string{
+			``,
+			`{"duplicate_id": 2}`,
+			`{"canonical_id": 1}`,
+			`{"duplicate_id": 2, "canonical_id": 2}`,
+			`{"duplicate_id": -1, "canonical_id": 2}`,
+		}
+
+		for _, payload := range payloads {
+			mockHandler := handler.NewProductHandler(&handler.MockProductStore{})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/products/merge", strings.NewReader(payload))
+			w := httptest.NewRecorder()
+
+			mockHandler.MergeProducts(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+		}
+	})
+
+	t.Run("returns the merge summary", func(t *testing.T) {
+		mockHandler := handler.NewProductHandler(&handler.MockProductStore{})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/products/merge", strings.NewReader(`{"duplicate_id": 2, "canonical_id": 1}`))
+		w := httptest.NewRecorder()
+
+		mockHandler.MergeProducts(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var merge types.ProductMerge
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &merge))
+		assert.Equal(t, 2, merge.DuplicateID)
+		assert.Equal(t, 1, merge.CanonicalID)
+	})
+
+	t.Run("missing product returns 404", func(t *testing.T) {
+		mock := &handler.MockProductStore{MergeProductsErr: fmt.Errorf("merging: %w", store.ErrNotFound)}
+		mockHandler := handler.NewProductHandler(mock)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/products/merge", strings.NewReader(`{"duplicate_id": 2, "canonical_id": 1}`))
+		w := httptest.NewRecorder()
+
+		mockHandler.MergeProducts(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("db error returns 500", func(t *testing.T) {
+		mock := &handler.MockProductStore{MergeProductsErr: errors.New("db error")}
+		mockHandler := handler.NewProductHandler(mock)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/products/merge", strings.NewReader(`{"duplicate_id": 2, "canonical_id": 1}`))
+		w := httptest.NewRecorder()
+
+		mockHandler.MergeProducts(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
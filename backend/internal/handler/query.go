@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"backend/internal/types"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize		= 100
+	maxSearchLength	= 100
+)
+
+// default direction for each sort, newest and cheapest first
+var defaultSortOrder = map[string]string{
+	types.SortByAddedAt: 		types.SortDesc,
+	types.SortByPrice: 			types.SortAsc,
+	types.SortByName: 			types.SortAsc,
+	types.SortByLastChecked: 	types.SortDesc,
+	types.SortByBiggestDrop: 	types.SortDesc,
+	types.SortByDealScore: 		types.SortDesc,
+}
+
+// Parse the sort, filter and paging query params for the tracked products list
+func parseProductListQuery(values url.Values) (types.ProductListQuery, error) {
+	query := types.ProductListQuery{
+		Sort: 	types.SortByAddedAt,
+		Limit: 	defaultPageSize,
+		Search: strings.TrimSpace(values.Get("q")),
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		if _, ok := defaultSortOrder[sort]; !ok {
+			return query, fmt.Errorf("invalid sort %q", sort)
+		}
+		query.Sort = sort
+	}
+
+	query.Order = defaultSortOrder[query.Sort]
+	if order := values.Get("order"); order != "" {
+		if order != types.SortAsc && order != types.SortDesc {
+			return query, fmt.Errorf("invalid order %q, expected asc or desc", order)
+		}
+		query.Order = order
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			return query, fmt.Errorf("invalid limit, must be between 1 and %d", maxPageSize)
+		}
+		query.Limit = parsed
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		decoded, err := types.DecodeProductCursor(cursor)
+		if err != nil {
+			return query, err
+		}
+		// a cursor only makes sense for the ordering it was created with
+		if decoded.Sort != query.Sort || decoded.Order != query.Order {
+			return query, errors.New("cursor does not match sort and order")
+		}
+		query.Cursor = decoded
+	}
+
+	if inStock := values.Get("in_stock"); inStock != "" {
+		parsed, err := strconv.ParseBool(inStock)
+		if err != nil {
+			return query, errors.New("invalid in_stock, must be true or false")
+		}
+		query.InStock = &parsed
+	}
+
+	for _, source := range values["source"] {
+		for _, platform := range strings.Split(source, ",") {
+			platform = strings.TrimSpace(platform)
+			if platform != "" {
+				query.Sources = append(query.Sources, platform)
+			}
+		}
+	}
+
+	if collection := values.Get("collection"); collection != "" {
+		collectionID, err := strconv.Atoi(collection)
+		if err != nil || collectionID < 1 {
+			return query, errors.New("invalid collection, must be a positive number")
+		}
+		query.CollectionID = &collectionID
+	}
+
+	for _, tags := range values["tag"] {
+		for _, tag := range strings.Split(tags, ",") {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag != "" {
+				query.Tags = append(query.Tags, tag)
+			}
+		}
+	}
+
+	var err error
+	query.MinPrice, err = parsePrice(values, "min_price")
+	if err != nil {
+		return query, err
+	}
+	query.MaxPrice, err = parsePrice(values, "max_price")
+	if err != nil {
+		return query, err
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return query, errors.New("min_price can't be greater than max_price")
+	}
+
+	if len(query.Search) > maxSearchLength {
+		return query, fmt.Errorf("search can be at most %d characters", maxSearchLength)
+	}
+
+	return query, nil
+}
+
+func parsePrice(values url.Values, name string) (*float64, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	price, err := strconv.ParseFloat(raw, 64)
+	if err != nil || price < 0 {
+		return nil, fmt.Errorf("invalid %s, must be a non negative number", name)
+	}
+
+	return &price, nil
+}
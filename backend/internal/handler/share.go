@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"backend/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// share tokens are 32 random bytes in hex
+var shareTokenPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type ShareHandler struct {
+	shares		store.ShareStore
+	validate	*validator.Validate
+}
+
+func NewShareHandler(shares store.ShareStore) *ShareHandler {
+	return &ShareHandler{
+		shares: 	shares,
+		validate: 	validator.New(),
+	}
+}
+
+// POST route to create a public read only link to a collection the logged in
+// user owns or to the price history of a product on their watchlist. Takes
+// collection_id or product_id and an optional expires_in_hours
+func (h *ShareHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	var payload types.ShareLinkInput
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link, dbErr := h.shares.InsertShareLink(r.Context(), user.UserId, payload)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Collection or product in user's watchlist not found")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, link)
+}
+
+// GET route to list the logged in user's share links that still work
+func (h *ShareHandler) GetShareLinks(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	links, dbErr := h.shares.FetchShareLinks(r.Context(), user.UserId)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, links)
+}
+
+// DELETE route to revoke one of the logged in user's share links
+func (h *ShareHandler) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	shareID, ok := pathID(w, r, "share_id")
+	if !ok {
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	dbErr := h.shares.RevokeShareLink(r.Context(), user.UserId, shareID)
+	if dbErr != nil {
+		writeStoreError(w, r, dbErr, "Share link not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET route without login returning the read only view of a share token,
+// unknown, revoked and expired tokens all answer 404. Responses aren't cached
+// so a revoked link stops working right away
+func (h *ShareHandler) GetPublicShare(w http.ResponseWriter, r *http.Request) {
+	share, ok := h.fetchPublicShare(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, share)
+}
+
+// GET route without login rendering the share as a small html page meant to
+// be embedded in an iframe on other sites
+func (h *ShareHandler) GetPublicShareEmbed(w http.ResponseWriter, r *http.Request) {
+	share, ok := h.fetchPublicShare(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src https: data:; frame-ancestors *")
+	w.Header().Set("Cache-Control", "no-store")
+
+	err := embedTemplate.Execute(w, share)
+	if err != nil {
+		http.Error(w, "Failed to render share", http.StatusInternalServerError)
+	}
+}
+
+func (h *ShareHandler) fetchPublicShare(w http.ResponseWriter, r *http.Request) (types.PublicShare, bool) {
+	token := r.PathValue("token")
+	if !shareTokenPattern.MatchString(token) {
+		http.Error(w, "Share link not found", http.StatusNotFound)
+		return types.PublicShare{}, false
+	}
+
+	share, dbErr := h.shares.FetchPublicShare(r.Context(), token)
+	if dbErr != nil {
+		if errors.Is(dbErr, store.ErrNotFound) {
+			http.Error(w, "Share link not found", http.StatusNotFound)
+			return types.PublicShare{}, false
+		}
+		writeStoreError(w, r, dbErr, "")
+		return types.PublicShare{}, false
+	}
+
+	return share, true
+}
+
+var embedTemplate = template.Must(template.New("embed").Funcs(template.FuncMap{
+	"price": func(amount float64, currency string) string {
+		return fmt.Sprintf("%.2f %s", amount, currency)
+	},
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>
+body { font-family: system-ui, sans-serif; margin: 0; padding: 12px; font-size: 14px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; }
+.total { font-weight: bold; margin-top: 8px; }
+</style>
+</head>
+<body>
+{{- with .Collection}}
+<h1>{{.Name}}</h1>
+{{- if .Description}}<p>{{.Description}}</p>{{end}}
+<table>
+<tr><th>Product</th><th>Qty</th><th>Lowest price</th><th>Source</th></tr>
+{{- range .Items}}
+<tr><td>{{.ProductName}}</td><td>{{.Quantity}}</td><td>{{if .LowestPrice}}{{price .LowestPrice $.Collection.Currency}}{{else}}-{{end}}</td><td>{{.LowestSource}}</td></tr>
+{{- end}}
+</table>
+<p class="total">Total: {{price .Total .Currency}}{{if .UnpricedCount}} ({{.UnpricedCount}} without a price){{end}}</p>
+{{- end}}
+{{- with .Product}}
+<h1>{{.Name}}</h1>
+<table>
+<tr><th>Date</th><th>Source</th><th>Price</th><th>In stock</th></tr>
+{{- range .History}}
+<tr><td>{{date .CheckedAt}}</td><td>{{.Platform}}</td><td>{{price .Price .Currency}}</td><td>{{if .InStock}}yes{{else}}no{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
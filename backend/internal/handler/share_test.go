@@ -0,0 +1,164 @@
+//go:build unit
+
+package handler_test
+
+import (
+	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"backend/internal/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Unit tests for the ShareHandler functions
+func TestShareHandlers(t *testing.T) {
+	token := strings.Repeat("ab", 32)
+
+	serve := func(mock *handler.MockShareStore, method, path, body string, withUser bool) *httptest.ResponseRecorder {
+		h := handler.NewShareHandler(mock)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/shares", h.GetShareLinks)
+		mux.HandleFunc("POST /api/v1/shares", h.CreateShareLink)
+		mux.HandleFunc("DELETE /api/v1/shares/{share_id}", h.RevokeShareLink)
+		mux.HandleFunc("GET /api/v1/public/shares/{token}", h.GetPublicShare)
+		mux.HandleFunc("GET /api/v1/public/shares/{token}/embed", h.GetPublicShareEmbed)
+
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if withUser {
+			req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("managing links requires a logged in user", func(t *testing.T) {
+		routes := [][2]string{
+			{http.MethodGet, "/api/v1/shares"},
+			{http.MethodPost, "/api/v1/shares"},
+			{http.MethodDelete, "/api/v1/shares/1"},
+		}
+		for _, route := range routes {
+			w := serve(&handler.MockShareStore{}, route[0], route[1], `{"product_id": 1}`, false)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, "%s %s", route[0], route[1])
+		}
+	})
+
+	t.Run("creates a link for a collection or a product", func(t *testing.T) {
+		tests := []struct {
+			name		string
+			body		string
+			wantCode	int
+		}{
+			{"collection", `{"collection_id": 3}`, http.StatusCreated},
+			{"product with expiry", `{"product_id": 2, "expires_in_hours": 24}`, http.StatusCreated},
+			{"neither", `{}`, http.StatusBadRequest},
+			{"both", `{"collection_id": 3, "product_id": 2}`, http.StatusBadRequest},
+			{"negative expiry", `{"product_id": 2, "expires_in_hours": -1}`, http.StatusBadRequest},
+			{"expiry over a year", `{"product_id": 2, "expires_in_hours": 9000}`, http.StatusBadRequest},
+			{"invalid json", `{`, http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := serve(&handler.MockShareStore{}, http.MethodPost, "/api/v1/shares", tt.body, true)
+				assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			})
+		}
+	})
+
+	t.Run("maps store errors", func(t *testing.T) {
+		tests := []struct {
+			name		string
+			err			error
+			wantCode	int
+		}{
+			{"not found", fmt.Errorf("collection 3: %w", store.ErrNotFound), http.StatusNotFound},
+			{"not the owner", fmt.Errorf("collection 3: %w", store.ErrForbidden), http.StatusForbidden},
+			{"database error", fmt.Errorf("connection reset"), http.StatusInternalServerError},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := serve(&handler.MockShareStore{InsertErr: tt.err}, http.MethodPost, "/api/v1/shares", `{"collection_id": 3}`, true)
+				assert.Equal(t, tt.wantCode, w.Code)
+
+				w = serve(&handler.MockShareStore{RevokeErr: tt.err}, http.MethodDelete, "/api/v1/shares/1", "", true)
+				assert.Equal(t, tt.wantCode, w.Code)
+			})
+		}
+	})
+
+	t.Run("revokes a link", func(t *testing.T) {
+		w := serve(&handler.MockShareStore{}, http.MethodDelete, "/api/v1/shares/1", "", true)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = serve(&handler.MockShareStore{}, http.MethodDelete, "/api/v1/shares/abc", "", true)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("public view needs no login", func(t *testing.T) {
+		mock := &handler.MockShareStore{Share: types.PublicShare{
+			Target: 	types.ShareTargetProduct,
+			Product: 	&types.PublicProduct{ProductID: 2, Name: "Ryzen 7 7700", History: []types.PublicPricePoint{
+				{Platform: "amazon", Price: 299.99, Currency: "USD", InStock: true, CheckedAt: time.Now()},
+			}},
+		}}
+
+		w := serve(mock, http.MethodGet, "/api/v1/public/shares/"+token, "", false)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), "cors is left to the middleware")
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"), "revoked links can't be served from a cache")
+
+		var share types.PublicShare
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&share))
+		require.NotNil(t, share.Product)
+		assert.Nil(t, share.Collection)
+		assert.Len(t, share.Product.History, 1)
+	})
+
+	t.Run("embed renders escaped html", func(t *testing.T) {
+		mock := &handler.MockShareStore{Share: types.PublicShare{
+			Target: 	types.ShareTargetCollection,
+			Collection: &types.PublicCollection{
+				Name: 		"<script>alert(1)</script>",
+				Currency: 	"USD",
+				Total: 		650,
+				Items: 		[]types.PublicCollectionItem{{ProductName: "DDR5 16GB", LowestPrice: 50, Quantity: 2}},
+			},
+		}}
+
+		w := serve(mock, http.MethodGet, "/api/v1/public/shares/"+token+"/embed", "", false)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, w.Header().Get("Content-Security-Policy"), "frame-ancestors *")
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		assert.Contains(t, w.Body.String(), "DDR5 16GB")
+		assert.Contains(t, w.Body.String(), "650.00 USD")
+		assert.NotContains(t, w.Body.String(), "<script>")
+	})
+
+	t.Run("unknown, revoked or malformed tokens are not found", func(t *testing.T) {
+		mock := &handler.MockShareStore{PublicErr: fmt.Errorf("share link: %w", store.ErrNotFound)}
+
+		w := serve(mock, http.MethodGet, "/api/v1/public/shares/"+token, "", false)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serve(mock, http.MethodGet, "/api/v1/public/shares/"+token+"/embed", "", false)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = serve(&handler.MockShareStore{}, http.MethodGet, "/api/v1/public/shares/not-a-token", "", false)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -2,22 +2,57 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
+	"backend/internal/middleware"
 	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/logger"
 	"github.com/go-playground/validator/v10"
 )
 
 type UserHandler struct {
 	users		store.UserStore
 	validate 	*validator.Validate
+	cookie		SessionCookie
 }
 
-func NewUserHandler(users store.UserStore) *UserHandler {
-	return &UserHandler{
+// settings for the session cookie set on login
+type SessionCookie struct {
+	MaxAge		time.Duration
+	Secure		bool
+	SameSite	http.SameSite
+	Domain		string
+}
+
+type UserHandlerOption func(*UserHandler)
+
+// override the default session cookie settings
+func WithSessionCookie(cookie SessionCookie) UserHandlerOption {
+	return func(h *UserHandler) {
+		h.cookie = cookie
+	}
+}
+
+func NewUserHandler(users store.UserStore, opts ...UserHandlerOption) *UserHandler {
+	h := &UserHandler{
 		users: 		users,
 		validate: 	validator.New(),
+		cookie: 	SessionCookie{
+			MaxAge: 	24 * time.Hour,
+			Secure: 	true,
+			SameSite: 	http.SameSiteStrictMode,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(h)
 	}
+
+	return h
 }
 
 // Create a new user account
@@ -40,7 +75,7 @@ func (h *UserHandler) UserSignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	insertErr := h.users.InsertNewUser(payload.Username, payload.Email, payload.Password)
+	insertErr := h.users.InsertNewUser(r.Context(), payload.Username, payload.Email, payload.Password)
 	if insertErr != nil {
 		http.Error(w, insertErr.Error(), http.StatusInternalServerError)
 		return
@@ -73,7 +108,7 @@ func (h *UserHandler) UserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionToken, err := h.users.LoginUser(payload.Username, payload.Password)
+	sessionToken, err := h.users.LoginUser(r.Context(), payload.Username, payload.Password)
 	if err != nil {
 		if err.Error() == "passwords don't match, can't login" {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
@@ -87,10 +122,11 @@ func (h *UserHandler) UserLogin(w http.ResponseWriter, r *http.Request) {
 		Name:     "session_token",
 		Value:    sessionToken,
 		Path:     "/",
-		MaxAge:   86400, // 24 hours in seconds
+		Domain:   h.cookie.Domain,
+		MaxAge:   int(h.cookie.MaxAge.Seconds()),
 		HttpOnly: true,  
-		Secure:   true,  
-		SameSite: http.SameSiteStrictMode,
+		Secure:   h.cookie.Secure,  
+		SameSite: h.cookie.SameSite,
 	})
 
 	w.Header().Set("Content-Type", "application/json")
@@ -104,3 +140,79 @@ func (h *UserHandler) UserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// GET route for the logged in user's preferences
+func (h *UserHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	preferences, err := h.users.FetchUserPreferences(r.Context(), user.UserId)
+	if err != nil {
+		writePreferencesError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(preferences)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// PUT and PATCH route to change the logged in user's preferences,
+// display_currency is an ISO 4217 code that prices are converted to and
+// price_basis picks whether offers and alerts are compared on the sticker
+// price or the landed cost with shipping, tax and coupons. Fields left out
+// keep their current value
+func (h *UserHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	var payload types.UserPreferences
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid json payload", http.StatusBadRequest)
+		return
+	}
+
+	payload.DisplayCurrency = strings.ToUpper(strings.TrimSpace(payload.DisplayCurrency))
+	payload.PriceBasis = strings.ToLower(strings.TrimSpace(payload.PriceBasis))
+	if payload.DisplayCurrency == "" && payload.PriceBasis == "" {
+		http.Error(w, "Nothing to update: set display_currency or price_basis", http.StatusBadRequest)
+		return
+	}
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	preferences, err := h.users.UpdateUserPreferences(r.Context(), user.UserId, payload)
+	if err != nil {
+		writePreferencesError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(preferences)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func writePreferencesError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	logger.FromContext(r.Context()).Error("database error", "err", err)
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
@@ -4,13 +4,20 @@ package handler_test
 
 import (
 	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"backend/internal/types"
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Unit tests for the UserSignUp Handler function
@@ -149,4 +156,116 @@ func TestUserLoginHandler(t *testing.T) {
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code, "It should return http status 500")
 	})
+
+	t.Run("session cookie uses configured settings", func(t *testing.T) {
+		mockHandler := handler.NewUserHandler(&handler.MockUserStore{}, handler.WithSessionCookie(handler.SessionCookie{
+			MaxAge: 	2 * time.Hour,
+			Secure: 	false,
+			SameSite: 	http.SameSiteLaxMode,
+			Domain: 	"example.com",
+		}))
+
+		payload := map[string]interface{}{"username": "idk", "password": "pass123"}
+		body, _ := json.Marshal(payload)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/user/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		mockHandler.UserLogin(w, req)
+
+		cookies := w.Result().Cookies()
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, cookies, 1)
+		assert.Equal(t, 7200, cookies[0].MaxAge)
+		assert.False(t, cookies[0].Secure)
+		assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		assert.Equal(t, "example.com", cookies[0].Domain)
+	})
+}
+
+// request carrying the logged in user like AuthMiddleware would set it
+func withUser(req *http.Request) *http.Request {
+	return req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+}
+
+// Unit tests for the GetPreferences and UpdatePreferences Handler functions
+func TestPreferencesHandlers(t *testing.T) {
+	t.Run("requires a logged in user", func(t *testing.T) {
+		mockHandler := handler.NewUserHandler(&handler.MockUserStore{})
+
+		w := httptest.NewRecorder()
+		mockHandler.GetPreferences(w, httptest.NewRequest(http.MethodGet, "/api/v1/user/preferences", nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = httptest.NewRecorder()
+		mockHandler.UpdatePreferences(w, httptest.NewRequest(http.MethodPut, "/api/v1/user/preferences", strings.NewReader(`{"display_currency": "EUR"}`)))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("returns the preferences", func(t *testing.T) {
+		mockHandler := handler.NewUserHandler(&handler.MockUserStore{})
+
+		w := httptest.NewRecorder()
+		mockHandler.GetPreferences(w, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/user/preferences", nil)))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"display_currency": "USD", "price_basis": "sticker"}`, w.Body.String())
+	})
+
+	t.Run("rejects unknown currencies", func(t *testing.T) {
+		payloads := []string{
+			``,
+			`{}`,
+			`{"display_currency": "US"}`,
+			`{"display_currency": "XYZ"}`,
+			`{"display_currency": "dollars"}`,
+			`{"price_basis": "cheapest"}`,
+		}
+
+		for _, payload := range payloads {
+			mockHandler := handler.NewUserHandler(&handler.MockUserStore{})
+
+			w := httptest.NewRecorder()
+			mockHandler.UpdatePreferences(w, withUser(httptest.NewRequest(http.MethodPut, "/api/v1/user/preferences", strings.NewReader(payload))))
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+		}
+	})
+
+	t.Run("upper cases the currency code before saving", func(t *testing.T) {
+		mock := &handler.MockUserStore{}
+		mockHandler := handler.NewUserHandler(mock)
+
+		w := httptest.NewRecorder()
+		mockHandler.UpdatePreferences(w, withUser(httptest.NewRequest(http.MethodPut, "/api/v1/user/preferences", strings.NewReader(`{"display_currency": " eur "}`))))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, types.UserPreferences{DisplayCurrency: "EUR"}, mock.LastPreferences)
+	})
+
+	t.Run("updates only the given fields", func(t *testing.T) {
+		mock := &handler.MockUserStore{}
+		mockHandler := handler.NewUserHandler(mock)
+
+		w := httptest.NewRecorder()
+		mockHandler.UpdatePreferences(w, withUser(httptest.NewRequest(http.MethodPatch, "/api/v1/user/preferences", strings.NewReader(`{"price_basis": "Landed"}`))))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, types.UserPreferences{PriceBasis: types.PriceBasisLanded}, mock.LastPreferences)
+	})
+
+	t.Run("store errors map to 404 and 500", func(t *testing.T) {
+		mockHandler := handler.NewUserHandler(&handler.MockUserStore{PreferencesErr: fmt.Errorf("user 1: %w", store.ErrNotFound)})
+
+		w := httptest.NewRecorder()
+		mockHandler.GetPreferences(w, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/user/preferences", nil)))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		mockHandler = handler.NewUserHandler(&handler.MockUserStore{PreferencesErr: errors.New("db error")})
+
+		w = httptest.NewRecorder()
+		mockHandler.UpdatePreferences(w, withUser(httptest.NewRequest(http.MethodPut, "/api/v1/user/preferences", strings.NewReader(`{"display_currency": "GBP"}`))))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
 }
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/platform"
+	"backend/internal/store"
+	"backend/internal/types"
+	"backend/pkg/db"
+	"backend/pkg/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PATCH route to change the logged in user's settings for a product in their
+// watchlist. acceptable_conditions limits which offers (new, used, refurbished)
+// count towards the lowest price, min_seller_rating drops offers from
+// marketplace sellers rated below it or without a rating unless
+// allow_unrated_sellers is set and excluded_platforms drops every offer
+// of those platforms. preferred_platforms win ties on price, target_price and
+// notes are for the user and paused stops the entry's alerts from
+// triggering. tags replace the entry's tags, lower cased. Fields left out
+// keep their value, a platform can't end up both preferred and excluded
+func (h *ProductHandler) UpdateWatchlistEntry(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(r.PathValue("product_id"))
+	if err != nil || productID < 1 {
+		http.Error(w, "Invalid product id: must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	var payload types.WatchlistUpdate
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	normalizeWatchlistUpdate(&payload)
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conflict := watchlistUpdateConflict(payload)
+	if conflict != "" {
+		http.Error(w, conflict, http.StatusBadRequest)
+		return
+	}
+
+	entry, dbErr := h.products.UpdateWatchlistEntry(r.Context(), user.UserId, productID, payload)
+	if dbErr != nil {
+		if errors.Is(dbErr, store.ErrNotFound) {
+			http.Error(w, "Product not found in user's watchlist", http.StatusNotFound)
+			return
+		}
+		if errors.Is(dbErr, store.ErrConflict) {
+			http.Error(w, "A platform can't be both preferred and excluded", http.StatusConflict)
+			return
+		}
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+		if db.HandleDatabaseErrors(w, dbErr) {
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeErr := json.NewEncoder(w).Encode(entry)
+	if encodeErr != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// POST route to add, delete and update many products of the logged in user's
+// watchlist in one transaction. mode atomic (default) undoes the whole batch
+// when an operation fails, best_effort keeps the operations that worked.
+// Operations are {op: add, product_name or url}, {op: delete, product_id} or
+// {op: update, product_id, update} with the fields of the PATCH route. The
+// result has the status of every operation by its index
+func (h *ProductHandler) BatchWatchlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+		return
+	}
+
+	var payload types.WatchlistBatch
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	if payload.Mode == "" {
+		payload.Mode = types.BatchModeAtomic
+	}
+	for _, op := range payload.Operations {
+		if op.Update != nil {
+			normalizeWatchlistUpdate(op.Update)
+		}
+	}
+
+	err = h.validate.Struct(payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// operations that could never run reject the whole request
+	for i := range payload.Operations {
+		invalid := prepareWatchlistOperation(&payload.Operations[i])
+		if invalid != "" {
+			http.Error(w, fmt.Sprintf("Invalid operation %d: %s", i, invalid), http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, dbErr := h.products.BatchWatchlist(r.Context(), user.UserId, payload)
+	if dbErr != nil {
+		logger.FromContext(r.Context()).Error("database error", "err", dbErr)
+		if db.HandleDatabaseErrors(w, dbErr) {
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// check the operation has what its op needs and turn an add's url into a
+// product source, returns why it's invalid otherwise
+func prepareWatchlistOperation(op *types.WatchlistOperation) string {
+	switch op.Op {
+	case types.BatchOpAdd:
+		if op.ProductName == "" && op.URL == "" {
+			return "product_name or url is required"
+		}
+		if op.URL == "" {
+			return ""
+		}
+
+		listing, err := platform.ParseURL(op.URL)
+		if err != nil {
+			return err.Error()
+		}
+		if op.ProductName == "" {
+			op.ProductName = listing.Name
+		}
+		if op.ProductName == "" {
+			op.ProductName = listing.FallbackName()
+		}
+		op.Source = &types.ProductSource{
+			Platform: 			listing.Platform,
+			PlatformProductID: 	listing.ProductID,
+			URL: 				listing.URL,
+		}
+
+	case types.BatchOpDelete:
+		if op.ProductID == 0 {
+			return "product_id is required"
+		}
+
+	case types.BatchOpUpdate:
+		if op.ProductID == 0 {
+			return "product_id is required"
+		}
+		if op.Update == nil {
+			return "update is required"
+		}
+		return watchlistUpdateConflict(*op.Update)
+	}
+
+	return ""
+}
+
+// lower case the lists and trim the notes of an update before validating it
+func normalizeWatchlistUpdate(update *types.WatchlistUpdate) {
+	for _, list := range []*[]string{update.AcceptableConditions, update.PreferredPlatforms, update.ExcludedPlatforms, update.Tags} {
+		if list != nil {
+			*list = uniqueLower(*list)
+		}
+	}
+	if update.Notes != nil {
+		notes := strings.TrimSpace(*update.Notes)
+		update.Notes = &notes
+	}
+}
+
+// why the update can't be applied when it lists a platform as both preferred
+// and excluded, empty otherwise. Conflicts with the stored settings are
+// caught by the store
+func watchlistUpdateConflict(update types.WatchlistUpdate) string {
+	if update.PreferredPlatforms == nil || update.ExcludedPlatforms == nil {
+		return ""
+	}
+	for _, platform := range *update.PreferredPlatforms {
+		if slices.Contains(*update.ExcludedPlatforms, platform) {
+			return "Platform " + platform + " can't be both preferred and excluded"
+		}
+	}
+	return ""
+}
+
+// trimmed lower case values without duplicates, in their original order
+func uniqueLower(values []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
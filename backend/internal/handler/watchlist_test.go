@@ -0,0 +1,208 @@
+//go:build unit
+
+package handler_test
+
+import (
+	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"backend/internal/types"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Unit tests for the UpdateWatchlistEntry Handler function
+func TestUpdateWatchlistEntryHandler(t *testing.T) {
+	serve := func(mock *handler.MockProductStore, path, body string, withUser bool) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.HandleFunc("PATCH /api/v1/watchlist/{product_id}", handler.NewProductHandler(mock).UpdateWatchlistEntry)
+
+		req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+		if withUser {
+			req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+		}
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("invalid product id returns 400", func(t *testing.T) {
+		for _, path := range []string{"/api/v1/watchlist/abc", "/api/v1/watchlist/0"} {
+			w := serve(&handler.MockProductStore{}, path, `{}`, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		}
+	})
+
+	t.Run("missing user returns 401", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{}, "/api/v1/watchlist/4", `{}`, false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("rejects invalid settings", func(t *testing.T) {
+		payloads := []string{
+			``,
+			`{"acceptable_conditions": ["broken"]}`,
+			`{"acceptable_conditions": "new"}`,
+			`{"min_seller_rating": -1}`,
+			`{"min_seller_rating": 101}`,
+			`{"target_price": -5}`,
+			`{"notes": 12}`,
+			`{"excluded_platforms": ["walmart"]}`,
+			`{"paused": "yes"}`,
+			`{"tags": [""]}`,
+			`{"tags": ["` + strings.Repeat("a", 31) + `"]}`,
+			`{"preferred_platforms": ["amazon"], "excluded_platforms": ["Amazon", "ebay"]}`,
+		}
+
+		for _, payload := range payloads {
+			w := serve(&handler.MockProductStore{}, "/api/v1/watchlist/4", payload, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+		}
+	})
+
+	t.Run("normalizes conditions before saving", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serve(mock, "/api/v1/watchlist/4", `{"acceptable_conditions": ["New", " refurbished", "new"], "min_seller_rating": 95}`, true)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, mock.LastWatchlistUpdate.AcceptableConditions)
+		assert.Equal(t, []string{types.ConditionNew, types.ConditionRefurbished}, *mock.LastWatchlistUpdate.AcceptableConditions)
+		require.NotNil(t, mock.LastWatchlistUpdate.MinSellerRating)
+		assert.Equal(t, 95.0, *mock.LastWatchlistUpdate.MinSellerRating)
+	})
+
+	t.Run("normalizes platforms and notes before saving", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serve(mock, "/api/v1/watchlist/4", `{
+			"target_price": 49.99,
+			"notes": "  wait for black friday ",
+			"preferred_platforms": ["BestBuy"],
+			"excluded_platforms": ["ebay", "EBAY"],
+			"paused": true,
+			"tags": ["Homelab", "homelab ", "GPU"]
+		}`, true)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		update := mock.LastWatchlistUpdate
+		require.NotNil(t, update.TargetPrice)
+		assert.Equal(t, 49.99, *update.TargetPrice)
+		require.NotNil(t, update.Notes)
+		assert.Equal(t, "wait for black friday", *update.Notes)
+		require.NotNil(t, update.PreferredPlatforms)
+		assert.Equal(t, []string{"bestbuy"}, *update.PreferredPlatforms)
+		require.NotNil(t, update.ExcludedPlatforms)
+		assert.Equal(t, []string{"ebay"}, *update.ExcludedPlatforms)
+		require.NotNil(t, update.Paused)
+		assert.True(t, *update.Paused)
+		require.NotNil(t, update.Tags)
+		assert.Equal(t, []string{"homelab", "gpu"}, *update.Tags)
+	})
+
+	t.Run("leaves out fields that are not sent", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serve(mock, "/api/v1/watchlist/4", `{"min_seller_rating": 0}`, true)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, mock.LastWatchlistUpdate.AcceptableConditions)
+		assert.Nil(t, mock.LastWatchlistUpdate.Paused)
+		assert.NotNil(t, mock.LastWatchlistUpdate.MinSellerRating)
+	})
+
+	t.Run("store errors map to 404, 409 and 500", func(t *testing.T) {
+		mock := &handler.MockProductStore{WatchlistErr: fmt.Errorf("product 4: %w", store.ErrNotFound)}
+		w := serve(mock, "/api/v1/watchlist/4", `{"acceptable_conditions": []}`, true)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// excluding a platform the stored entry prefers
+		mock = &handler.MockProductStore{WatchlistErr: fmt.Errorf("platform ebay of product 4: %w", store.ErrConflict)}
+		w = serve(mock, "/api/v1/watchlist/4", `{"excluded_platforms": ["ebay"]}`, true)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		mock = &handler.MockProductStore{WatchlistErr: errors.New("db error")}
+		w = serve(mock, "/api/v1/watchlist/4", `{"acceptable_conditions": []}`, true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+// Unit tests for the BatchWatchlist Handler function
+func TestBatchWatchlistHandler(t *testing.T) {
+	serve := func(mock *handler.MockProductStore, body string, withUser bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/watchlist/batch", strings.NewReader(body))
+		if withUser {
+			req = req.WithContext(middleware.WithUser(req.Context(), middleware.UserContext{UserId: 1, Username: "user1"}))
+		}
+		w := httptest.NewRecorder()
+
+		handler.NewProductHandler(mock).BatchWatchlist(w, req)
+		return w
+	}
+
+	t.Run("missing user returns 401", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{}, `{"operations": [{"op": "delete", "product_id": 1}]}`, false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("rejects invalid batches", func(t *testing.T) {
+		payloads := []string{
+			`{`,
+			`{"operations": []}`,
+			`{"mode": "yolo", "operations": [{"op": "delete", "product_id": 1}]}`,
+			`{"operations": [{"op": "rename", "product_id": 1}]}`,
+			`{"operations": [{"op": "add"}]}`,
+			`{"operations": [{"op": "add", "product_name": "x"}]}`,
+			`{"operations": [{"op": "add", "url": "https://example.com/item"}]}`,
+			`{"operations": [{"op": "delete"}]}`,
+			`{"operations": [{"op": "update", "product_id": 1}]}`,
+			`{"operations": [{"op": "update", "product_id": 1, "update": {"target_price": -1}}]}`,
+			`{"operations": [{"op": "update", "product_id": 1, "update": {"preferred_platforms": ["ebay"], "excluded_platforms": ["ebay"]}}]}`,
+			`{"operations": [` + strings.Repeat(`{"op": "delete", "product_id": 1},`, 100) + `{"op": "delete", "product_id": 1}]}`,
+		}
+
+		for _, payload := range payloads {
+			mock := &handler.MockProductStore{}
+			w := serve(mock, payload, true)
+			assert.Equal(t, http.StatusBadRequest, w.Code, payload)
+			assert.Empty(t, mock.LastBatch.Operations, "nothing runs: %s", payload)
+		}
+	})
+
+	t.Run("defaults to atomic and prepares operations", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serve(mock, `{"operations": [
+			{"op": "add", "product_name": "Ryzen 7 7700"},
+			{"op": "add", "url": "https://www.amazon.com/dp/B09XS7JWHH"},
+			{"op": "delete", "product_id": 4},
+			{"op": "update", "product_id": 5, "update": {"tags": [" CPU "]}}
+		]}`, true)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		batch := mock.LastBatch
+		assert.Equal(t, types.BatchModeAtomic, batch.Mode)
+		require.Len(t, batch.Operations, 4)
+		assert.Nil(t, batch.Operations[0].Source)
+		require.NotNil(t, batch.Operations[1].Source)
+		assert.Equal(t, "amazon", batch.Operations[1].Source.Platform)
+		assert.NotEmpty(t, batch.Operations[1].ProductName)
+		assert.Equal(t, []string{"cpu"}, *batch.Operations[3].Update.Tags)
+	})
+
+	t.Run("keeps the best effort mode", func(t *testing.T) {
+		mock := &handler.MockProductStore{}
+		w := serve(mock, `{"mode": "best_effort", "operations": [{"op": "delete", "product_id": 4}]}`, true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, types.BatchModeBestEffort, mock.LastBatch.Mode)
+	})
+
+	t.Run("store errors return 500", func(t *testing.T) {
+		w := serve(&handler.MockProductStore{BatchErr: errors.New("connection reset")}, `{"operations": [{"op": "delete", "product_id": 4}]}`, true)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
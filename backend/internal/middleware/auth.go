@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"backend/internal/store"
+	"backend/pkg/logger"
 	"context"
+	"log/slog"
 	"net/http"
 )
 
@@ -15,6 +17,18 @@ type UserContext struct {
 	Username 	string
 }
 
+// store the logged in user on the context, AuthMiddleware does this for
+// every protected route
+func WithUser(ctx context.Context, user UserContext) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// fetch the logged in user set by AuthMiddleware
+func UserFromContext(ctx context.Context) (UserContext, bool) {
+	user, ok := ctx.Value(userContextKey).(UserContext)
+	return user, ok
+}
+
 type Handler struct {
 	handler		store.ValidationStore
 }
@@ -33,7 +47,7 @@ func (h *Handler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		userId, username, err := h.handler.ValidateSession(cookie.Value)
+		userId, username, err := h.handler.ValidateSession(r.Context(), cookie.Value)
 		if err != nil {
 			http.Error(w, "Unauthorized: invalid session", http.StatusUnauthorized)
 			return
@@ -44,7 +58,38 @@ func (h *Handler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			Username: 	username,
 		}
 
-		ctx := context.WithValue(r.Context(), userContextKey, userContext)
+		ctx := WithUser(r.Context(), userContext)
+
+		// tag the access log and every log line further down the request with the user
+		annotateRequestLog(ctx, slog.Int("user_id", userId))
+		ctx = logger.WithContext(ctx, logger.FromContext(ctx).With("user_id", userId))
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
-}
\ No newline at end of file
+}
+
+// only lets users with the admin flag through, must be wrapped by
+// AuthMiddleware so the logged in user is in the context
+func (h *Handler) AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized: no session", http.StatusUnauthorized)
+			return
+		}
+
+		isAdmin, err := h.handler.IsAdminUser(r.Context(), user.UserId)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("admin check failed", "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if !isAdmin {
+			http.Error(w, "Forbidden: admin only", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
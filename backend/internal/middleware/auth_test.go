@@ -0,0 +1,65 @@
+//go:build unit
+
+package middleware_test
+
+import (
+	"backend/internal/middleware"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// unit tests for AdminMiddleware
+func TestAdminMiddleware(t *testing.T) {
+	serve := func(store *stubValidationStore, withSession bool) (*httptest.ResponseRecorder, bool) {
+		called := false
+		m := middleware.NewMiddlewareHandler(store)
+		admin := m.AdminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusOK)
+		})
+
+		handler := admin
+		if withSession {
+			handler = m.AuthMiddleware(admin)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/products/merge", nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: "token"})
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+		return w, called
+	}
+
+	t.Run("admins are let through", func(t *testing.T) {
+		w, called := serve(&stubValidationStore{userId: 1, username: "admin", isAdmin: true}, true)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, called)
+	})
+
+	t.Run("non admins get 403", func(t *testing.T) {
+		w, called := serve(&stubValidationStore{userId: 2, username: "user"}, true)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.False(t, called)
+	})
+
+	t.Run("missing user in context returns 401", func(t *testing.T) {
+		w, called := serve(&stubValidationStore{isAdmin: true}, false)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.False(t, called)
+	})
+
+	t.Run("store error returns 500", func(t *testing.T) {
+		w, called := serve(&stubValidationStore{userId: 1, adminErr: errors.New("db error")}, true)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.False(t, called)
+	})
+}
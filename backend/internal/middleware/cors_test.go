@@ -0,0 +1,95 @@
+//go:build unit
+
+package middleware_test
+
+import (
+	"backend/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func corsHandler(options middleware.CORSOptions, called *bool) http.Handler {
+	return middleware.CORS(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*called = true
+	}))
+}
+
+// unit tests for CORS middleware
+func TestCORS(t *testing.T) {
+	options := middleware.CORSOptions{
+		AllowedOrigins: 	[]string{"http://localhost:5173"},
+		AllowCredentials: 	true,
+		MaxAge: 			10 * time.Minute,
+	}
+
+	t.Run("allowed origin gets cors headers", func(t *testing.T) {
+		var called bool
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/get/1", nil)
+		req.Header.Set("Origin", "http://localhost:5173")
+		w := httptest.NewRecorder()
+
+		corsHandler(options, &called).ServeHTTP(w, req)
+
+		assert.True(t, called, "handler should be called")
+		assert.Equal(t, "http://localhost:5173", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Contains(t, w.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("unknown origin gets no cors headers", func(t *testing.T) {
+		var called bool
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/get/1", nil)
+		req.Header.Set("Origin", "https://evil.example.com")
+		w := httptest.NewRecorder()
+
+		corsHandler(options, &called).ServeHTTP(w, req)
+
+		assert.True(t, called, "the browser enforces cors, the request itself still runs")
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("preflight is answered without calling the handler", func(t *testing.T) {
+		var called bool
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/products/delete", nil)
+		req.Header.Set("Origin", "http://localhost:5173")
+		req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+		w := httptest.NewRecorder()
+
+		corsHandler(options, &called).ServeHTTP(w, req)
+
+		assert.False(t, called, "preflight should not reach the router")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), http.MethodDelete)
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), middleware.CSRFHeader)
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("preflight from unknown origin is passed through", func(t *testing.T) {
+		var called bool
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/products/delete", nil)
+		req.Header.Set("Origin", "https://evil.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+		w := httptest.NewRecorder()
+
+		corsHandler(options, &called).ServeHTTP(w, req)
+
+		assert.True(t, called)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("wildcard origin without credentials", func(t *testing.T) {
+		var called bool
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Origin", "https://anything.example.com")
+		w := httptest.NewRecorder()
+
+		corsHandler(middleware.CORSOptions{AllowedOrigins: []string{"*"}}, &called).ServeHTTP(w, req)
+
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	CSRFCookieName	= "csrf_token"
+	CSRFHeader		= "X-CSRF-Token"
+)
+
+const csrfContextKey contextKey = "csrf_token"
+
+type CSRFOptions struct {
+	Secure		bool
+	SameSite	http.SameSite
+	Domain		string
+}
+
+// double submit csrf protection. Every client gets a random token in a cookie
+// readable by the frontend, state changing requests must echo the cookie
+// value back in the X-CSRF-Token header. Another site can make the browser
+// send our cookies but can't read them to set the header
+func CSRF(options CSRFOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var token string
+			cookie, err := r.Cookie(CSRFCookieName)
+			if err == nil && cookie.Value != "" {
+				token = cookie.Value
+			}
+
+			if isStateChanging(r.Method) {
+				header := r.Header.Get(CSRFHeader)
+				if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(header)) != 1 {
+					http.Error(w, "Forbidden: invalid csrf token", http.StatusForbidden)
+					return
+				}
+			}
+
+			if token == "" {
+				token = newCSRFToken()
+				http.SetCookie(w, &http.Cookie{
+					Name:     CSRFCookieName,
+					Value:    token,
+					Path:     "/",
+					Domain:   options.Domain,
+					HttpOnly: false, // the frontend has to read it to send the header
+					Secure:   options.Secure,
+					SameSite: options.SameSite,
+				})
+			}
+
+			ctx := context.WithValue(r.Context(), csrfContextKey, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// fetch the csrf token for the current request set by the CSRF middleware
+func CSRFTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(csrfContextKey).(string)
+	return token
+}
+
+func isStateChanging(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	default:
+		return true
+	}
+}
+
+func newCSRFToken() string {
+	tokenBytes := make([]byte, 32)
+	_, _ = rand.Read(tokenBytes)
+
+	return hex.EncodeToString(tokenBytes)
+}
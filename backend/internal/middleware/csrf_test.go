@@ -0,0 +1,89 @@
+//go:build unit
+
+package middleware_test
+
+import (
+	"backend/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func csrfHandler(called *bool, token *string) http.Handler {
+	options := middleware.CSRFOptions{Secure: true, SameSite: http.SameSiteStrictMode}
+
+	return middleware.CSRF(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*called = true
+		*token = middleware.CSRFTokenFromContext(r.Context())
+	}))
+}
+
+// unit tests for CSRF middleware
+func TestCSRF(t *testing.T) {
+	t.Run("safe request without a cookie gets a new token", func(t *testing.T) {
+		var called bool
+		var token string
+		w := httptest.NewRecorder()
+
+		csrfHandler(&called, &token).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/csrf", nil))
+
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.True(t, called)
+		assert.Equal(t, middleware.CSRFCookieName, cookies[0].Name)
+		assert.Equal(t, cookies[0].Value, token, "context token should match the cookie")
+		assert.False(t, cookies[0].HttpOnly, "frontend must be able to read the token")
+		assert.Len(t, token, 64)
+	})
+
+	t.Run("safe request with a cookie keeps the token", func(t *testing.T) {
+		var called bool
+		var token string
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/csrf", nil)
+		req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: "existing"})
+		w := httptest.NewRecorder()
+
+		csrfHandler(&called, &token).ServeHTTP(w, req)
+
+		assert.Empty(t, w.Result().Cookies(), "no new cookie should be set")
+		assert.Equal(t, "existing", token)
+	})
+
+	t.Run("state changing requests need matching cookie and header", func(t *testing.T) {
+		testCases := []struct {
+			name			string
+			cookie			string
+			header			string
+			expectedStatus	int
+		}{
+			{"no cookie or header", "", "", http.StatusForbidden},
+			{"cookie without header", "token", "", http.StatusForbidden},
+			{"header without cookie", "", "token", http.StatusForbidden},
+			{"mismatched header", "token", "other", http.StatusForbidden},
+			{"matching header", "token", "token", http.StatusOK},
+		}
+
+		for _, method := range []string{http.MethodPost, http.MethodDelete, http.MethodPatch} {
+			for _, tc := range testCases {
+				var called bool
+				var token string
+				req := httptest.NewRequest(method, "/api/v1/products/delete", nil)
+				if tc.cookie != "" {
+					req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: tc.cookie})
+				}
+				if tc.header != "" {
+					req.Header.Set(middleware.CSRFHeader, tc.header)
+				}
+				w := httptest.NewRecorder()
+
+				csrfHandler(&called, &token).ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code, "%s %s", method, tc.name)
+				assert.Equal(t, tc.expectedStatus == http.StatusOK, called, "%s %s", method, tc.name)
+			}
+		}
+	})
+}
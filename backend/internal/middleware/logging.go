@@ -1,7 +1,9 @@
 package middleware
 
 import (
-	"log"
+	"backend/pkg/logger"
+	"context"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -18,6 +20,22 @@ func (w *WrappedWriter) WriteHeader(statuscode int) {
 	w.StatusCode = statuscode
 }
 
+const requestLogContextKey contextKey = "request_log"
+
+// extra fields added to the access log line by middleware further
+// down the chain, like the user id once AuthMiddleware has run
+type requestLog struct {
+	attrs []slog.Attr
+}
+
+// add fields to the access log line for the current request, does nothing
+// if the request isn't wrapped by the Logging middleware
+func annotateRequestLog(ctx context.Context, attrs ...slog.Attr) {
+	if reqLog, ok := ctx.Value(requestLogContextKey).(*requestLog); ok {
+		reqLog.attrs = append(reqLog.attrs, attrs...)
+	}
+}
+
 // logging middleware to track status codes, the url path, and response latency
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -28,7 +46,19 @@ func Logging(next http.Handler) http.Handler {
 			StatusCode: http.StatusOK,
 		}
 
-		next.ServeHTTP(wrapped, r)
-		log.Println(wrapped.StatusCode, r.Method, r.URL.Path, time.Since(start))
+		reqLog := &requestLog{}
+		ctx := context.WithValue(r.Context(), requestLogContextKey, reqLog)
+
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		attrs := []slog.Attr{
+			slog.Int("status", wrapped.StatusCode),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Duration("duration", time.Since(start)),
+		}
+		attrs = append(attrs, reqLog.attrs...)
+
+		logger.FromContext(ctx).LogAttrs(ctx, slog.LevelInfo, "request", attrs...)
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"backend/internal/ratelimit"
+	"backend/internal/store"
+	"backend/pkg/logger"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RateLimiter struct {
+	store			store.RateLimitStore
+	trustedProxies	[]netip.Prefix
+}
+
+// anonymous requests coming from one of the trusted proxies are keyed on the
+// client address the proxies put in X-Forwarded-For, every other request on
+// the address it came from
+func NewRateLimiter(store store.RateLimitStore, trustedProxies []netip.Prefix) *RateLimiter {
+	return &RateLimiter{store: store, trustedProxies: trustedProxies}
+}
+
+// Limit requests to a route with a token bucket per user, or per client ip
+// for requests without a logged in user. Wrap it inside AuthMiddleware so the
+// user is known. Sets the RateLimit-* headers on every response and answers
+// 429 with Retry-After once the bucket is empty
+func (l *RateLimiter) Limit(route string, limit ratelimit.Limit) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := l.bucketKey(route, r)
+
+			result, err := l.store.TakeRateLimitToken(r.Context(), key, limit)
+			if err != nil {
+				// fail open so a rate limit backend outage doesn't take the api down
+				logger.FromContext(r.Context()).Warn("rate limit check failed", "route", route, "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+// buckets are per route so a burst on one route doesn't block the others
+func (l *RateLimiter) bucketKey(route string, r *http.Request) string {
+	if user, ok := UserFromContext(r.Context()); ok {
+		return fmt.Sprintf("%s:user:%d", route, user.UserId)
+	}
+
+	return fmt.Sprintf("%s:ip:%s", route, l.clientIP(r))
+}
+
+// the right-most X-Forwarded-For hop that isn't a trusted proxy, which is the
+// address the outermost proxy saw. Hops left of it come from the client and
+// can be anything so they are never used
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !l.trusted(remote) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop != "" && !l.trusted(hop) {
+			return hop
+		}
+	}
+
+	return remote
+}
+
+func (l *RateLimiter) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	for _, prefix := range l.trustedProxies {
+		if prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
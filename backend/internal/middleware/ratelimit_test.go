@@ -0,0 +1,122 @@
+//go:build unit
+
+package middleware_test
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/ratelimit"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// records the bucket keys the limiter asks for
+type keyRecordingStore struct {
+	keys	[]string
+	err		error
+}
+
+func (s *keyRecordingStore) TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	s.keys = append(s.keys, key)
+	return ratelimit.Result{Allowed: true, Limit: limit.Requests, Remaining: limit.Requests - 1}, s.err
+}
+
+// unit tests for RateLimiter middleware
+func TestRateLimiter(t *testing.T) {
+	okHandler := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	t.Run("sets headers and limits once the bucket is empty", func(t *testing.T) {
+		limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), nil)
+		limited := limiter.Limit("user.login", ratelimit.Limit{Requests: 2, Period: time.Minute})(okHandler)
+
+		var w *httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			w = httptest.NewRecorder()
+			limited(w, httptest.NewRequest(http.MethodPost, "/api/v1/user/login", nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+		w = httptest.NewRecorder()
+		limited(w, httptest.NewRequest(http.MethodPost, "/api/v1/user/login", nil))
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("keys on the user when logged in", func(t *testing.T) {
+		store := &keyRecordingStore{}
+		limiter := middleware.NewRateLimiter(store, nil)
+		m := middleware.NewMiddlewareHandler(&stubValidationStore{userId: 7, username: "user1"})
+		limited := m.AuthMiddleware(limiter.Limit("products.add_name", ratelimit.Limit{Requests: 1, Period: time.Minute})(okHandler))
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/products/add/name", nil)
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: "token"})
+		limited(httptest.NewRecorder(), req)
+
+		require.Len(t, store.keys, 1)
+		assert.Equal(t, "products.add_name:user:7", store.keys[0])
+	})
+
+	t.Run("keys on the client ip when anonymous", func(t *testing.T) {
+		store := &keyRecordingStore{}
+		limited := middleware.NewRateLimiter(store, nil).Limit("user.login", ratelimit.Limit{Requests: 1, Period: time.Minute})(okHandler)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/user/login", nil)
+		req.RemoteAddr = "203.0.113.5:4321"
+		req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		limited(httptest.NewRecorder(), req)
+
+		require.Len(t, store.keys, 1)
+		assert.Equal(t, "user.login:ip:203.0.113.5", store.keys[0], "forwarded header is ignored unless the proxy is trusted")
+	})
+
+	t.Run("uses the right-most untrusted forwarded ip behind a trusted proxy", func(t *testing.T) {
+		store := &keyRecordingStore{}
+		proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+		limited := middleware.NewRateLimiter(store, proxies).Limit("user.login", ratelimit.Limit{Requests: 1, Period: time.Minute})(okHandler)
+
+		// the client sent 192.0.2.77 itself, the proxies appended the rest
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/user/login", nil)
+		req.RemoteAddr = "10.0.0.2:4321"
+		req.Header.Set("X-Forwarded-For", "192.0.2.77, 198.51.100.1, 10.0.0.1")
+		limited(httptest.NewRecorder(), req)
+
+		require.Len(t, store.keys, 1)
+		assert.Equal(t, "user.login:ip:198.51.100.1", store.keys[0])
+	})
+
+	t.Run("spoofed forwarded ips from untrusted peers are ignored", func(t *testing.T) {
+		store := &keyRecordingStore{}
+		proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+		limited := middleware.NewRateLimiter(store, proxies).Limit("user.login", ratelimit.Limit{Requests: 1, Period: time.Minute})(okHandler)
+
+		for _, forwarded := range []string{"192.0.2.1", "192.0.2.2"} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/user/login", nil)
+			req.RemoteAddr = "203.0.113.5:4321"
+			req.Header.Set("X-Forwarded-For", forwarded)
+			limited(httptest.NewRecorder(), req)
+		}
+
+		assert.Equal(t, []string{"user.login:ip:203.0.113.5", "user.login:ip:203.0.113.5"}, store.keys)
+	})
+
+	t.Run("fails open when the backend errors", func(t *testing.T) {
+		store := &keyRecordingStore{err: errors.New("db down")}
+		limited := middleware.NewRateLimiter(store, nil).Limit("user.login", ratelimit.Limit{Requests: 1, Period: time.Minute})(okHandler)
+
+		w := httptest.NewRecorder()
+		limited(w, httptest.NewRequest(http.MethodPost, "/api/v1/user/login", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"backend/pkg/logger"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDContextKey contextKey = "request_id"
+
+// longest client supplied request id we accept before generating our own
+const maxRequestIDLength = 128
+
+// attaches a request id to every request, reusing the X-Request-ID header
+// sent by the client or a proxy and generating one otherwise. The id is
+// echoed back in the response and added to the request scoped logger
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+		ctx = logger.WithContext(ctx, logger.FromContext(ctx).With("request_id", requestID))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// fetch the request id set by the RequestID middleware
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+// only accept short printable ascii ids so clients can't inject
+// newlines or huge values into our logs
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	idBytes := make([]byte, 16)
+	_, _ = rand.Read(idBytes)
+
+	return hex.EncodeToString(idBytes)
+}
@@ -0,0 +1,149 @@
+//go:build unit
+
+package middleware_test
+
+import (
+	"backend/internal/middleware"
+	"backend/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubValidationStore struct {
+	userId		int
+	username	string
+	err 		error
+	isAdmin		bool
+	adminErr	error
+}
+
+func (s *stubValidationStore) ValidateSession(ctx context.Context, sessionToken string) (int, string, error) {
+	return s.userId, s.username, s.err
+}
+
+func (s *stubValidationStore) IsAdminUser(ctx context.Context, userID int) (bool, error) {
+	return s.isAdmin, s.adminErr
+}
+
+// unit tests for RequestID middleware
+func TestRequestID(t *testing.T) {
+	t.Run("generates a request id when none is sent", func(t *testing.T) {
+		var ctxRequestID string
+		mockHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctxRequestID = middleware.RequestIDFromContext(r.Context())
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		recorder := httptest.NewRecorder()
+
+		middleware.RequestID(mockHandler).ServeHTTP(recorder, req)
+
+		headerID := recorder.Header().Get(middleware.RequestIDHeader)
+		assert.Len(t, headerID, 32, "generated id should be 16 hex encoded bytes")
+		assert.Equal(t, headerID, ctxRequestID, "context id should match response header")
+	})
+
+	t.Run("reuses the client request id", func(t *testing.T) {
+		var ctxRequestID string
+		mockHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctxRequestID = middleware.RequestIDFromContext(r.Context())
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set(middleware.RequestIDHeader, "abc-123")
+		recorder := httptest.NewRecorder()
+
+		middleware.RequestID(mockHandler).ServeHTTP(recorder, req)
+
+		assert.Equal(t, "abc-123", recorder.Header().Get(middleware.RequestIDHeader))
+		assert.Equal(t, "abc-123", ctxRequestID)
+	})
+
+	t.Run("replaces invalid client request ids", func(t *testing.T) {
+		invalidIDs := []string{"has space", "new\nline", strings.Repeat("a", 129)}
+
+		for _, invalidID := range invalidIDs {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set(middleware.RequestIDHeader, invalidID)
+			recorder := httptest.NewRecorder()
+
+			middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(recorder, req)
+
+			assert.NotEqual(t, invalidID, recorder.Header().Get(middleware.RequestIDHeader))
+			assert.Len(t, recorder.Header().Get(middleware.RequestIDHeader), 32)
+		}
+	})
+
+	t.Run("request id is added to the context logger", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		baseLogger := logger.New(&logBuffer, "json", slog.LevelInfo)
+
+		mockHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger.FromContext(r.Context()).Info("inside handler")
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req = req.WithContext(logger.WithContext(req.Context(), baseLogger))
+		req.Header.Set(middleware.RequestIDHeader, "req-1")
+
+		middleware.RequestID(mockHandler).ServeHTTP(httptest.NewRecorder(), req)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(logBuffer.Bytes(), &entry))
+		assert.Equal(t, "req-1", entry["request_id"])
+	})
+}
+
+// unit tests for the access log fields added by AuthMiddleware
+func TestRequestLogUserID(t *testing.T) {
+	t.Run("access log includes user id for authenticated requests", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		baseLogger := logger.New(&logBuffer, "json", slog.LevelInfo)
+
+		m := middleware.NewMiddlewareHandler(&stubValidationStore{userId: 7, username: "user1"})
+		protected := m.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/get/7", nil)
+		req = req.WithContext(logger.WithContext(req.Context(), baseLogger))
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: "token"})
+
+		middleware.RequestID(middleware.Logging(protected)).ServeHTTP(httptest.NewRecorder(), req)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(logBuffer.Bytes(), &entry))
+		assert.Equal(t, float64(7), entry["user_id"])
+		assert.Equal(t, float64(http.StatusOK), entry["status"])
+		assert.NotEmpty(t, entry["request_id"])
+	})
+
+	t.Run("access log has no user id when authentication fails", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		baseLogger := logger.New(&logBuffer, "json", slog.LevelInfo)
+
+		m := middleware.NewMiddlewareHandler(&stubValidationStore{err: errors.New("invalid")})
+		protected := m.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/get/7", nil)
+		req = req.WithContext(logger.WithContext(req.Context(), baseLogger))
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: "token"})
+
+		middleware.RequestID(middleware.Logging(protected)).ServeHTTP(httptest.NewRecorder(), req)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(logBuffer.Bytes(), &entry))
+		assert.NotContains(t, entry, "user_id")
+		assert.Equal(t, float64(http.StatusUnauthorized), entry["status"])
+	})
+}
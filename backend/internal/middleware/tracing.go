@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"backend/pkg/logger"
+	"backend/pkg/tracing"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracing middleware that starts a server span for every request, continuing
+// the trace from an incoming W3C traceparent header if there is one. The span
+// is renamed to the matched route pattern once the router has run
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := tracing.Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		// tie log lines to the trace so they can be looked up from a span
+		spanContext := span.SpanContext()
+		if spanContext.IsValid() {
+			ctx = logger.WithContext(ctx, logger.FromContext(ctx).With("trace_id", spanContext.TraceID().String()))
+		}
+
+		wrapped := &WrappedWriter{
+			ResponseWriter: w,
+			StatusCode: 	http.StatusOK,
+		}
+
+		// the router sets the matched pattern on the request it was handed
+		traced := r.WithContext(ctx)
+		next.ServeHTTP(wrapped, traced)
+
+		if traced.Pattern != "" {
+			span.SetName(traced.Pattern)
+			span.SetAttributes(attribute.String("http.route", traced.Pattern))
+		}
+
+		span.SetAttributes(attribute.Int("http.response.status_code", wrapped.StatusCode))
+		if wrapped.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.StatusCode))
+		}
+	})
+}
@@ -0,0 +1,79 @@
+//go:build unit
+
+package middleware_test
+
+import (
+	"backend/internal/middleware"
+	"backend/pkg/tracing"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// swap the global tracer provider for one that records spans in memory
+func setupSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	original := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	tracing.SetupPropagation()
+	t.Cleanup(func() { otel.SetTracerProvider(original) })
+
+	return recorder
+}
+
+// unit tests for Tracing middleware
+func TestTracing(t *testing.T) {
+	t.Run("names the span after the matched route", func(t *testing.T) {
+		recorder := setupSpanRecorder(t)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v1/products/get/{id...}", func(w http.ResponseWriter, r *http.Request) {})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products/get/2", nil)
+		middleware.Tracing(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "GET /api/v1/products/get/{id...}", spans[0].Name())
+	})
+
+	t.Run("continues the trace from the traceparent header", func(t *testing.T) {
+		recorder := setupSpanRecorder(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		middleware.Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	})
+
+	t.Run("marks server errors on the span", func(t *testing.T) {
+		recorder := setupSpanRecorder(t)
+
+		mockHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		middleware.Tracing(mockHandler).ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+}
@@ -0,0 +1,92 @@
+package normalize
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// spellings of the same unit mapped to the one used in normalized names
+var unitAliases = map[string]string{
+	"inch": 	"in",
+	"inches": 	"in",
+	"\"": 		"in",
+	"lbs": 		"lb",
+	"pound": 	"lb",
+	"pounds": 	"lb",
+	"litre": 	"l",
+	"liter": 	"l",
+	"watt": 	"w",
+	"watts": 	"w",
+}
+
+// a number followed by a unit with an optional space or dash between them,
+// longer units come first so "gb" isn't matched as "g"
+var unitPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*-?\s*(inches|inch|pounds|pound|litre|liter|watts|watt|ghz|mhz|mah|lbs|gb|tb|mb|kb|hz|mm|cm|ml|oz|lb|kg|in|"|w|l|g)`)
+
+// Normalize a product name so trivial variations of the same product map to
+// the same key. Folds unicode compatibility forms, case, whitespace, dashes,
+// quotes, trademark symbols and unit spellings ("16 GB", "16-gb" and "16.0GB"
+// all become "16gb")
+func ProductName(name string) string {
+	// dropped before NFKC which would otherwise turn ™ into "TM"
+	name = strings.Map(func(r rune) rune {
+		if r == '™' || r == '®' || r == '©' {
+			return -1
+		}
+		return r
+	}, name)
+
+	name = strings.ToLower(norm.NFKC.String(name))
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.Is(unicode.Pd, r):
+			return '-'
+		case r == '“' || r == '”' || r == '″':
+			return '"'
+		case unicode.IsSpace(r):
+			return ' '
+		}
+		return r
+	}, name)
+
+	return strings.Join(strings.Fields(normalizeUnits(name)), " ")
+}
+
+// rewrite every standalone number and unit pair in its canonical form,
+// pairs glued to other words like "a16gb" or "16gbps" are left alone
+func normalizeUnits(name string) string {
+	var out strings.Builder
+	last := 0
+
+	for _, match := range unitPattern.FindAllStringSubmatchIndex(name, -1) {
+		start, end := match[0], match[1]
+		if start > 0 && isWordByte(name[start-1]) || end < len(name) && isWordByte(name[end]) {
+			continue
+		}
+
+		number := name[match[2]:match[3]]
+		if strings.Contains(number, ".") {
+			number = strings.TrimRight(strings.TrimRight(number, "0"), ".")
+		}
+
+		unit := name[match[4]:match[5]]
+		if alias, ok := unitAliases[unit]; ok {
+			unit = alias
+		}
+
+		out.WriteString(name[last:start])
+		out.WriteString(number + unit)
+		last = end
+	}
+	out.WriteString(name[last:])
+
+	return out.String()
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '.'
+}
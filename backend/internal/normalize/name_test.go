@@ -0,0 +1,49 @@
+//go:build unit
+
+package normalize_test
+
+import (
+	"backend/internal/normalize"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// unit tests for ProductName function
+func TestProductName(t *testing.T) {
+	t.Run("folds case and whitespace", func(t *testing.T) {
+		assert.Equal(t, "nvidia geforce rtx 4090", normalize.ProductName("  NVIDIA   GeForce\tRTX 4090 "))
+	})
+
+	t.Run("variations of the same product share a key", func(t *testing.T) {
+		variants := []string{
+			"Samsung 980 Pro 2TB SSD",
+			"samsung 980 pro 2 TB ssd",
+			"Samsung® 980 PRO 2-tb SSD",
+			"Ｓａｍｓｕｎｇ 980 Pro 2TB SSD",
+			"Samsung 980 Pro 2.0 TB SSD",
+		}
+
+		for _, variant := range variants {
+			assert.Equal(t, "samsung 980 pro 2tb ssd", normalize.ProductName(variant), variant)
+		}
+	})
+
+	t.Run("unit spellings are unified", func(t *testing.T) {
+		assert.Equal(t, "dell 27in monitor", normalize.ProductName(`Dell 27" Monitor`))
+		assert.Equal(t, "dell 27in monitor", normalize.ProductName("Dell 27 inch Monitor"))
+		assert.Equal(t, "dell 27in monitor", normalize.ProductName("Dell 27-Inches Monitor"))
+		assert.Equal(t, "kettlebell 20lb", normalize.ProductName("Kettlebell 20 lbs"))
+		assert.Equal(t, "16gb ddr5 - 6000mhz", normalize.ProductName("16 GB DDR5 – 6000 MHz"))
+	})
+
+	t.Run("trademark symbols are dropped", func(t *testing.T) {
+		assert.Equal(t, "logitech mx master 3s", normalize.ProductName("Logitech™ MX Master® 3S"))
+	})
+
+	t.Run("numbers glued to words are left alone", func(t *testing.T) {
+		assert.Equal(t, "a16gb 10gbps", normalize.ProductName("A16GB 10Gbps"))
+		assert.Equal(t, "rtx 4090", normalize.ProductName("RTX 4090"))
+		assert.Equal(t, "version 1.05", normalize.ProductName("Version 1.05"))
+	})
+}
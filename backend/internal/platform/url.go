@@ -0,0 +1,323 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	Amazon 	= "amazon"
+	Ebay 	= "ebay"
+	Newegg 	= "newegg"
+	BestBuy = "bestbuy"
+)
+
+// names of the platforms as the stores write them
+var displayNames = map[string]string{
+	Amazon: 	"Amazon",
+	Ebay: 		"eBay",
+	Newegg: 	"Newegg",
+	BestBuy: 	"Best Buy",
+}
+
+// Whether name is one of the platforms above
+func Supported(name string) bool {
+	_, ok := displayNames[name]
+	return ok
+}
+
+var (
+	ErrInvalidURL 		= errors.New("invalid product url")
+	ErrUnsupported 		= errors.New("unsupported platform")
+	ErrNoProductID 		= errors.New("no product id found in url")
+	ErrShortenedURL 	= errors.New("shortened links are not supported, use the full product url")
+)
+
+// A product listing recognized from a store url
+type Listing struct {
+	Platform 	string
+	ProductID 	string	// ASIN, eBay item id, Newegg item number or Best Buy SKU, see regionalID
+	URL 		string	// canonical url without tracking params
+	Name 		string	// best guess from the url slug, empty when the url has none
+}
+
+var (
+	amazonASIN 		= regexp.MustCompile(`^[A-Z0-9]{10}$`)
+	ebayItemID 		= regexp.MustCompile(`^\d{9,15}$`)
+	neweggItem 		= regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]{5,29}$`)
+	bestBuySKU 		= regexp.MustCompile(`^\d{6,8}$`)
+	bestBuySKUPath 	= regexp.MustCompile(`^(\d{6,8})\.p$`)
+)
+
+// hosts of link shorteners the stores hand out in share buttons, these
+// need a request to resolve so they're rejected instead
+var shortenerHosts = map[string]bool{
+	"a.co": 		true,
+	"amzn.to": 		true,
+	"amzn.eu": 		true,
+	"ebay.us": 		true,
+	"bby.us": 		true,
+}
+
+// domains of the stores a listing can be added from, anything else is
+// unsupported even when it looks like a store so lookalikes such as
+// amazon.xyz or amazon.co.attacker are never stored and scraped
+var storeDomains = map[string]string{
+	"amazon.com": 		Amazon,
+	"amazon.ca": 		Amazon,
+	"amazon.com.mx": 	Amazon,
+	"amazon.com.br": 	Amazon,
+	"amazon.co.uk": 	Amazon,
+	"amazon.ie": 		Amazon,
+	"amazon.de": 		Amazon,
+	"amazon.fr": 		Amazon,
+	"amazon.it": 		Amazon,
+	"amazon.es": 		Amazon,
+	"amazon.nl": 		Amazon,
+	"amazon.se": 		Amazon,
+	"amazon.pl": 		Amazon,
+	"amazon.com.be": 	Amazon,
+	"amazon.com.tr": 	Amazon,
+	"amazon.ae": 		Amazon,
+	"amazon.sa": 		Amazon,
+	"amazon.in": 		Amazon,
+	"amazon.co.jp": 	Amazon,
+	"amazon.com.au": 	Amazon,
+	"amazon.sg": 		Amazon,
+	"ebay.com": 		Ebay,
+	"ebay.ca": 			Ebay,
+	"ebay.co.uk": 		Ebay,
+	"ebay.ie": 			Ebay,
+	"ebay.de": 			Ebay,
+	"ebay.at": 			Ebay,
+	"ebay.ch": 			Ebay,
+	"ebay.fr": 			Ebay,
+	"ebay.it": 			Ebay,
+	"ebay.es": 			Ebay,
+	"ebay.nl": 			Ebay,
+	"ebay.be": 			Ebay,
+	"ebay.pl": 			Ebay,
+	"ebay.com.au": 		Ebay,
+	"newegg.com": 		Newegg,
+	"newegg.ca": 		Newegg,
+	"bestbuy.com": 		BestBuy,
+	"bestbuy.ca": 		BestBuy,
+}
+
+// home store of the platforms whose regional stores keep their own catalog,
+// the same ASIN or SKU on amazon.co.uk or bestbuy.ca is another listing in
+// another currency. eBay item ids are the same listing on every site
+var homeDomains = map[string]string{
+	Amazon: 	"amazon.com",
+	Newegg: 	"newegg.com",
+	BestBuy: 	"bestbuy.com",
+}
+
+// product id of a listing as it's stored, ids from a regional store are
+// prefixed with its domain (amazon.co.uk:B09XS7JWHH) so each store is its own
+// source, ids from the home store are kept as they are
+func regionalID(platform, domain, id string) string {
+	if home, ok := homeDomains[platform]; ok && domain != home {
+		return domain + ":" + id
+	}
+	return id
+}
+
+// Recognize an Amazon, eBay, Newegg or Best Buy product url, pull out the
+// platform's product id and build a canonical url for it
+func ParseURL(rawURL string) (Listing, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return Listing{}, ErrInvalidURL
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if shortenerHosts[host] {
+		return Listing{}, ErrShortenedURL
+	}
+
+	domain, store := storeDomain(host)
+	segments := pathSegments(u.Path)
+
+	switch store {
+	case Amazon:
+		return parseAmazon(domain, segments)
+	case Ebay:
+		return parseEbay(domain, segments)
+	case Newegg:
+		return parseNewegg(domain, segments, u.Query())
+	case BestBuy:
+		return parseBestBuy(domain, segments, u.Query())
+	}
+
+	return Listing{}, fmt.Errorf("%w: %s", ErrUnsupported, host)
+}
+
+// the host's store domain and platform, subdomains like smile. or m. are
+// dropped so every url of a store uses the same host. Hosts outside
+// storeDomains return an empty platform
+func storeDomain(host string) (string, string) {
+	for domain := host; ; {
+		if store, ok := storeDomains[domain]; ok {
+			return domain, store
+		}
+
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return "", ""
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// amazon.com/<slug>/dp/<asin>, /gp/product/<asin>, /gp/aw/d/<asin> and
+// /exec/obidos/ASIN/<asin>
+func parseAmazon(host string, segments []string) (Listing, error) {
+	for i, segment := range segments {
+		if i+1 >= len(segments) {
+			break
+		}
+
+		marker := strings.ToLower(segment)
+		if marker != "dp" && marker != "product" && marker != "d" && marker != "asin" {
+			continue
+		}
+
+		asin := strings.ToUpper(segments[i+1])
+		if !amazonASIN.MatchString(asin) {
+			continue
+		}
+
+		name := ""
+		if marker == "dp" && i > 0 {
+			name = slugName(segments[i-1])
+		}
+
+		return Listing{
+			Platform: 	Amazon,
+			ProductID: 	regionalID(Amazon, host, asin),
+			URL: 		fmt.Sprintf("https://www.%s/dp/%s", host, asin),
+			Name: 		name,
+		}, nil
+	}
+
+	return Listing{}, fmt.Errorf("%w: expected an amazon /dp/ link", ErrNoProductID)
+}
+
+// ebay.com/itm/<id> and ebay.com/itm/<slug>/<id>
+func parseEbay(host string, segments []string) (Listing, error) {
+	if len(segments) >= 2 && segments[0] == "itm" {
+		itemID := segments[len(segments)-1]
+		if ebayItemID.MatchString(itemID) {
+			name := ""
+			if len(segments) == 3 {
+				name = slugName(segments[1])
+			}
+
+			return Listing{
+				Platform: 	Ebay,
+				ProductID: 	itemID,
+				URL: 		fmt.Sprintf("https://www.%s/itm/%s", host, itemID),
+				Name: 		name,
+			}, nil
+		}
+	}
+
+	return Listing{}, fmt.Errorf("%w: expected an ebay /itm/ link", ErrNoProductID)
+}
+
+// newegg.com/<slug>/p/<item> and the older newegg.com/Product/Product.aspx?Item=<item>
+func parseNewegg(host string, segments []string, query url.Values) (Listing, error) {
+	item, name := "", ""
+
+	for i, segment := range segments {
+		if segment == "p" && i+1 < len(segments) {
+			item = strings.ToUpper(segments[i+1])
+			if i > 0 {
+				name = slugName(segments[i-1])
+			}
+			break
+		}
+	}
+	if item == "" {
+		item = strings.ToUpper(query.Get("Item"))
+	}
+
+	if !neweggItem.MatchString(item) {
+		return Listing{}, fmt.Errorf("%w: expected a newegg /p/ link", ErrNoProductID)
+	}
+
+	return Listing{
+		Platform: 	Newegg,
+		ProductID: 	regionalID(Newegg, host, item),
+		URL: 		fmt.Sprintf("https://www.%s/p/%s", host, item),
+		Name: 		name,
+	}, nil
+}
+
+// bestbuy.com/site/<slug>/<sku>.p?skuId=<sku>
+func parseBestBuy(host string, segments []string, query url.Values) (Listing, error) {
+	sku, name := query.Get("skuId"), ""
+
+	if len(segments) >= 2 && segments[0] == "site" {
+		if match := bestBuySKUPath.FindStringSubmatch(segments[len(segments)-1]); match != nil {
+			if sku == "" {
+				sku = match[1]
+			}
+			if len(segments) == 3 {
+				name = slugName(segments[1])
+			}
+		}
+	}
+
+	if !bestBuySKU.MatchString(sku) {
+		return Listing{}, fmt.Errorf("%w: expected a best buy /site/ link with a sku", ErrNoProductID)
+	}
+
+	return Listing{
+		Platform: 	BestBuy,
+		ProductID: 	regionalID(BestBuy, host, sku),
+		URL: 		fmt.Sprintf("https://www.%s/site/%s.p?skuId=%s", host, sku, sku),
+		Name: 		name,
+	}, nil
+}
+
+// placeholder product name for listings whose url has no usable slug,
+// like "Amazon B09XS7JWHH", without the store domain of regional ids
+func (l Listing) FallbackName() string {
+	return displayNames[l.Platform] + " " + l.ProductID[strings.LastIndex(l.ProductID, ":")+1:]
+}
+
+func pathSegments(path string) []string {
+	segments := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// turn a url slug like "Sony-WH-1000XM5-Canceling-Headphones" into a name,
+// slugs that are just ids or too short to be useful give an empty name
+func slugName(slug string) string {
+	slug, err := url.PathUnescape(slug)
+	if err != nil {
+		return ""
+	}
+
+	name := strings.Join(strings.Fields(strings.NewReplacer("-", " ", "_", " ", "+", " ").Replace(slug)), " ")
+	if len(name) < 4 || !strings.Contains(name, " ") {
+		return ""
+	}
+
+	return name
+}
@@ -0,0 +1,134 @@
+//go:build unit
+
+package platform_test
+
+import (
+	"backend/internal/platform"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unit tests for ParseURL function
+func TestParseURL(t *testing.T) {
+	t.Run("recognizes supported platforms", func(t *testing.T) {
+		cases := []struct {
+			url 		string
+			expected 	platform.Listing
+		}{
+			{
+				url: "https://www.amazon.com/Sony-WH-1000XM5-Canceling-Headphones/dp/B09XS7JWHH/ref=sr_1_3?crid=2X&keywords=xm5&tag=affiliate-20",
+				expected: platform.Listing{
+					Platform: 	platform.Amazon,
+					ProductID: 	"B09XS7JWHH",
+					URL: 		"https://www.amazon.com/dp/B09XS7JWHH",
+					Name: 		"Sony WH 1000XM5 Canceling Headphones",
+				},
+			},
+			{
+				url: "smile.amazon.co.uk/gp/product/b09xs7jwhh?psc=1",
+				expected: platform.Listing{
+					Platform: 	platform.Amazon,
+					ProductID: 	"amazon.co.uk:B09XS7JWHH",
+					URL: 		"https://www.amazon.co.uk/dp/B09XS7JWHH",
+				},
+			},
+			{
+				url: "https://www.ebay.com/itm/256348591234?hash=item3baf&_trkparms=ispr%3D1&amdata=enc",
+				expected: platform.Listing{
+					Platform: 	platform.Ebay,
+					ProductID: 	"256348591234",
+					URL: 		"https://www.ebay.com/itm/256348591234",
+				},
+			},
+			{
+				url: "https://m.ebay.co.uk/itm/Logitech-MX-Master-3S/256348591234",
+				expected: platform.Listing{
+					Platform: 	platform.Ebay,
+					ProductID: 	"256348591234",
+					URL: 		"https://www.ebay.co.uk/itm/256348591234",
+					Name: 		"Logitech MX Master 3S",
+				},
+			},
+			{
+				url: "https://www.newegg.com/samsung-980-pro-2tb/p/N82E16820147795?Description=980%20pro&cm_re=980_pro-_-20-147-795-_-Product",
+				expected: platform.Listing{
+					Platform: 	platform.Newegg,
+					ProductID: 	"N82E16820147795",
+					URL: 		"https://www.newegg.com/p/N82E16820147795",
+					Name: 		"samsung 980 pro 2tb",
+				},
+			},
+			{
+				url: "http://www.newegg.com/Product/Product.aspx?Item=N82E16820147795",
+				expected: platform.Listing{
+					Platform: 	platform.Newegg,
+					ProductID: 	"N82E16820147795",
+					URL: 		"https://www.newegg.com/p/N82E16820147795",
+				},
+			},
+			{
+				url: "https://www.bestbuy.com/site/apple-airpods-pro-2nd-generation/6447382.p?skuId=6447382&intl=nosplash&utm_source=feed",
+				expected: platform.Listing{
+					Platform: 	platform.BestBuy,
+					ProductID: 	"6447382",
+					URL: 		"https://www.bestbuy.com/site/6447382.p?skuId=6447382",
+					Name: 		"apple airpods pro 2nd generation",
+				},
+			},
+			{
+				url: "https://www.bestbuy.ca/site/6447382.p",
+				expected: platform.Listing{
+					Platform: 	platform.BestBuy,
+					ProductID: 	"bestbuy.ca:6447382",
+					URL: 		"https://www.bestbuy.ca/site/6447382.p?skuId=6447382",
+				},
+			},
+		}
+
+		for _, c := range cases {
+			listing, err := platform.ParseURL(c.url)
+
+			require.NoError(t, err, c.url)
+			assert.Equal(t, c.expected, listing, c.url)
+		}
+	})
+
+	t.Run("rejects unsupported and invalid urls", func(t *testing.T) {
+		cases := map[string]error{
+			"": 												platform.ErrInvalidURL,
+			"ftp://amazon.com/dp/B09XS7JWHH": 					platform.ErrInvalidURL,
+			"https://www.walmart.com/ip/123456": 				platform.ErrUnsupported,
+			"https://amazon.evil.com/dp/B09XS7JWHH": 			platform.ErrUnsupported,
+			"https://amazon.xyz/dp/B09XS7JWHH": 				platform.ErrUnsupported,
+			"https://www.amazon.co.attacker/dp/B09XS7JWHH": 	platform.ErrUnsupported,
+			"https://amazon.com.evil/dp/B09XS7JWHH": 			platform.ErrUnsupported,
+			"https://myamazon.com/dp/B09XS7JWHH": 				platform.ErrUnsupported,
+			"https://ebay.evil/itm/256348591234": 				platform.ErrUnsupported,
+			"https://smile.ebay.co.attacker/itm/256348591234": 	platform.ErrUnsupported,
+			"https://newegg.co/p/N82E16820147795": 				platform.ErrUnsupported,
+			"https://bestbuy.com.evil/site/6447382.p": 			platform.ErrUnsupported,
+			"https://amzn.to/3xYz": 							platform.ErrShortenedURL,
+			"https://www.amazon.com/s?k=headphones": 			platform.ErrNoProductID,
+			"https://www.ebay.com/sch/i.html?_nkw=mouse": 		platform.ErrNoProductID,
+			"https://www.newegg.com/p/pl?d=ssd": 				platform.ErrNoProductID,
+			"https://www.bestbuy.com/site/searchpage.jsp": 		platform.ErrNoProductID,
+		}
+
+		for rawURL, expected := range cases {
+			_, err := platform.ParseURL(rawURL)
+			assert.ErrorIs(t, err, expected, rawURL)
+		}
+	})
+}
+
+// unit tests for Listing.FallbackName method
+func TestFallbackName(t *testing.T) {
+	for _, rawURL := range []string{"https://www.amazon.com/dp/B09XS7JWHH", "https://www.amazon.de/dp/B09XS7JWHH"} {
+		listing, err := platform.ParseURL(rawURL)
+
+		require.NoError(t, err, rawURL)
+		assert.Equal(t, "Amazon B09XS7JWHH", listing.FallbackName(), rawURL)
+	}
+}
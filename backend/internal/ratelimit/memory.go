@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// how often idle buckets are swept out of the memory store
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens		float64
+	updatedAt	time.Time
+	limit		Limit
+}
+
+// in memory token buckets for a single replica, use the postgres backend
+// when running more than one replica so they share the same buckets
+type MemoryStore struct {
+	mu			sync.Mutex
+	buckets		map[string]*bucket
+	lastSweep	time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: 	make(map[string]*bucket),
+		lastSweep: 	time.Now(),
+	}
+}
+
+// Take a token from the bucket for key, creating a full bucket if needed
+func (m *MemoryStore) TakeRateLimitToken(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updatedAt: now}
+		m.buckets[key] = b
+	}
+
+	tokens, result := Take(b.tokens, now.Sub(b.updatedAt), limit)
+	b.tokens = tokens
+	b.updatedAt = now
+	b.limit = limit
+
+	return result, nil
+}
+
+// drop buckets that have refilled completely, they behave the same as a
+// missing bucket so keeping them around only costs memory
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if now.Sub(b.updatedAt) >= b.limit.Period {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// token bucket limit allowing Requests requests per Period, the bucket holds
+// at most Requests tokens and refills continuously over the period
+type Limit struct {
+	Requests	int
+	Period		time.Duration
+}
+
+type Result struct {
+	Allowed		bool
+	Limit		int
+	Remaining	int
+	// time until the bucket is full again
+	Reset		time.Duration
+	// time until the next request would be allowed, zero when allowed
+	RetryAfter	time.Duration
+}
+
+// Parse a limit written as requests/period, ex: 5/1m
+func ParseLimit(value string) (Limit, error) {
+	requests, period, found := strings.Cut(value, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected requests/period like 5/1m", value)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || count < 1 {
+		return Limit{}, fmt.Errorf("invalid rate limit request count %q", requests)
+	}
+
+	duration, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit period %q", period)
+	}
+
+	return Limit{Requests: count, Period: duration}, nil
+}
+
+// String and Set let a Limit be used directly as a flag value
+func (l *Limit) String() string {
+	if l == nil || l.Requests == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+func (l *Limit) Set(value string) error {
+	parsed, err := ParseLimit(value)
+	if err != nil {
+		return err
+	}
+
+	*l = parsed
+	return nil
+}
+
+func (l Limit) Validate() error {
+	if l.Requests < 1 || l.Period <= 0 {
+		return errors.New("rate limit needs at least 1 request and a positive period")
+	}
+	return nil
+}
+
+// tokens added to the bucket per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Refill a bucket that last had tokens left elapsed ago and try to take one
+// token from it. Returns the tokens left in the bucket and the outcome, shared
+// by every backend so they all limit the same way
+func Take(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	capacity := float64(limit.Requests)
+	rate := limit.rate()
+
+	if elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed.Seconds()*rate)
+	}
+
+	result := Result{Limit: limit.Requests}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = secondsToDuration((capacity - tokens) / rate)
+
+	return tokens, result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
@@ -0,0 +1,98 @@
+//go:build unit
+
+package ratelimit_test
+
+import (
+	"backend/internal/ratelimit"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unit tests for ParseLimit function
+func TestParseLimit(t *testing.T) {
+	t.Run("parses requests and period", func(t *testing.T) {
+		limit, err := ratelimit.ParseLimit("5/1m")
+
+		require.NoError(t, err)
+		assert.Equal(t, ratelimit.Limit{Requests: 5, Period: time.Minute}, limit)
+		assert.Equal(t, "5/1m0s", limit.String())
+	})
+
+	t.Run("rejects invalid limits", func(t *testing.T) {
+		for _, value := range []string{"", "5", "0/1m", "x/1m", "5/soon", "5/-1m"} {
+			_, err := ratelimit.ParseLimit(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+// unit tests for Take function
+func TestTake(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 10, Period: 10 * time.Second}
+
+	t.Run("takes a token from a full bucket", func(t *testing.T) {
+		tokens, result := ratelimit.Take(10, 0, limit)
+
+		assert.Equal(t, 9.0, tokens)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 9, result.Remaining)
+		assert.Equal(t, 10, result.Limit)
+		assert.Equal(t, time.Second, result.Reset, "one token refills in a second")
+	})
+
+	t.Run("denies an empty bucket with retry after", func(t *testing.T) {
+		tokens, result := ratelimit.Take(0.5, 0, limit)
+
+		assert.Equal(t, 0.5, tokens, "denied requests don't use up tokens")
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	})
+
+	t.Run("refills over time up to capacity", func(t *testing.T) {
+		tokens, result := ratelimit.Take(0, 3*time.Second, limit)
+		assert.Equal(t, 2.0, tokens)
+		assert.True(t, result.Allowed)
+
+		tokens, _ = ratelimit.Take(0, time.Hour, limit)
+		assert.Equal(t, 9.0, tokens, "bucket never holds more than its capacity")
+	})
+}
+
+// unit tests for MemoryStore
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	limit := ratelimit.Limit{Requests: 3, Period: time.Hour}
+
+	t.Run("allows a burst up to the limit then denies", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+
+		for i := 0; i < 3; i++ {
+			result, err := store.TakeRateLimitToken(ctx, "key", limit)
+			require.NoError(t, err)
+			assert.True(t, result.Allowed, "request %d should be allowed", i+1)
+			assert.Equal(t, 2-i, result.Remaining)
+		}
+
+		result, err := store.TakeRateLimitToken(ctx, "key", limit)
+		require.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Positive(t, result.RetryAfter)
+	})
+
+	t.Run("keys have separate buckets", func(t *testing.T) {
+		store := ratelimit.NewMemoryStore()
+
+		for i := 0; i < 3; i++ {
+			_, _ = store.TakeRateLimitToken(ctx, "user:1", limit)
+		}
+
+		result, err := store.TakeRateLimitToken(ctx, "user:2", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"backend/internal/handler"
+	"backend/internal/middleware"
+	"backend/internal/store"
+	"net/http"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// shared dependencies handed to every route group
+type Dependencies struct {
+	Pool		*pgxpool.Pool
+	Config		*config.Config
+	RateLimits	store.RateLimitStore
+}
+
+func (d Dependencies) rateLimiter() *middleware.RateLimiter {
+	return middleware.NewRateLimiter(d.RateLimits, d.Config.RateLimit.TrustedProxyPrefixes())
+}
+
+// registers the routes for one domain of the api on the mux
+type RouteGroup func(mux *http.ServeMux, deps Dependencies)
+
+// Product routes, every route except the public share views requires a
+// valid session
+func RegisterProductRoutes(mux *http.ServeMux, deps Dependencies) {
+	productRepo := db.NewRepository(deps.Pool)
+	userRepo := db.NewRepository(deps.Pool)
+
+	h := handler.NewProductHandler(productRepo)
+	c := handler.NewCollectionHandler(productRepo)
+	s := handler.NewShareHandler(productRepo)
+	e := handler.NewExportHandler(productRepo)
+	m := middleware.NewMiddlewareHandler(userRepo)
+
+	limits := deps.Config.RateLimit
+	limiter := deps.rateLimiter()
+
+	// adding a product kicks off scraping so it gets a stricter limit
+	mux.HandleFunc("POST /api/v1/products/add/name", m.AuthMiddleware(limiter.Limit("products.add_name", limits.AddProduct)(h.AddProductName)))
+	mux.HandleFunc("POST /api/v1/products/add/url", m.AuthMiddleware(limiter.Limit("products.add_url", limits.AddProduct)(h.AddProductURL)))
+	mux.HandleFunc("POST /api/v1/watchlist/import", m.AuthMiddleware(limiter.Limit("watchlist.import", limits.Import)(h.ImportWatchlist)))
+	mux.HandleFunc("POST /api/v1/watchlist/batch", m.AuthMiddleware(limiter.Limit("watchlist.batch", limits.AddProduct)(h.BatchWatchlist)))
+	mux.HandleFunc("GET /api/v1/products/get/{id...}", m.AuthMiddleware(limiter.Limit("products.get", limits.Default)(h.GetUserTrackedProducts)))
+	mux.HandleFunc("GET /api/v1/products/{id}", m.AuthMiddleware(limiter.Limit("products.detail", limits.Default)(h.GetProduct)))
+	mux.HandleFunc("GET /api/v1/products/{id}/{resource...}", m.AuthMiddleware(limiter.Limit("products.resources", limits.Default)(h.GetProductResource)))
+	mux.HandleFunc("POST /api/v1/products/{id}/alerts", m.AuthMiddleware(limiter.Limit("alerts.create", limits.Default)(h.CreateAlert)))
+	mux.HandleFunc("DELETE /api/v1/alerts/{alert_id}", m.AuthMiddleware(limiter.Limit("alerts.delete", limits.Default)(h.DeleteAlert)))
+	mux.HandleFunc("GET /api/v1/products/search", m.AuthMiddleware(limiter.Limit("products.search", limits.Default)(h.SearchProducts)))
+	mux.HandleFunc("DELETE /api/v1/products/delete", m.AuthMiddleware(limiter.Limit("products.delete", limits.Default)(h.DeleteProduct)))
+	mux.HandleFunc("PATCH /api/v1/watchlist/{product_id}", m.AuthMiddleware(limiter.Limit("watchlist.update", limits.Default)(h.UpdateWatchlistEntry)))
+	mux.HandleFunc("GET /api/v1/watchlist/tags", m.AuthMiddleware(limiter.Limit("watchlist.tags", limits.Default)(c.GetTags)))
+
+	mux.HandleFunc("GET /api/v1/collections", m.AuthMiddleware(limiter.Limit("collections.list", limits.Default)(c.GetCollections)))
+	mux.HandleFunc("POST /api/v1/collections", m.AuthMiddleware(limiter.Limit("collections.create", limits.Default)(c.CreateCollection)))
+	mux.HandleFunc("GET /api/v1/collections/{collection_id}", m.AuthMiddleware(limiter.Limit("collections.get", limits.Default)(c.GetCollection)))
+	mux.HandleFunc("PATCH /api/v1/collections/{collection_id}", m.AuthMiddleware(limiter.Limit("collections.update", limits.Default)(c.UpdateCollection)))
+	mux.HandleFunc("DELETE /api/v1/collections/{collection_id}", m.AuthMiddleware(limiter.Limit("collections.delete", limits.Default)(c.DeleteCollection)))
+	mux.HandleFunc("PUT /api/v1/collections/{collection_id}/items/{product_id}", m.AuthMiddleware(limiter.Limit("collections.items", limits.Default)(c.PutCollectionItem)))
+	mux.HandleFunc("DELETE /api/v1/collections/{collection_id}/items/{product_id}", m.AuthMiddleware(limiter.Limit("collections.items", limits.Default)(c.DeleteCollectionItem)))
+	mux.HandleFunc("POST /api/v1/collections/{collection_id}/members", m.AuthMiddleware(limiter.Limit("collections.members", limits.Default)(c.AddCollectionMember)))
+	mux.HandleFunc("PATCH /api/v1/collections/{collection_id}/members/{user_id}", m.AuthMiddleware(limiter.Limit("collections.members", limits.Default)(c.UpdateCollectionMember)))
+	mux.HandleFunc("DELETE /api/v1/collections/{collection_id}/members/{user_id}", m.AuthMiddleware(limiter.Limit("collections.members", limits.Default)(c.DeleteCollectionMember)))
+
+	mux.HandleFunc("GET /api/v1/shares", m.AuthMiddleware(limiter.Limit("shares.list", limits.Default)(s.GetShareLinks)))
+	mux.HandleFunc("POST /api/v1/shares", m.AuthMiddleware(limiter.Limit("shares.create", limits.Default)(s.CreateShareLink)))
+	mux.HandleFunc("DELETE /api/v1/shares/{share_id}", m.AuthMiddleware(limiter.Limit("shares.revoke", limits.Default)(s.RevokeShareLink)))
+
+	// streams the whole price history so it can take a while
+	mux.HandleFunc("GET /api/v1/export", m.AuthMiddleware(limiter.Limit("export", limits.Default)(e.ExportWatchlist)))
+
+	// public read only views of share links, the token is the only credential
+	mux.HandleFunc("GET /api/v1/public/shares/{token}", limiter.Limit("public.shares", limits.Default)(s.GetPublicShare))
+	mux.HandleFunc("GET /api/v1/public/shares/{token}/embed", limiter.Limit("public.shares", limits.Default)(s.GetPublicShareEmbed))
+
+	// catalog maintenance, only for users with the admin flag
+	mux.HandleFunc("POST /api/v1/admin/products/merge", m.AuthMiddleware(m.AdminMiddleware(limiter.Limit("admin.products.merge", limits.Default)(h.MergeProducts))))
+}
+
+// User account routes for signing up, logging in and the user's preferences
+func RegisterUserRoutes(mux *http.ServeMux, deps Dependencies) {
+	cfg := deps.Config
+	userRepo := db.NewRepository(deps.Pool, db.WithSessionTTL(cfg.Session.TTL))
+	m := middleware.NewMiddlewareHandler(userRepo)
+
+	// already validated when the config was loaded
+	sameSite, _ := cfg.Cookie.SameSiteMode()
+	h := handler.NewUserHandler(userRepo, handler.WithSessionCookie(handler.SessionCookie{
+		MaxAge: 	cfg.Session.TTL,
+		Secure: 	cfg.Cookie.Secure,
+		SameSite: 	sameSite,
+		Domain: 	cfg.Cookie.Domain,
+	}))
+
+	limits := cfg.RateLimit
+	limiter := deps.rateLimiter()
+
+	// login is keyed per ip and kept strict to slow down password guessing
+	mux.HandleFunc("POST /api/v1/user/login", limiter.Limit("user.login", limits.Login)(h.UserLogin))
+	mux.HandleFunc("POST /api/v1/user/signup", limiter.Limit("user.signup", limits.Default)(h.UserSignUp))
+
+	mux.HandleFunc("GET /api/v1/user/preferences", m.AuthMiddleware(limiter.Limit("user.preferences", limits.Default)(h.GetPreferences)))
+	mux.HandleFunc("PUT /api/v1/user/preferences", m.AuthMiddleware(limiter.Limit("user.preferences", limits.Default)(h.UpdatePreferences)))
+	mux.HandleFunc("PATCH /api/v1/user/preferences", m.AuthMiddleware(limiter.Limit("user.preferences", limits.Default)(h.UpdatePreferences)))
+}
+
+// Mount the route groups on a new mux wrapped in the middleware shared by
+// every service
+func NewRouter(deps Dependencies, groups ...RouteGroup) http.Handler {
+	cfg := deps.Config
+	router := http.NewServeMux()
+
+	// lets the frontend fetch a csrf token before its first POST
+	router.HandleFunc("GET /api/v1/csrf", handler.CSRFToken)
+
+	for _, register := range groups {
+		register(router, deps)
+	}
+
+	// already validated when the config was loaded
+	sameSite, _ := cfg.Cookie.SameSiteMode()
+
+	cors := middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins: 	cfg.CORS.AllowedOrigins,
+		AllowCredentials: 	cfg.CORS.AllowCredentials,
+		MaxAge: 			cfg.CORS.MaxAge,
+	})
+	csrf := middleware.CSRF(middleware.CSRFOptions{
+		Secure: 	cfg.Cookie.Secure,
+		SameSite: 	sameSite,
+		Domain: 	cfg.Cookie.Domain,
+	})
+
+	// cors runs before csrf so preflight requests are answered without a token
+	return middleware.RequestID(middleware.Tracing(middleware.Logging(cors(csrf(router)))))
+}
@@ -0,0 +1,126 @@
+//go:build unit
+
+package server_test
+
+import (
+	"backend/internal/config"
+	"backend/internal/middleware"
+	"backend/internal/ratelimit"
+	"backend/internal/server"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDependencies() server.Dependencies {
+	return server.Dependencies{
+		Config: &config.Config{
+			Session: 	config.SessionConfig{TTL: time.Hour},
+			Cookie: 	config.CookieConfig{Secure: true, SameSite: "strict"},
+			RateLimit: 	config.RateLimitConfig{
+				Default: 	ratelimit.Limit{Requests: 100, Period: time.Minute},
+				Login: 		ratelimit.Limit{Requests: 2, Period: time.Minute},
+				AddProduct: ratelimit.Limit{Requests: 2, Period: time.Minute},
+				Import: 	ratelimit.Limit{Requests: 2, Period: time.Minute},
+			},
+		},
+		RateLimits: ratelimit.NewMemoryStore(),
+	}
+}
+
+// attach a matching csrf cookie and header to a state changing request
+func withCSRFToken(req *http.Request) *http.Request {
+	req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: "token"})
+	req.Header.Set(middleware.CSRFHeader, "token")
+	return req
+}
+
+// unit tests for NewRouter function
+func TestNewRouter(t *testing.T) {
+	t.Run("combined router mounts every route group", func(t *testing.T) {
+		router := server.NewRouter(testDependencies(), server.RegisterUserRoutes, server.RegisterProductRoutes)
+
+		// product routes are mounted behind the auth middleware
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, withCSRFToken(httptest.NewRequest(http.MethodDelete, "/api/v1/products/delete", nil)))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// user routes are mounted, wrong method means the path matched
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/user/login", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+
+	t.Run("split router only serves its own group", func(t *testing.T) {
+		router := server.NewRouter(testDependencies(), server.RegisterUserRoutes)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, withCSRFToken(httptest.NewRequest(http.MethodDelete, "/api/v1/products/delete", nil)))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("shared middleware wraps the router", func(t *testing.T) {
+		router := server.NewRouter(testDependencies(), server.RegisterProductRoutes)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+		assert.NotEmpty(t, w.Header().Get(middleware.RequestIDHeader), "request id middleware should run for every route")
+	})
+
+	t.Run("state changing routes require a csrf token", func(t *testing.T) {
+		router := server.NewRouter(testDependencies(), server.RegisterUserRoutes, server.RegisterProductRoutes)
+
+		for _, path := range []string{"/api/v1/user/login", "/api/v1/user/signup", "/api/v1/products/add/name"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+			assert.Equal(t, http.StatusForbidden, w.Code, path)
+		}
+	})
+
+	t.Run("csrf token route returns the cookie token", func(t *testing.T) {
+		router := server.NewRouter(testDependencies())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/csrf", nil))
+
+		var response map[string]string
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, cookies[0].Value, response["csrf_token"])
+	})
+
+	t.Run("preflight from the frontend is answered", func(t *testing.T) {
+		deps := testDependencies()
+		deps.Config.CORS = config.CORSConfig{AllowedOrigins: []string{"http://localhost:5173"}, AllowCredentials: true}
+		router := server.NewRouter(deps, server.RegisterProductRoutes)
+
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/products/delete", nil)
+		req.Header.Set("Origin", "http://localhost:5173")
+		req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "http://localhost:5173", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("login is rate limited per ip", func(t *testing.T) {
+		router := server.NewRouter(testDependencies(), server.RegisterUserRoutes)
+
+		var w *httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, withCSRFToken(httptest.NewRequest(http.MethodPost, "/api/v1/user/login", nil)))
+		}
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, "third login within a minute should be limited")
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"backend/internal/ratelimit"
+	"backend/pkg/logger"
+	"backend/pkg/tracing"
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// how long in flight requests get to finish once a shutdown signal arrives
+const shutdownTimeout = 10 * time.Second
+
+// Load the config, set up logging, tracing and the db pool, then serve the
+// given route groups until the process receives SIGINT or SIGTERM
+func Run(serviceName string, args []string, groups ...RouteGroup) {
+	// settings come from flags, env vars or an optional .env file (see -help)
+	cfg, err := config.Load(serviceName, args)
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	slog.SetDefault(logger.New(os.Stdout, cfg.Log.Format, logger.ParseLevel(cfg.Log.Level)))
+	cfg.LogEffective(slog.Default())
+
+	tracing.SetupPropagation()
+	if cfg.Tracing.Enabled() {
+		shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.ServiceName, cfg.Tracing.EndpointURL)
+		if err != nil {
+			slog.Error("tracing setup failed", "err", err)
+			os.Exit(1)
+		}
+		defer func() { _ = shutdownTracing(context.Background()) }()
+	}
+
+	pool := db.ConnectionPool(cfg.Database)
+	defer pool.Close() // cleanup if run exits normally
+
+	deps := Dependencies{Pool: pool, Config: cfg}
+	if cfg.RateLimit.Backend == "postgres" {
+		deps.RateLimits = db.NewRepository(pool)
+	} else {
+		deps.RateLimits = ratelimit.NewMemoryStore()
+	}
+
+	server := &http.Server{
+		Addr: 		cfg.Server.ListenAddr,
+		Handler: 	NewRouter(deps, groups...),
+	}
+
+	// go channel for listening to sigint/sigterm signals for graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	// trigger sigChan channel if app recieves either SIGTERM or SIGINT indicating it should shutdown
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	// http server started as a goroutine so sigint/sigterm can shut it down
+	go func() {
+		slog.Info("server running", "service", serviceName, "addr", cfg.Server.ListenAddr)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server failed to start", "err", err)
+			os.Exit(1)
+		}
+	}()
+
+	// Wait for shutdown signal, <- blocks pool.close until notify runs
+	<-sigChan
+	slog.Info("shutting down gracefully")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(ctx)
+	if err != nil {
+		slog.Error("server shutdown failed", "err", err)
+	}
+
+	pool.Close()
+	slog.Info("shutdown complete")
+}
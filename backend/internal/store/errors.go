@@ -0,0 +1,15 @@
+package store
+
+import "errors"
+
+// returned by stores when the record a request refers to doesn't exist so
+// handlers can answer with a 404 without knowing the storage details
+var ErrNotFound = errors.New("not found")
+
+// returned when the record exists but the user's role doesn't allow the
+// change, handlers answer with a 403
+var ErrForbidden = errors.New("forbidden")
+
+// returned when a change would leave the record contradicting itself, like a
+// platform both preferred and excluded, handlers answer with a 409
+var ErrConflict = errors.New("conflict")
@@ -1,21 +1,63 @@
 package store
 
 import (
+	"backend/internal/ratelimit"
 	"backend/internal/types"
+	"context"
 	"net/http"
 )
 
 // Repository interface for storing the interfaces
 // used by user handlers
 type UserStore interface {
-	InsertNewUser(username, email, password string) error
-	LoginUser(username, password string) (string, error)
+	InsertNewUser(ctx context.Context, username, email, password string) error
+	LoginUser(ctx context.Context, username, password string) (string, error)
+	FetchUserPreferences(ctx context.Context, userID int) (types.UserPreferences, error)
+	UpdateUserPreferences(ctx context.Context, userID int, preferences types.UserPreferences) (types.UserPreferences, error)
 }
 
 type ProductStore interface {
-	InsertProductForUser(userID int, productName string) (types.Product, error)
-	FetchUserTrackedProducts(userID int) ([]types.UserProduct, error)
-	DeleteProductForUser(userID, productID int) error
+	InsertProductForUser(ctx context.Context, userID int, productName string) (types.Product, error)
+	InsertProductURLForUser(ctx context.Context, userID int, productName string, source types.ProductSource) (types.Product, error)
+	FetchUserTrackedProducts(ctx context.Context, userID int) ([]types.UserProduct, error)
+	FetchUserTrackedProductsPage(ctx context.Context, userID int, query types.ProductListQuery) (types.ProductPage, error)
+	FetchProductDetail(ctx context.Context, userID, productID int) (types.Product, error)
+	DeleteProductForUser(ctx context.Context, userID, productID int) error
+	SearchProducts(ctx context.Context, search string, limit int) ([]types.ProductSearchResult, error)
+	UpdateWatchlistEntry(ctx context.Context, userID, productID int, update types.WatchlistUpdate) (types.WatchlistEntry, error)
+	ImportWatchlist(ctx context.Context, userID int, items []types.ImportItem) ([]types.ImportResult, error)
+	BatchWatchlist(ctx context.Context, userID int, batch types.WatchlistBatch) (types.WatchlistBatchResult, error)
+	FetchProductStats(ctx context.Context, userID, productID int) (types.ProductStats, error)
+	FetchProductForecast(ctx context.Context, userID, productID int) (types.ProductForecast, error)
+	InsertAlertRule(ctx context.Context, userID, productID int, input types.AlertRuleInput) (types.AlertRule, error)
+	DeleteAlertRule(ctx context.Context, userID, alertID int) error
+	MergeProducts(ctx context.Context, duplicateID, canonicalID int) (types.ProductMerge, error)
+	FetchCollectionAccess(ctx context.Context, userID, collectionID int) (types.CollectionAccess, error)
+}
+
+type CollectionStore interface {
+	FetchCollections(ctx context.Context, userID int) ([]types.Collection, error)
+	FetchCollection(ctx context.Context, userID, collectionID int) (types.Collection, error)
+	InsertCollection(ctx context.Context, userID int, input types.CollectionInput) (types.Collection, error)
+	UpdateCollection(ctx context.Context, userID, collectionID int, update types.CollectionUpdate) (types.Collection, error)
+	DeleteCollection(ctx context.Context, userID, collectionID int) error
+	SetCollectionItem(ctx context.Context, userID, collectionID, productID, quantity int) (types.Collection, error)
+	DeleteCollectionItem(ctx context.Context, userID, collectionID, productID int) error
+	FetchUserTags(ctx context.Context, userID int) ([]types.TagCount, error)
+	AddCollectionMember(ctx context.Context, userID, collectionID int, invite types.CollectionInvite) (types.CollectionMember, error)
+	UpdateCollectionMember(ctx context.Context, userID, collectionID, memberID int, role string) (types.CollectionMember, error)
+	DeleteCollectionMember(ctx context.Context, userID, collectionID, memberID int) error
+}
+
+type ShareStore interface {
+	InsertShareLink(ctx context.Context, userID int, input types.ShareLinkInput) (types.ShareLink, error)
+	FetchShareLinks(ctx context.Context, userID int) ([]types.ShareLink, error)
+	RevokeShareLink(ctx context.Context, userID, shareID int) error
+	FetchPublicShare(ctx context.Context, token string) (types.PublicShare, error)
+}
+
+type ExportStore interface {
+	ExportWatchlist(ctx context.Context, userID int, each func(types.ExportRow) error) error
 }
 
 type MiddlewareStore interface {
@@ -24,5 +66,10 @@ type MiddlewareStore interface {
 }
 
 type ValidationStore interface {
-	ValidateSession(sessionToken string) (int, string, error)
+	ValidateSession(ctx context.Context, sessionToken string) (int, string, error)
+	IsAdminUser(ctx context.Context, userID int) (bool, error)
+}
+
+type RateLimitStore interface {
+	TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
 }
@@ -0,0 +1,87 @@
+package types
+
+import "time"
+
+const (
+	CollectionRoleOwner 	= "owner"
+	CollectionRoleEditor 	= "editor"
+	CollectionRoleViewer 	= "viewer"
+)
+
+// a named group of products from a user's watchlist. The total adds up the
+// lowest price of every item times its quantity in the user's display
+// currency, items that have no price yet are left out and counted instead
+type Collection struct {
+	ID				int					`json:"collection_id"`
+	OwnerID			int					`json:"owner_id"`
+	Owner			string				`json:"owner"`
+	// role of the user fetching the collection
+	Role			string				`json:"role"`
+	Name			string				`json:"name"`
+	Description		string				`json:"description"`
+	CreatedAt		time.Time			`json:"created_at"`
+	ItemCount		int					`json:"item_count"`
+	Total			float64				`json:"total"`
+	UnpricedCount	int					`json:"unpriced_count"`
+	Currency		string				`json:"currency"`
+	// only filled when fetching a single collection
+	Items			[]CollectionItem	`json:"items,omitempty"`
+	Members			[]CollectionMember	`json:"members,omitempty"`
+	Alerts			[]CollectionAlert	`json:"alerts,omitempty"`
+}
+
+type CollectionItem struct {
+	UserProduct
+	Quantity	int		`json:"quantity"`
+}
+
+type CollectionInput struct {
+	Name		string	`json:"name" validate:"required,min=1,max=100"`
+	Description	string	`json:"description" validate:"max=1000"`
+}
+
+// fields of a collection to change, nil fields are left as they are
+type CollectionUpdate struct {
+	Name		*string	`json:"name" validate:"omitempty,min=1,max=100"`
+	Description	*string	`json:"description" validate:"omitempty,max=1000"`
+}
+
+// a tag used on the user's watchlist with the number of entries that have it
+type TagCount struct {
+	Tag		string	`json:"tag"`
+	Count	int		`json:"count"`
+}
+
+// what a user may do with a collection, the owner manages it and its members,
+// editors change its items and viewers only read it
+type CollectionAccess struct {
+	CollectionID	int
+	OwnerID			int
+	Role			string
+}
+
+func (a CollectionAccess) CanEdit() bool {
+	return a.Role == CollectionRoleOwner || a.Role == CollectionRoleEditor
+}
+
+type CollectionMember struct {
+	UserID		int			`json:"user_id"`
+	Username	string		`json:"username"`
+	Role		string		`json:"role"`
+	AddedAt		time.Time	`json:"added_at"`
+}
+
+// share a collection with a user found by username or email
+type CollectionInvite struct {
+	Username	string	`json:"username" validate:"required_without=Email,omitempty,max=50"`
+	Email		string	`json:"email" validate:"required_without=Username,omitempty,email"`
+	Role		string	`json:"role" validate:"required,oneof=viewer editor"`
+}
+
+// an alert rule the owner or a member has on one of the collection's items
+type CollectionAlert struct {
+	AlertRule
+	ProductID	int		`json:"product_id"`
+	UserID		int		`json:"user_id"`
+	Username	string	`json:"username"`
+}
@@ -0,0 +1,24 @@
+package types
+
+import "time"
+
+// ECB reference rate, units of the currency per 1 EUR on the date
+type ExchangeRate struct {
+	Date		time.Time	`json:"date"`
+	Currency	string		`json:"currency"`
+	Rate		float64		`json:"rate"`
+}
+
+// currency every user sees prices in until they pick another one
+const DefaultDisplayCurrency = "USD"
+
+const (
+	PriceBasisSticker 	= "sticker"
+	PriceBasisLanded 	= "landed"
+)
+
+// settings a user can change, empty fields are left as they are on update
+type UserPreferences struct {
+	DisplayCurrency		string		`json:"display_currency" validate:"omitempty,iso4217"`
+	PriceBasis			string		`json:"price_basis" validate:"omitempty,oneof=sticker landed"`
+}
@@ -0,0 +1,35 @@
+package types
+
+import "time"
+
+const (
+	ExportFormatCSV 	= "csv"
+	ExportFormatJSON 	= "json"
+	ExportFormatNDJSON 	= "ndjson"
+)
+
+// one price snapshot of a watched product as it was scraped, prices are in
+// the currency of the listing. Products without sources or snapshots get a
+// single row with the source and snapshot fields nil
+type ExportRow struct {
+	ProductID		int			`json:"product_id"`
+	ProductName		string		`json:"product_name"`
+	AddedAt			*time.Time	`json:"added_at"`
+	TargetPrice		*float64	`json:"target_price"`
+	Notes			string		`json:"notes"`
+	Tags			[]string	`json:"tags"`
+	Paused			bool		`json:"paused"`
+	SourceID		*int		`json:"source_id"`
+	Platform		*string		`json:"platform"`
+	ProductURL		*string		`json:"product_url"`
+	Condition		*string		`json:"condition"`
+	SellerName		*string		`json:"seller_name"`
+	SellerRating	*float64	`json:"seller_rating"`
+	Price			*float64	`json:"price"`
+	Currency		*string		`json:"currency"`
+	ShippingCost	*float64	`json:"shipping_cost"`
+	EstimatedTax	*float64	`json:"estimated_tax"`
+	Discount		*float64	`json:"discount"`
+	InStock			*bool		`json:"in_stock"`
+	CheckedAt		*time.Time	`json:"checked_at"`
+}
@@ -0,0 +1,33 @@
+package types
+
+import "time"
+
+// forecast lowest price of a product on a day with its 95% confidence band
+type ForecastPoint struct {
+	Date	time.Time	`json:"date"`
+	Price	float64		`json:"price"`
+	Lower	float64		`json:"lower"`
+	Upper	float64		`json:"upper"`
+}
+
+// one of the user's active predicted_below alerts on the product, the first
+// forecast day below its threshold is nil when the forecast stays above it
+type ForecastAlert struct {
+	AlertRule
+	PredictedAt		*time.Time	`json:"predicted_at"`
+	PredictedPrice	*float64	`json:"predicted_price"`
+}
+
+// daily lowest price of a product projected over the next 30 days from its
+// snapshot history, on the user's price basis in their display currency.
+// Method is empty and points are empty without enough history or when the
+// last snapshot is more than a week old
+type ProductForecast struct {
+	ProductID	int				`json:"product_id"`
+	Currency	string			`json:"currency"`
+	PriceBasis	string			`json:"price_basis"`
+	Method		string			`json:"method"`
+	HistoryDays	int				`json:"history_days"`
+	Points		[]ForecastPoint	`json:"points"`
+	Alerts		[]ForecastAlert	`json:"alerts"`
+}
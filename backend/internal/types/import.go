@@ -0,0 +1,46 @@
+package types
+
+const (
+	ImportStatusCreated 	= "created" // added to the watchlist
+	ImportStatusUpdated 	= "updated" // already watched, target price or tags changed
+	ImportStatusUnchanged 	= "unchanged" // already watched with the same settings
+	ImportStatusInvalid 	= "invalid"
+	ImportStatusFailed 		= "failed"
+)
+
+// one row of an uploaded csv or json file, a name or a store listing url is
+// required. Tags are added to the entry's tags and the target price replaces
+// its target when set
+type ImportRow struct {
+	Name			string		`json:"name" validate:"required_without=URL,omitempty,min=2,max=255"`
+	URL				string		`json:"url" validate:"omitempty,max=2048"`
+	TargetPrice		*float64	`json:"target_price" validate:"omitempty,gte=0"`
+	Tags			[]string	`json:"tags" validate:"omitempty,max=20,dive,min=1,max=30"`
+}
+
+// a validated row ready to insert, Source is set for rows with a url
+type ImportItem struct {
+	Line			int
+	Name			string
+	Source			*ProductSource
+	TargetPrice		*float64
+	Tags			[]string
+}
+
+// the outcome of one row, line is the csv line or json array index from 1
+type ImportResult struct {
+	Line			int		`json:"line"`
+	Status			string	`json:"status"`
+	ProductID		int		`json:"product_id,omitempty"`
+	ProductName		string	`json:"product_name,omitempty"`
+	Error			string	`json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Created		int				`json:"created"`
+	Updated		int				`json:"updated"`
+	Unchanged	int				`json:"unchanged"`
+	Invalid		int				`json:"invalid"`
+	Failed		int				`json:"failed"`
+	Rows		[]ImportResult	`json:"rows"`
+}
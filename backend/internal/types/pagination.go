@@ -0,0 +1,105 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// columns the tracked products list can be sorted by
+const (
+	SortByAddedAt		= "added_at"
+	SortByPrice			= "price"
+	SortByName			= "name"
+	SortByLastChecked	= "last_checked_at"
+	SortByBiggestDrop	= "biggest_drop"
+	SortByDealScore		= "deal_score"
+)
+
+const (
+	SortAsc		= "asc"
+	SortDesc	= "desc"
+)
+
+// Sorting, filtering and paging options for the tracked products list,
+// nil filters are not applied
+type ProductListQuery struct {
+	Sort		string
+	Order		string
+	Limit		int
+	Cursor		*ProductCursor
+	InStock		*bool
+	Sources		[]string
+	MinPrice	*float64
+	MaxPrice	*float64
+	Search		string
+	// products in one of the user's collections
+	CollectionID	*int
+	// products tagged with every one of the tags
+	Tags		[]string
+}
+
+// Position of the last product on a page, the sort value plus the product id
+// as a tie breaker so pages stay stable when values repeat
+type ProductCursor struct {
+	Sort		string	`json:"s"`
+	Order		string	`json:"o"`
+	Value		string	`json:"v"`
+	ProductID	int		`json:"id"`
+}
+
+type ProductPage struct {
+	Products	[]UserProduct
+	// empty when there are no more pages
+	NextCursor	string
+	// products matching the filters across every page
+	TotalCount	int
+}
+
+// Encode the cursor as an opaque url safe string
+func (c ProductCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode a cursor returned by Encode
+func DecodeProductCursor(encoded string) (*ProductCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var cursor ProductCursor
+	err = json.Unmarshal(raw, &cursor)
+	if err != nil || cursor.ProductID <= 0 || !cursor.validValue() {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &cursor, nil
+}
+
+// the value is cast to the sort column's type in the page query, so it has
+// to parse as one or a tampered cursor would fail the query
+func (c ProductCursor) validValue() bool {
+	switch c.Sort {
+	case SortByPrice, SortByBiggestDrop, SortByDealScore:
+		value, err := strconv.ParseFloat(c.Value, 64)
+		return err == nil && !math.IsInf(value, 0) && !math.IsNaN(value)
+	case SortByName:
+		return true
+	case SortByLastChecked:
+		if c.Value == "epoch" {
+			return true
+		}
+		_, err := time.Parse(time.RFC3339Nano, c.Value)
+		return err == nil
+	case SortByAddedAt:
+		_, err := time.Parse(time.RFC3339Nano, c.Value)
+		return err == nil
+	default:
+		return false
+	}
+}
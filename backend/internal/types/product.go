@@ -3,11 +3,113 @@ package types
 import "time"
 
 type Product struct {
-	ID			int			`json:"product_id"`
-	Name 		string		`json:"product_name"`
-	URL 		string		`json:"url"`
-	CreatedAt 	time.Time	`json:"created_at"`
-	Prices		[]PriceData `json:"prices"`
+	ID				int				`json:"product_id"`
+	Name 			string			`json:"product_name"`
+	ImageUrl		string			`json:"image_url"`
+	URL 			string			`json:"url"`
+	CreatedAt 		time.Time		`json:"created_at"`
+	LastCheckedAt	*time.Time		`json:"last_checked_at"`
+	Prices			[]PriceData 	`json:"prices"`
+	Sources			[]ProductOffer	`json:"sources"`
+	Currency		string			`json:"currency"`
+	PriceBasis		string			`json:"price_basis"`
+	LowestEverPrice	float64			`json:"lowest_ever_price"`
+	AvgPrice30d		float64			`json:"avg_price_30d"`
+	Alerts			[]ProductAlert	`json:"alerts"`
+}
+
+// a source of a product with its latest snapshot, price, currency and
+// checked_at are empty when the source hasn't been scraped yet. Costs are in
+// the offer's currency, display_price is the price on the user's price basis
+// in their display currency, 0 when it can't be converted
+type ProductOffer struct {
+	SourceID			int			`json:"source_id"`
+	Platform			string		`json:"platform"`
+	PlatformProductID	string		`json:"platform_product_id"`
+	URL					string		`json:"url"`
+	Price				float64		`json:"price"`
+	Currency			string		`json:"currency"`
+	ShippingCost		float64		`json:"shipping_cost"`
+	EstimatedTax		float64		`json:"estimated_tax"`
+	Discount			float64		`json:"discount"`
+	LandedPrice			float64		`json:"landed_price"`
+	DisplayPrice		float64		`json:"display_price"`
+	InStock				bool		`json:"in_stock"`
+	CheckedAt			*time.Time	`json:"checked_at"`
+	Condition			string		`json:"condition"`
+	SellerName			string		`json:"seller_name"`
+	SellerRating		float64		`json:"seller_rating"`
+	// whether the offer passes the user's condition and seller rating filters
+	Acceptable			bool		`json:"acceptable"`
+	// the advertised discount checked against the source's price history,
+	// nil when the offer claims no discount
+	DiscountCheck		*DiscountCheck	`json:"discount_check,omitempty"`
+}
+
+const (
+	DiscountReasonNeverCharged 	= "list_price_never_charged"
+	DiscountReasonInflated 		= "inflated_discount"
+)
+
+// a discount claimed from a list or "was" price compared to what the source
+// actually charged before, in the offer's currency on the sticker price. The
+// reference price is the lowest price in the 30 days before the offer and
+// the highest price covers 90 days, both are 0 and the check unverified
+// without snapshots in those windows. ActualPercent is negative when the
+// price went up
+type DiscountCheck struct {
+	ListPrice		float64		`json:"list_price"`
+	ClaimedPercent	float64		`json:"claimed_percent"`
+	ReferencePrice	float64		`json:"reference_price"`
+	ActualPercent	float64		`json:"actual_percent"`
+	HighestPrice90d	float64		`json:"highest_price_90d"`
+	Verified		bool		`json:"verified"`
+	Misleading		bool		`json:"misleading"`
+	// why the discount is misleading, empty otherwise
+	Reason			string		`json:"reason,omitempty"`
+}
+
+const (
+	AlertPriceBelow 	= "price_below"
+	AlertPercentDrop 	= "percent_drop"
+	AlertBackInStock 	= "back_in_stock"
+	// threshold is a price in the user's display currency the 30 day
+	// forecast falls below
+	AlertPredictedBelow = "predicted_below"
+)
+
+// price_basis is the basis prices are compared on for the rule, the rule's
+// own or the owner's preference when the rule doesn't set one
+type AlertRule struct {
+	ID			int			`json:"alert_id"`
+	Type		string		`json:"type"`
+	Threshold	float64		`json:"threshold"`
+	PriceBasis	string		`json:"price_basis"`
+	Active		bool		`json:"active"`
+	CreatedAt	time.Time	`json:"created_at"`
+}
+
+// a new alert rule on a product of the user's watchlist. threshold is a price
+// in the user's display currency, a percent for percent_drop and left out for
+// back_in_stock. An empty price_basis follows the user's preference
+type AlertRuleInput struct {
+	Type		string		`json:"type" validate:"required,oneof=price_below percent_drop back_in_stock predicted_below"`
+	Threshold	*float64	`json:"threshold" validate:"required_unless=Type back_in_stock,omitempty,gte=0"`
+	PriceBasis	string		`json:"price_basis" validate:"omitempty,oneof=sticker landed"`
+}
+
+// an alert rule evaluated against the product's latest offers. The current
+// price is the lowest in stock offer passing the user's watchlist filters on
+// the rule's price basis in the user's display currency, 0 without one.
+// predicted_below rules are evaluated by the forecast and never trigger here
+type ProductAlert struct {
+	AlertRule
+	CurrentPrice	float64		`json:"current_price"`
+	// source of the offer setting the current price, 0 without one
+	SourceID		int			`json:"source_id,omitempty"`
+	// that offer claims a discount its price history doesn't back
+	MisleadingDiscount	bool	`json:"misleading_discount"`
+	Triggered		bool		`json:"triggered"`
 }
 
 type PriceData struct {
@@ -17,6 +119,16 @@ type PriceData struct {
 	Timestamp	time.Time	`json:"timestamp"`
 }
 
+// a listing of a product on one store
+type ProductSource struct {
+	Platform			string		`json:"platform"`
+	PlatformProductID	string		`json:"platform_product_id"`
+	URL					string		`json:"url"`
+}
+
+// deal score from which a product is flagged as a good time to buy
+const GoodDealScore = 70
+
 type UserProduct struct {
 	ProductID 		int 		`json:"product_id"`
 	ProductName		string		`json:"product_name"`
@@ -26,4 +138,45 @@ type UserProduct struct {
 	LowestPrice		float64		`json:"lowest_price"`
 	LowestSource	string 		`json:"lowest_source"`
 	InStock			bool		`json:"in_stock"`
-}
\ No newline at end of file
+	PriceDrop		float64		`json:"price_drop"`
+	Currency		string		`json:"currency"`
+	PriceBasis		string		`json:"price_basis"`
+	TargetPrice		float64		`json:"target_price"`
+	Paused			bool		`json:"paused"`
+	Tags			[]string	`json:"tags"`
+	// 0 to 100, higher is a better deal. 0 without enough price history
+	DealScore		float64		`json:"deal_score"`
+	// share of prices in the last 180 days cheaper than the current one, 0 is
+	// the cheapest
+	PricePercentile	float64		`json:"price_percentile"`
+	// percent the current price is above the all time low, which like the
+	// rest of the deal score only looks back 180 days
+	AboveAllTimeLow	float64		`json:"above_all_time_low"`
+	// down, up or flat over the last week, empty without enough history
+	PriceTrend		string		`json:"price_trend"`
+	// deal score of at least GoodDealScore
+	GoodTimeToBuy	bool		`json:"good_time_to_buy"`
+}
+
+type ProductSearchResult struct {
+	ProductID		int			`json:"product_id"`
+	ProductName		string		`json:"product_name"`
+	ImageUrl		string		`json:"image_url"`
+	WatcherCount	int			`json:"watcher_count"`
+	Score			float64		`json:"score"`
+}
+
+type ProductMerge struct {
+	CanonicalID		int			`json:"canonical_id"`
+	DuplicateID		int			`json:"duplicate_id"`
+	Alias			string		`json:"alias"`
+	MovedWatchers	int			`json:"moved_watchers"`
+	MergedWatchers	int			`json:"merged_watchers"`
+	MovedSources	int			`json:"moved_sources"`
+}
+
+// outcome of filling in normalized names on a database created before them
+type NameBackfill struct {
+	Normalized	int
+	Merges		[]ProductMerge
+}
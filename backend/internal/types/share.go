@@ -0,0 +1,73 @@
+package types
+
+import "time"
+
+const (
+	ShareTargetCollection 	= "collection"
+	ShareTargetProduct 		= "product"
+)
+
+// a public read only link to a collection or a product's price history
+type ShareLink struct {
+	ID				int			`json:"share_id"`
+	// only returned when the link is created
+	Token			string		`json:"token,omitempty"`
+	Target			string		`json:"target"`
+	CollectionID	*int		`json:"collection_id,omitempty"`
+	ProductID		*int		`json:"product_id,omitempty"`
+	// nil when the link never expires
+	ExpiresAt		*time.Time	`json:"expires_at"`
+	CreatedAt		time.Time	`json:"created_at"`
+}
+
+// link to create for either a collection or a product, expires_in_hours of 0
+// never expires
+type ShareLinkInput struct {
+	CollectionID	int	`json:"collection_id" validate:"required_without=ProductID,excluded_with=ProductID,omitempty,gte=1"`
+	ProductID		int	`json:"product_id" validate:"required_without=CollectionID,omitempty,gte=1"`
+	ExpiresInHours	int	`json:"expires_in_hours" validate:"gte=0,lte=8760"`
+}
+
+// what a share link shows to anyone with the token, only one of collection
+// and product is set. Notes, tags, members and alerts are left out
+type PublicShare struct {
+	Target		string				`json:"target"`
+	ExpiresAt	*time.Time			`json:"expires_at"`
+	Collection	*PublicCollection	`json:"collection,omitempty"`
+	Product		*PublicProduct		`json:"product,omitempty"`
+}
+
+type PublicCollection struct {
+	Name			string					`json:"name"`
+	Description		string					`json:"description"`
+	Total			float64					`json:"total"`
+	UnpricedCount	int						`json:"unpriced_count"`
+	Currency		string					`json:"currency"`
+	Items			[]PublicCollectionItem	`json:"items"`
+}
+
+type PublicCollectionItem struct {
+	ProductID		int		`json:"product_id"`
+	ProductName		string	`json:"product_name"`
+	ImageUrl		string	`json:"image_url"`
+	LowestPrice		float64	`json:"lowest_price"`
+	LowestSource	string	`json:"lowest_source"`
+	InStock			bool	`json:"in_stock"`
+	Quantity		int		`json:"quantity"`
+}
+
+type PublicProduct struct {
+	ProductID	int					`json:"product_id"`
+	Name		string				`json:"product_name"`
+	ImageUrl	string				`json:"image_url"`
+	History		[]PublicPricePoint	`json:"history"`
+}
+
+// one price snapshot of a source in the currency it was listed in
+type PublicPricePoint struct {
+	Platform	string		`json:"platform"`
+	Price		float64		`json:"price"`
+	Currency	string		`json:"currency"`
+	InStock		bool		`json:"in_stock"`
+	CheckedAt	time.Time	`json:"checked_at"`
+}
@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+// price statistics of a product or one of its sources over every snapshot on
+// the user's price basis, in their display currency unless it has no rates
+// for the currency the stats were built in. Averages, medians and standard deviations cover the
+// last 7, 30 and 90 days and are nil when there were no snapshots in that
+// window. CurrentVsAvg30d is how many percent the current price is above
+// (positive) or below the 30 day average
+type PriceStats struct {
+	SourceID		*int		`json:"source_id,omitempty"`
+	Platform		string		`json:"platform,omitempty"`
+	Currency		string		`json:"currency"`
+	SnapshotCount	int			`json:"snapshot_count"`
+	CurrentPrice	*float64	`json:"current_price"`
+	AllTimeLow		*float64	`json:"all_time_low"`
+	AllTimeLowAt	*time.Time	`json:"all_time_low_at"`
+	AllTimeHigh		*float64	`json:"all_time_high"`
+	AllTimeHighAt	*time.Time	`json:"all_time_high_at"`
+	Avg7d			*float64	`json:"avg_7d"`
+	Median7d		*float64	`json:"median_7d"`
+	StdDev7d		*float64	`json:"stddev_7d"`
+	Avg30d			*float64	`json:"avg_30d"`
+	Median30d		*float64	`json:"median_30d"`
+	StdDev30d		*float64	`json:"stddev_30d"`
+	Avg90d			*float64	`json:"avg_90d"`
+	Median90d		*float64	`json:"median_90d"`
+	StdDev90d		*float64	`json:"stddev_90d"`
+	CurrentVsAvg30d	*float64	`json:"current_vs_avg_30d"`
+	RefreshedAt		time.Time	`json:"refreshed_at"`
+}
+
+// stats of a product across its sources, nil without any prices or before
+// the stats job first built them, and of each source
+type ProductStats struct {
+	ProductID	int				`json:"product_id"`
+	PriceBasis	string			`json:"price_basis"`
+	Overall		*PriceStats		`json:"overall"`
+	Sources		[]PriceStats	`json:"sources"`
+}
@@ -0,0 +1,93 @@
+package types
+
+import "time"
+
+const (
+	ConditionNew 			= "new"
+	ConditionUsed 			= "used"
+	ConditionRefurbished 	= "refurbished"
+)
+
+// a product in a user's watchlist with the user's settings for it. Empty
+// lists and 0 numbers mean the setting is off, a paused entry stays in the
+// watchlist but its alerts don't trigger
+type WatchlistEntry struct {
+	ProductID				int			`json:"product_id"`
+	AddedAt					time.Time	`json:"added_at"`
+	AcceptableConditions	[]string	`json:"acceptable_conditions"`
+	MinSellerRating			float64		`json:"min_seller_rating"`
+	AllowUnratedSellers		bool		`json:"allow_unrated_sellers"`
+	TargetPrice				float64		`json:"target_price"`
+	Notes					string		`json:"notes"`
+	PreferredPlatforms		[]string	`json:"preferred_platforms"`
+	ExcludedPlatforms		[]string	`json:"excluded_platforms"`
+	Paused					bool		`json:"paused"`
+	Tags					[]string	`json:"tags"`
+}
+
+// settings of a watchlist entry to change, nil fields are left as they are.
+// The platform validation is registered by the product handler and accepts
+// the platforms of the platform package
+type WatchlistUpdate struct {
+	AcceptableConditions	*[]string	`json:"acceptable_conditions" validate:"omitempty,dive,oneof=new used refurbished"`
+	MinSellerRating			*float64	`json:"min_seller_rating" validate:"omitempty,gte=0,lte=100"`
+	AllowUnratedSellers		*bool		`json:"allow_unrated_sellers"`
+	TargetPrice				*float64	`json:"target_price" validate:"omitempty,gte=0"`
+	Notes					*string		`json:"notes" validate:"omitempty,max=1000"`
+	PreferredPlatforms		*[]string	`json:"preferred_platforms" validate:"omitempty,dive,platform"`
+	ExcludedPlatforms		*[]string	`json:"excluded_platforms" validate:"omitempty,dive,platform"`
+	Paused					*bool		`json:"paused"`
+	Tags					*[]string	`json:"tags" validate:"omitempty,max=20,dive,min=1,max=30"`
+}
+
+const (
+	BatchModeAtomic 		= "atomic" // one failing operation rolls back the whole batch
+	BatchModeBestEffort 	= "best_effort" // failing operations are skipped
+
+	BatchOpAdd 		= "add"
+	BatchOpDelete 	= "delete"
+	BatchOpUpdate 	= "update"
+
+	BatchStatusOK 			= "ok"
+	BatchStatusFailed 		= "failed"
+	BatchStatusRolledBack 	= "rolled_back" // succeeded but undone by a later failure
+	BatchStatusSkipped 		= "skipped" // not run after an earlier failure
+)
+
+// many watchlist changes run in one transaction, mode defaults to atomic
+type WatchlistBatch struct {
+	Mode		string					`json:"mode" validate:"omitempty,oneof=atomic best_effort"`
+	Operations	[]WatchlistOperation	`json:"operations" validate:"required,min=1,max=100,dive"`
+}
+
+// add takes a product_name or url, delete and update take a product_id and
+// update its settings in update
+type WatchlistOperation struct {
+	Op			string				`json:"op" validate:"required,oneof=add delete update"`
+	ProductName	string				`json:"product_name" validate:"omitempty,min=2"`
+	URL			string				`json:"url" validate:"omitempty,max=2048"`
+	ProductID	int					`json:"product_id" validate:"omitempty,gte=1"`
+	Update		*WatchlistUpdate	`json:"update"`
+	// the listing of an add by url, set by the handler
+	Source		*ProductSource		`json:"-"`
+}
+
+type WatchlistOperationResult struct {
+	Index		int				`json:"index"`
+	Op			string			`json:"op"`
+	Status		string			`json:"status"`
+	ProductID	int				`json:"product_id,omitempty"`
+	ProductName	string			`json:"product_name,omitempty"`
+	// settings after an update
+	Entry		*WatchlistEntry	`json:"entry,omitempty"`
+	Error		string			`json:"error,omitempty"`
+}
+
+// outcome of a batch, committed is false when an atomic batch was rolled back
+type WatchlistBatchResult struct {
+	Mode		string						`json:"mode"`
+	Committed	bool						`json:"committed"`
+	Succeeded	int							`json:"succeeded"`
+	Failed		int							`json:"failed"`
+	Results		[]WatchlistOperationResult	`json:"results"`
+}
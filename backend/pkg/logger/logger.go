@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type contextKey struct{}
+
+// Create a new slog logger writing to w, format selects between
+// "json" and "text" output and defaults to text for anything else
+func New(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	if strings.EqualFold(format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Parse a level name like "debug" or "warn" into a slog level,
+// unknown or empty values fall back to info
+func ParseLevel(level string) slog.Level {
+	var parsed slog.Level
+	err := parsed.UnmarshalText([]byte(level))
+	if err != nil {
+		return slog.LevelInfo
+	}
+
+	return parsed
+}
+
+// Store the logger in the context so request scoped fields like the
+// request id follow the request down into handlers and repositories
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// Fetch the request scoped logger from the context, falls back to the
+// default logger if none was stored
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+
+	return slog.Default()
+}
@@ -0,0 +1,65 @@
+//go:build unit
+
+package logger_test
+
+import (
+	"backend/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unit tests for New function
+func TestNew(t *testing.T) {
+	t.Run("json format writes json lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger.New(&buf, "json", slog.LevelInfo).Info("hello", "key", "value")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "hello", entry["msg"])
+		assert.Equal(t, "value", entry["key"])
+	})
+
+	t.Run("text format is the default", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger.New(&buf, "", slog.LevelInfo).Info("hello", "key", "value")
+
+		assert.Contains(t, buf.String(), "msg=hello")
+		assert.Contains(t, buf.String(), "key=value")
+	})
+
+	t.Run("drops logs below the level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger.New(&buf, "text", slog.LevelWarn).Info("hello")
+
+		assert.Empty(t, buf.String())
+	})
+}
+
+// unit tests for ParseLevel function
+func TestParseLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelDebug, logger.ParseLevel("debug"))
+	assert.Equal(t, slog.LevelError, logger.ParseLevel("ERROR"))
+	assert.Equal(t, slog.LevelInfo, logger.ParseLevel(""))
+	assert.Equal(t, slog.LevelInfo, logger.ParseLevel("verbose"))
+}
+
+// unit tests for WithContext and FromContext functions
+func TestFromContext(t *testing.T) {
+	t.Run("returns default logger when none stored", func(t *testing.T) {
+		assert.Equal(t, slog.Default(), logger.FromContext(context.Background()))
+	})
+
+	t.Run("returns stored logger", func(t *testing.T) {
+		l := logger.New(&bytes.Buffer{}, "json", slog.LevelInfo)
+		ctx := logger.WithContext(context.Background(), l)
+
+		assert.Same(t, l, logger.FromContext(ctx))
+	})
+}
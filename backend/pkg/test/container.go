@@ -147,12 +147,21 @@ func CleanupTables(t *testing.T, pool *pgxpool.Pool) {
 
 	ctx := context.Background()
 	tables := []string{
+		"alert_rules",
+		"share_links",
+		"collection_items",
+		"collection_members",
+		"collections",
+		"price_stats",
 		"price_snapshots",
 		"product_sources",
 		"user_watchlist",
+		"product_aliases",
 		"products",
 		"users",
 		"sessions",
+		"rate_limits",
+		"exchange_rates",
 	}
 
 	for _, table := range tables {
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"backend/internal/normalize"
 	"context"
 	"testing"
 	"time"
@@ -12,6 +13,9 @@ type ProductSourceConfig struct {
 	Platform          string                                                                               
 	PlatformProductID string                                                                               
 	URL               string                                                                               
+	// defaults to new
+	Condition         string
+	SellerName        string
 } 
 
 type PriceSnapshotConfig struct {
@@ -20,6 +24,23 @@ type PriceSnapshotConfig struct {
 	Currency 		string
 	InStock			bool
 	CheckedAt		*time.Time
+	ShippingCost	float64
+	EstimatedTax	float64
+	Discount		float64
+	ListPrice		float64	// stored as NULL when 0
+	// empty condition and seller fields are stored as NULL
+	Condition		string
+	SellerName		string
+	SellerRating	float64
+}
+
+type AlertRuleConfig struct {
+	UserID		int
+	ProductID	int
+	RuleType	string
+	Threshold	float64
+	PriceBasis	string	// stored as NULL when empty
+	Active		bool
 }
 
 // Create a test user and return userID in database
@@ -51,10 +72,10 @@ func SeedProduct(t *testing.T, pool *pgxpool.Pool, name, imageURL string) int {
 	var productId int
 
 	err := pool.QueryRow(ctx,
-		`INSERT INTO products (product_name, image_url, created_at, last_checked_at, check_priority)
-		 VALUES ($1, $2, NOW(), NOW(), 0)
+		`INSERT INTO products (product_name, normalized_name, image_url, created_at, last_checked_at, check_priority)
+		 VALUES ($1, $2, $3, NOW(), NOW(), 0)
 		 RETURNING id`,
-		 name, imageURL,
+		 name, normalize.ProductName(name), imageURL,
 	).Scan(&productId)
 
 	if err != nil {
@@ -89,10 +110,11 @@ func SeedProductSource(t *testing.T, pool *pgxpool.Pool, config ProductSourceCon
 	var sourceID int
 
 	err := pool.QueryRow(ctx,
-		`INSERT INTO product_sources (product_id, platform, platform_product_id, product_url)
-		 VALUES ($1, $2, $3, $4)
+		`INSERT INTO product_sources (product_id, platform, platform_product_id, product_url, condition, seller_name)
+		 VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'new'), NULLIF($6, ''))
 		 RETURNING id`,
 		 config.ProductID, config.Platform, config.PlatformProductID, config.URL,
+		 config.Condition, config.SellerName,
 	).Scan(&sourceID)
 
 	if err != nil {
@@ -121,12 +143,81 @@ func SeedPriceSnapshot(t *testing.T, pool *pgxpool.Pool, config PriceSnapshotCon
 	}
 
 	_, err := pool.Exec(ctx,
-		`INSERT INTO price_snapshots (product_source_id, price, currency, in_stock, checked_at)
-		 VALUES ($1, $2, $3, $4, $5)`,
+		`INSERT INTO price_snapshots (
+			product_source_id, price, currency, in_stock, checked_at, shipping_cost, estimated_tax, discount,
+			condition, seller_name, seller_rating, list_price
+		 )
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11::numeric, 0), NULLIF($12::numeric, 0))`,
 		 config.ProductSourceID, config.Price, currency, config.InStock, checkedAt,
+		 config.ShippingCost, config.EstimatedTax, config.Discount,
+		 config.Condition, config.SellerName, config.SellerRating, config.ListPrice,
 	)
 
 	if err != nil {
 		t.Fatalf("Failed to seed product snapshot: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// Create an alert rule and return its ID
+func SeedAlertRule(t *testing.T, pool *pgxpool.Pool, config AlertRuleConfig) int {
+	t.Helper()
+
+	ctx := context.Background()
+	var alertID int
+
+	err := pool.QueryRow(ctx,
+		`INSERT INTO alert_rules (user_id, product_id, rule_type, threshold, price_basis, active, created_at)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NOW())
+		 RETURNING id`,
+		 config.UserID, config.ProductID, config.RuleType, config.Threshold, config.PriceBasis, config.Active,
+	).Scan(&alertID)
+
+	if err != nil {
+		t.Fatalf("Failed to seed alert rule: %v", err)
+	}
+
+	return alertID
+}
+
+// Add an exchange rate, units of the currency per 1 EUR on the date
+func SeedExchangeRate(t *testing.T, pool *pgxpool.Pool, date time.Time, currency string, rate float64) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx,
+		`INSERT INTO exchange_rates (currency, rate_date, rate)
+		 VALUES ($1, $2, $3)`,
+		 currency, date, rate,
+	)
+
+	if err != nil {
+		t.Fatalf("Failed to seed exchange rate: %v", err)
+	}
+}
+
+// Set whether the user compares sticker prices or landed cost
+func SetPriceBasis(t *testing.T, pool *pgxpool.Pool, userID int, basis string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `UPDATE users SET price_basis = $2 WHERE id = $1`, userID, basis)
+
+	if err != nil {
+		t.Fatalf("Failed to set price basis: %v", err)
+	}
+}
+
+// Set the currency the user sees prices in
+func SetDisplayCurrency(t *testing.T, pool *pgxpool.Pool, userID int, currency string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `UPDATE users SET display_currency = $2 WHERE id = $1`, userID, currency)
+
+	if err != nil {
+		t.Fatalf("Failed to set display currency: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pgx log messages emitted once a statement has finished
+var tracedQueryMessages = map[string]bool{
+	"Query": 		true,
+	"Exec": 		true,
+	"SendBatch": 	true,
+}
+
+// pgx v4 logger that turns each finished sql statement into a span. pgx
+// logs with the query's context and the elapsed time, so the span is
+// created after the fact as a child of whatever span is in the context
+type QueryTracer struct{}
+
+func NewQueryTracer() *QueryTracer {
+	return &QueryTracer{}
+}
+
+func (q *QueryTracer) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if !tracedQueryMessages[msg] {
+		return
+	}
+
+	// skip statements run outside of a traced request
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return
+	}
+
+	end := time.Now()
+	start := end
+	if elapsed, ok := data["time"].(time.Duration); ok {
+		start = end.Add(-elapsed)
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("db.system", "postgresql")}
+	if sql, ok := data["sql"].(string); ok {
+		attrs = append(attrs, attribute.String("db.statement", sql))
+	}
+	if rowCount, ok := data["rowCount"].(int); ok {
+		attrs = append(attrs, attribute.Int("db.rows", rowCount))
+	}
+
+	_, span := Tracer().Start(ctx, "pgx."+msg,
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	if err, ok := data["err"].(error); ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End(trace.WithTimestamp(end))
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// name of the tracer used for spans created by this app
+const TracerName = "backend"
+
+// Returns the tracer used for spans started by our middleware and db code
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Set up the global tracer provider exporting spans over OTLP/HTTP to the
+// collector at endpointURL (ex: http://localhost:4318) along with W3C
+// traceparent propagation. The returned shutdown function flushes any
+// buffered spans
+func Setup(ctx context.Context, serviceName, endpointURL string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointURL))
+	if err != nil {
+		return nil, fmt.Errorf("error creating otlp exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serviceName),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+	SetupPropagation()
+
+	return provider.Shutdown, nil
+}
+
+// Register the W3C trace context propagator so incoming traceparent
+// headers are picked up even when no exporter is configured
+func SetupPropagation() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+}
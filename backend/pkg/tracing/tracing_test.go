@@ -0,0 +1,119 @@
+//go:build unit
+
+package tracing_test
+
+import (
+	"backend/pkg/tracing"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// in process stand in for an OTLP/HTTP collector that keeps every span it receives
+type collectorStub struct {
+	mu		sync.Mutex
+	spans 	[]*tracepb.Span
+}
+
+func (c *collectorStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil || r.URL.Path != "/v1/traces" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	for _, resourceSpans := range req.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			c.spans = append(c.spans, scopeSpans.Spans...)
+		}
+	}
+	c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	out, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+	_, _ = w.Write(out)
+}
+
+func (c *collectorStub) spanByName(name string) *tracepb.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+// unit tests for Setup and QueryTracer exporting to an OTLP collector
+func TestSetupExportsSpans(t *testing.T) {
+	collector := &collectorStub{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	ctx := context.Background()
+	shutdown, err := tracing.Setup(ctx, "test-service", server.URL)
+	require.NoError(t, err)
+
+	requestCtx, requestSpan := tracing.Tracer().Start(ctx, "GET /api/v1/products")
+
+	queryTracer := tracing.NewQueryTracer()
+	queryTracer.Log(requestCtx, 0, "Query", map[string]interface{}{
+		"sql": 		"SELECT 1",
+		"time": 	5 * time.Millisecond,
+		"rowCount": 1,
+	})
+	queryTracer.Log(requestCtx, 0, "Exec", map[string]interface{}{
+		"sql": 	"DELETE FROM user_watchlist",
+		"time": time.Millisecond,
+		"err": 	errors.New("boom"),
+	})
+	// messages that aren't finished statements should not create spans
+	queryTracer.Log(requestCtx, 0, "Dialing PostgreSQL server", nil)
+	requestSpan.End()
+
+	require.NoError(t, shutdown(ctx))
+
+	parent := collector.spanByName("GET /api/v1/products")
+	require.NotNil(t, parent, "request span should be exported")
+
+	query := collector.spanByName("pgx.Query")
+	require.NotNil(t, query, "query span should be exported")
+	assert.Equal(t, parent.SpanId, query.ParentSpanId, "query span should be a child of the request span")
+	assert.Equal(t, parent.TraceId, query.TraceId)
+	assert.Equal(t, uint64(5*time.Millisecond), query.EndTimeUnixNano-query.StartTimeUnixNano, "span should cover the query duration")
+
+	exec := collector.spanByName("pgx.Exec")
+	require.NotNil(t, exec, "exec span should be exported")
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, exec.Status.Code, "failed statements should be marked as errors")
+
+	assert.Nil(t, collector.spanByName("pgx.Dialing PostgreSQL server"))
+}
+
+// unit tests for QueryTracer outside of a trace
+func TestQueryTracerWithoutParent(t *testing.T) {
+	t.Run("does not panic or create spans without a parent span", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			tracing.NewQueryTracer().Log(context.Background(), 0, "Query", map[string]interface{}{"sql": "SELECT 1"})
+		})
+	})
+}
@@ -3,6 +3,9 @@ CREATE TABLE IF NOT EXISTS users (
     username VARCHAR UNIQUE NOT NULL,
     email VARCHAR UNIQUE NOT NULL,
     password_hash VARCHAR NOT NULL,
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    display_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    price_basis VARCHAR NOT NULL DEFAULT 'sticker', -- sticker or landed, see effective_price
     created_at TIMESTAMP DEFAULT NOW()
 );
 
@@ -14,13 +17,33 @@ CREATE TABLE IF NOT EXISTS sessions (
     created_at TIMESTAMP DEFAULT NOW()
 );
 
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE TABLE IF NOT EXISTS products (
     id SERIAL PRIMARY KEY,
     product_name VARCHAR UNIQUE NOT NULL,
+    -- see normalize.ProductName, used to dedupe inserts. Databases created
+    -- before this column get it from cmd/names
+    normalized_name VARCHAR UNIQUE NOT NULL,
     image_url VARCHAR,
     created_at TIMESTAMP DEFAULT NOW(),
     last_checked_at TIMESTAMP,
-    check_priority INT DEFAULT 0
+    check_priority INT DEFAULT 0,
+    search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', product_name)) STORED
+);
+
+-- fuzzy and full text lookups for the product search endpoint
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (product_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+
+-- names of duplicate products merged into a canonical one so adding the
+-- duplicate name again resolves to the canonical product
+CREATE TABLE IF NOT EXISTS product_aliases (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    alias_name VARCHAR NOT NULL,
+    normalized_name VARCHAR UNIQUE NOT NULL,
+    merged_at TIMESTAMP DEFAULT NOW()
 );
 
 CREATE TABLE IF NOT EXISTS user_watchlist (
@@ -28,15 +51,76 @@ CREATE TABLE IF NOT EXISTS user_watchlist (
     user_id INT NOT NULL REFERENCES users(id),
     product_id INT NOT NULL REFERENCES products(id),
     added_at TIMESTAMP DEFAULT NOW(),
+    -- offers that count towards the lowest price, NULL accepts everything
+    acceptable_conditions VARCHAR[],
+    min_seller_rating DECIMAL(5, 2), -- positive feedback percent
+    -- marketplace sellers without a rating pass min_seller_rating
+    allow_unrated_sellers BOOLEAN NOT NULL DEFAULT FALSE,
+    target_price DECIMAL(10, 2),
+    notes TEXT,
+    -- preferred platforms win ties on the lowest price, excluded ones never count
+    preferred_platforms VARCHAR[],
+    excluded_platforms VARCHAR[],
+    paused BOOLEAN NOT NULL DEFAULT false, -- alerts on the product don't trigger while paused
+    tags VARCHAR[],
     UNIQUE(user_id, product_id)
 );
 
+CREATE INDEX IF NOT EXISTS idx_user_watchlist_tags ON user_watchlist USING GIN (tags);
+
+-- user defined groups of watchlist products, e.g. the parts of one build
+CREATE TABLE IF NOT EXISTS collections (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS collection_items (
+    collection_id INT NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id),
+    quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    added_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY(collection_id, product_id)
+);
+
+-- users a collection is shared with, the owner in collections.user_id isn't a
+-- member. viewers can read the collection and editors can change its items
+CREATE TABLE IF NOT EXISTS collection_members (
+    collection_id INT NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id),
+    role VARCHAR NOT NULL CHECK (role IN ('viewer', 'editor')),
+    added_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY(collection_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_members_user ON collection_members(user_id);
+
+-- public read only links to a collection or a product's price history, the
+-- token is the only thing needed to read it. Only its sha-256 is stored, the
+-- token is shown once when the link is created
+CREATE TABLE IF NOT EXISTS share_links (
+    id SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id),
+    collection_id INT REFERENCES collections(id) ON DELETE CASCADE,
+    product_id INT REFERENCES products(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP, -- NULL never expires
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK ((collection_id IS NULL) <> (product_id IS NULL))
+);
+
 CREATE TABLE IF NOT EXISTS product_sources (
     id SERIAL PRIMARY KEY,
     product_id INT NOT NULL REFERENCES products(id),
     platform VARCHAR NOT NULL,
-    platform_product_id VARCHAR, -- ASIN, SKU, etc
+    platform_product_id VARCHAR, -- ASIN, SKU, etc, prefixed with the store domain for regional stores (amazon.co.uk:B0...)
     product_url VARCHAR,
+    condition VARCHAR NOT NULL DEFAULT 'new', -- new, used or refurbished
+    seller_name VARCHAR, -- marketplace seller of the listing, NULL when sold by the platform
     UNIQUE(platform, platform_product_id)
 );
 
@@ -46,7 +130,233 @@ CREATE TABLE IF NOT EXISTS price_snapshots (
     price DECIMAL(10, 2),
     currency VARCHAR DEFAULT 'USD',
     in_stock BOOLEAN,
-    checked_at TIMESTAMP DEFAULT NOW()
+    checked_at TIMESTAMP DEFAULT NOW(),
+    -- extra costs captured by the scrapers, NULL when the site didn't show them
+    shipping_cost DECIMAL(10, 2),
+    estimated_tax DECIMAL(10, 2),
+    discount DECIMAL(10, 2), -- coupons and checkout discounts
+    list_price DECIMAL(10, 2), -- advertised list or "was" price, NULL when the site showed none
+    -- offer seen on this check, condition falls back to the source's
+    condition VARCHAR,
+    seller_name VARCHAR,
+    seller_rating DECIMAL(5, 2) -- positive feedback percent, NULL when sold by the platform
+);
+
+-- whether an offer passes a watchlist entry's condition and seller rating
+-- filters. NULL filters accept everything. Offers sold by the platform itself
+-- have no seller and pass the rating threshold, marketplace sellers without a
+-- rating only pass it when the entry allows unrated sellers
+CREATE OR REPLACE FUNCTION offer_acceptable(
+    offer_condition VARCHAR, seller_name VARCHAR, seller_rating NUMERIC,
+    acceptable_conditions VARCHAR[], min_seller_rating NUMERIC, allow_unrated_sellers BOOLEAN
+) RETURNS BOOLEAN AS $$
+    SELECT (acceptable_conditions IS NULL OR offer_condition = ANY(acceptable_conditions))
+        AND (
+            min_seller_rating IS NULL
+            OR seller_name IS NULL
+            OR COALESCE(seller_rating >= min_seller_rating, COALESCE(allow_unrated_sellers, false))
+        )
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- price a user compares offers on, sticker is the listed price and landed is
+-- what checkout would cost with shipping and tax minus coupons
+CREATE OR REPLACE FUNCTION effective_price(price NUMERIC, shipping NUMERIC, tax NUMERIC, discount NUMERIC, basis VARCHAR) RETURNS NUMERIC AS $$
+    SELECT CASE
+        WHEN price IS NULL THEN NULL
+        WHEN basis = 'landed' THEN GREATEST(price + COALESCE(shipping, 0) + COALESCE(tax, 0) - COALESCE(discount, 0), 0)
+        ELSE price
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- per user alerts on a product, threshold is a price or a percent depending on
+-- rule_type. Alerts on a paused watchlist entry don't trigger
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    rule_type VARCHAR NOT NULL, -- price_below, percent_drop, back_in_stock, predicted_below
+    threshold DECIMAL(10, 2),
+    price_basis VARCHAR, -- sticker or landed, NULL follows the user's price_basis
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW()
 );
 
+CREATE INDEX IF NOT EXISTS idx_alert_rules_user_product ON alert_rules(user_id, product_id);
+
 CREATE INDEX idx_price_snapshots_time ON price_snapshots(product_source_id, checked_at DESC);
+
+-- ECB reference rates, units of the currency per 1 EUR on the day
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency VARCHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate NUMERIC(18, 8) NOT NULL,
+    PRIMARY KEY (currency, rate_date)
+);
+
+-- rate of the currency on the date, the closest earlier rate covers weekends
+-- and holidays, dates before the first imported rate use the earliest one.
+-- NULL when the currency has no rates at all
+CREATE OR REPLACE FUNCTION exchange_rate(code VARCHAR, at TIMESTAMP) RETURNS NUMERIC AS $$
+    SELECT CASE WHEN UPPER(code) = 'EUR' THEN 1::NUMERIC ELSE COALESCE(
+        (SELECT rate FROM exchange_rates
+         WHERE currency = UPPER(code) AND rate_date <= at::date
+         ORDER BY rate_date DESC LIMIT 1),
+        (SELECT rate FROM exchange_rates
+         WHERE currency = UPPER(code) AND rate_date > at::date
+         ORDER BY rate_date ASC LIMIT 1)
+    ) END
+$$ LANGUAGE SQL STABLE;
+
+-- convert an amount between currencies with the rates of the date, NULL when
+-- either currency has no rates so unconvertible prices drop out of comparisons
+CREATE OR REPLACE FUNCTION convert_price(amount NUMERIC, from_code VARCHAR, to_code VARCHAR, at TIMESTAMP) RETURNS NUMERIC AS $$
+    SELECT CASE
+        WHEN amount IS NULL THEN NULL
+        WHEN UPPER(from_code) = UPPER(to_code) THEN amount
+        ELSE ROUND(amount / exchange_rate(from_code, at) * exchange_rate(to_code, at), 2)
+    END
+$$ LANGUAGE SQL STABLE;
+
+-- cached price statistics per price basis, one row per source in the
+-- listing's currency and one per product (product_source_id NULL) across its
+-- sources in USD. Rebuilt by refresh_price_stats from the stats job (cmd/stats)
+-- when stale or old, reads convert them to the user's display currency
+CREATE TABLE IF NOT EXISTS price_stats (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    product_source_id INT REFERENCES product_sources(id) ON DELETE CASCADE,
+    price_basis VARCHAR NOT NULL, -- sticker or landed, see effective_price
+    currency VARCHAR(3) NOT NULL,
+    snapshot_count INT NOT NULL,
+    current_price DECIMAL(10, 2), -- latest price, the lowest latest one across sources for products
+    all_time_low DECIMAL(10, 2),
+    all_time_low_at TIMESTAMP, -- last time the price was at its low
+    all_time_high DECIMAL(10, 2),
+    all_time_high_at TIMESTAMP,
+    avg_7d DECIMAL(10, 2),
+    median_7d DECIMAL(10, 2),
+    stddev_7d DECIMAL(10, 2),
+    avg_30d DECIMAL(10, 2),
+    median_30d DECIMAL(10, 2),
+    stddev_30d DECIMAL(10, 2),
+    avg_90d DECIMAL(10, 2),
+    median_90d DECIMAL(10, 2),
+    stddev_90d DECIMAL(10, 2),
+    current_vs_avg_30d DECIMAL(8, 2), -- percent above (positive) or below the 30 day average
+    stale BOOLEAN NOT NULL DEFAULT false,
+    refreshed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_stats_product_source ON price_stats(product_id, COALESCE(product_source_id, 0), price_basis);
+
+-- rebuild the stats rows of a product from its snapshots on both price bases.
+-- Lows, highs and the latest price come from window functions over each
+-- source's history and the product's combined history, snapshots that can't
+-- be converted are left out
+CREATE OR REPLACE FUNCTION refresh_price_stats(pid INT) RETURNS VOID AS $$
+BEGIN
+    -- concurrent refreshes of a product wait for each other instead of
+    -- inserting the same rows twice
+    PERFORM pg_advisory_xact_lock(hashtext('price_stats'), pid);
+
+    DELETE FROM price_stats WHERE product_id = pid;
+
+    INSERT INTO price_stats (
+        product_id, product_source_id, price_basis, currency, snapshot_count, current_price,
+        all_time_low, all_time_low_at, all_time_high, all_time_high_at,
+        avg_7d, median_7d, stddev_7d, avg_30d, median_30d, stddev_30d,
+        avg_90d, median_90d, stddev_90d, current_vs_avg_30d, stale, refreshed_at
+    )
+    WITH snapshots AS (
+        SELECT psnap.id, psnap.product_source_id, b.basis,
+            effective_price(psnap.price, psnap.shipping_cost, psnap.estimated_tax, psnap.discount, b.basis) as price,
+            COALESCE(psnap.currency, 'USD') as currency, psnap.checked_at
+        FROM price_snapshots psnap
+        INNER JOIN product_sources ps ON psnap.product_source_id = ps.id
+        CROSS JOIN (VALUES ('sticker'), ('landed')) b(basis)
+        WHERE ps.product_id = pid
+        AND psnap.price IS NOT NULL
+    ),
+    source_currencies AS (
+        SELECT DISTINCT ON (product_source_id) product_source_id, currency
+        FROM snapshots
+        ORDER BY product_source_id, checked_at DESC, id DESC
+    ),
+    -- every snapshot once for its source and once for the product
+    history AS (
+        SELECT s.id, s.basis, s.product_source_id as stats_source_id, s.product_source_id,
+            sc.currency, convert_price(s.price, s.currency, sc.currency, s.checked_at) as price, s.checked_at
+        FROM snapshots s
+        INNER JOIN source_currencies sc ON s.product_source_id = sc.product_source_id
+        UNION ALL
+        SELECT s.id, s.basis, NULL, s.product_source_id,
+            'USD', convert_price(s.price, s.currency, 'USD', s.checked_at), s.checked_at
+        FROM snapshots s
+    ),
+    ranked AS (
+        SELECT
+            basis, stats_source_id, currency, price, checked_at,
+            ROW_NUMBER() OVER (PARTITION BY basis, stats_source_id, product_source_id ORDER BY checked_at DESC, id DESC) = 1 as latest,
+            FIRST_VALUE(price) OVER lows as low,
+            FIRST_VALUE(checked_at) OVER lows as low_at,
+            FIRST_VALUE(price) OVER highs as high,
+            FIRST_VALUE(checked_at) OVER highs as high_at
+        FROM history
+        WHERE price IS NOT NULL
+        WINDOW
+            lows AS (PARTITION BY basis, stats_source_id ORDER BY price ASC, checked_at DESC),
+            highs AS (PARTITION BY basis, stats_source_id ORDER BY price DESC, checked_at DESC)
+    ),
+    summary AS (
+        SELECT
+            basis, stats_source_id, MIN(currency) as currency, COUNT(*) as snapshot_count,
+            MIN(price) FILTER (WHERE latest) as current_price,
+            MIN(low) as low, MIN(low_at) as low_at, MIN(high) as high, MIN(high_at) as high_at,
+            AVG(price) FILTER (WHERE checked_at > NOW() - INTERVAL '7 days') as avg_7d,
+            PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY price::float8) FILTER (WHERE checked_at > NOW() - INTERVAL '7 days') as median_7d,
+            STDDEV_POP(price) FILTER (WHERE checked_at > NOW() - INTERVAL '7 days') as stddev_7d,
+            AVG(price) FILTER (WHERE checked_at > NOW() - INTERVAL '30 days') as avg_30d,
+            PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY price::float8) FILTER (WHERE checked_at > NOW() - INTERVAL '30 days') as median_30d,
+            STDDEV_POP(price) FILTER (WHERE checked_at > NOW() - INTERVAL '30 days') as stddev_30d,
+            AVG(price) FILTER (WHERE checked_at > NOW() - INTERVAL '90 days') as avg_90d,
+            PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY price::float8) FILTER (WHERE checked_at > NOW() - INTERVAL '90 days') as median_90d,
+            STDDEV_POP(price) FILTER (WHERE checked_at > NOW() - INTERVAL '90 days') as stddev_90d
+        FROM ranked
+        GROUP BY basis, stats_source_id
+    )
+    SELECT
+        pid, stats_source_id, basis, currency, snapshot_count, current_price,
+        low, low_at, high, high_at,
+        ROUND(avg_7d, 2), ROUND(median_7d::numeric, 2), ROUND(stddev_7d, 2),
+        ROUND(avg_30d, 2), ROUND(median_30d::numeric, 2), ROUND(stddev_30d, 2),
+        ROUND(avg_90d, 2), ROUND(median_90d::numeric, 2), ROUND(stddev_90d, 2),
+        ROUND((current_price - avg_30d) / NULLIF(avg_30d, 0) * 100, 2),
+        false, NOW()
+    FROM summary;
+END;
+$$ LANGUAGE plpgsql;
+
+-- new or changed snapshots mark their product's stats for a rebuild
+CREATE OR REPLACE FUNCTION mark_price_stats_stale() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE price_stats SET stale = true
+    WHERE product_id = (SELECT product_id FROM product_sources WHERE id = NEW.product_source_id)
+    AND NOT stale;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER price_snapshots_mark_stats_stale
+AFTER INSERT OR UPDATE ON price_snapshots
+FOR EACH ROW EXECUTE FUNCTION mark_price_stats_stale();
+
+-- token buckets shared by every replica when the postgres rate limit backend is used
+CREATE TABLE IF NOT EXISTS rate_limits (
+    bucket_key VARCHAR PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- when the bucket has refilled completely, full buckets behave the same
+    -- as missing ones so they are pruned past this time
+    full_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_full_at ON rate_limits(full_at);